@@ -2,11 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/PseudoMera/virtual-store/order/payment"
 	"github.com/PseudoMera/virtual-store/order/service"
 	"github.com/PseudoMera/virtual-store/order/store"
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
 )
 
 type OrderAPI struct {
@@ -20,10 +26,23 @@ func NewOrderAPI(service *service.OrderService) *OrderAPI {
 	}
 }
 
+type OrderItemRequest struct {
+	ProductID int `json:"product_id"`
+	SKUID     int `json:"sku_id"`
+	Quantity  int `json:"quantity"`
+}
+
+type PaymentCardRequest struct {
+	Number      string `json:"number"`
+	ExpiryMonth int    `json:"expiry_month"`
+	ExpiryYear  int    `json:"expiry_year"`
+	CVC         string `json:"cvc"`
+}
+
 type CreateOrderRequest struct {
-	UserID     int     `json:"user_id"`
-	TotalPrice float64 `json:"total_price"`
-	Status     string  `json:"status"`
+	UserID int                `json:"user_id"`
+	Items  []OrderItemRequest `json:"items"`
+	Card   PaymentCardRequest `json:"card"`
 }
 
 type CreateOrderResponse struct {
@@ -37,9 +56,28 @@ func (o *OrderAPI) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := o.service.CreateOrder(r.Context(), req.UserID, req.TotalPrice, store.OrderStatus(req.Status))
+	if err := auth.Authorize(r.Context(), req.UserID, auth.PermissionOrderWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	items := make([]store.OrderItem, len(req.Items))
+	for i := range req.Items {
+		items[i] = store.OrderItem{
+			ProductID: req.Items[i].ProductID,
+			SKUID:     req.Items[i].SKUID,
+			Quantity:  req.Items[i].Quantity,
+		}
+	}
+
+	id, err := o.service.CreateOrder(r.Context(), req.UserID, items, payment.Card{
+		Number:      req.Card.Number,
+		ExpiryMonth: req.Card.ExpiryMonth,
+		ExpiryYear:  req.Card.ExpiryYear,
+		CVC:         req.Card.CVC,
+	})
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
@@ -59,9 +97,9 @@ func (o *OrderAPI) GetOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	order, err := o.service.GetOrder(r.Context(), req.ID)
+	order, err := o.service.GetCallerOrder(r.Context(), req.ID, auth.PermissionOrderRead)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
 		return
 	}
 
@@ -69,7 +107,12 @@ func (o *OrderAPI) GetOrder(w http.ResponseWriter, r *http.Request) {
 }
 
 type GetOrdersByUserRequest struct {
-	UserID int `json:"user_id"`
+	UserID        int       `json:"user_id"`
+	Status        string    `json:"status"`
+	Currency      string    `json:"currency"`
+	CreatedAfter  time.Time `json:"created_after"`
+	CreatedBefore time.Time `json:"created_before"`
+	page.Request
 }
 
 func (o *OrderAPI) GetOrdersByUser(w http.ResponseWriter, r *http.Request) {
@@ -79,9 +122,19 @@ func (o *OrderAPI) GetOrdersByUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	orders, err := o.service.GetOrdersByUser(r.Context(), req.UserID)
+	if err := auth.Authorize(r.Context(), req.UserID, auth.PermissionOrderRead); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	orders, err := o.service.GetOrdersByUser(r.Context(), req.UserID, store.OrderFilter{
+		Status:        store.OrderStatus(req.Status),
+		Currency:      req.Currency,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+	}, req.Request)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
 		return
 	}
 
@@ -89,9 +142,10 @@ func (o *OrderAPI) GetOrdersByUser(w http.ResponseWriter, r *http.Request) {
 }
 
 type UpdateOrderRequest struct {
-	ID         int     `json:"id"`
-	Status     string  `json:"status"`
-	TotalPrice float64 `json:"total_price"`
+	ID         int         `json:"id"`
+	Status     string      `json:"status"`
+	TotalPrice money.Money `json:"total_price"`
+	Currency   string      `json:"currency"`
 }
 
 func (o *OrderAPI) UpdateOrder(w http.ResponseWriter, r *http.Request) {
@@ -101,8 +155,19 @@ func (o *OrderAPI) UpdateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := o.service.UpdateOrder(r.Context(), req.ID, store.OrderStatus(req.Status), req.TotalPrice); err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+	if err := auth.CheckPermission(r.Context(), auth.PermissionOrderWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	totalPrice, err := inCurrency("total_price", req.TotalPrice, req.Currency)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := o.service.UpdateOrder(r.Context(), req.ID, store.OrderStatus(req.Status), totalPrice); err != nil {
+		writeError(w, err)
 		return
 	}
 
@@ -112,6 +177,7 @@ func (o *OrderAPI) UpdateOrder(w http.ResponseWriter, r *http.Request) {
 type UpdateOrderStatusRequest struct {
 	ID     int    `json:"id"`
 	Status string `json:"status"`
+	Reason string `json:"reason"`
 }
 
 func (o *OrderAPI) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
@@ -121,10 +187,162 @@ func (o *OrderAPI) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := o.service.UpdateOrderStatus(r.Context(), req.ID, store.OrderStatus(req.Status)); err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+	if err := auth.CheckPermission(r.Context(), auth.PermissionOrderStatus); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := o.service.UpdateOrderStatus(r.Context(), req.ID, store.OrderStatus(req.Status), req.Reason); err != nil {
+		writeError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+type GetOrderStatusHistoryRequest struct {
+	ID int `json:"id"`
+}
+
+func (o *OrderAPI) GetOrderStatusHistory(w http.ResponseWriter, r *http.Request) {
+	var req GetOrderStatusHistoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := o.service.GetCallerOrder(r.Context(), req.ID, auth.PermissionOrderRead); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	history, err := o.service.GetOrderStatusHistory(r.Context(), req.ID)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, history, w)
+}
+
+type RefundItemRequest struct {
+	ProductID int `json:"product_id"`
+	SKUID     int `json:"sku_id"`
+	Quantity  int `json:"quantity"`
+}
+
+type RefundOrderRequest struct {
+	OrderID int                 `json:"order_id"`
+	Items   []RefundItemRequest `json:"items"`
+	Restock bool                `json:"restock"`
+	Reason  string              `json:"reason"`
+}
+
+func (o *OrderAPI) RefundOrder(w http.ResponseWriter, r *http.Request) {
+	var req RefundOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionOrderRefund); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	items := make([]store.RefundItem, len(req.Items))
+	for i := range req.Items {
+		items[i] = store.RefundItem{
+			ProductID: req.Items[i].ProductID,
+			SKUID:     req.Items[i].SKUID,
+			Quantity:  req.Items[i].Quantity,
+		}
+	}
+
+	refund, err := o.service.RefundOrder(r.Context(), req.OrderID, items, req.Restock, req.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusCreated, refund, w)
+}
+
+type GetRefundsRequest struct {
+	OrderID int `json:"order_id"`
+}
+
+func (o *OrderAPI) GetRefunds(w http.ResponseWriter, r *http.Request) {
+	var req GetRefundsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := o.service.GetCallerOrder(r.Context(), req.OrderID, auth.PermissionOrderRead); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	refunds, err := o.service.GetRefunds(r.Context(), req.OrderID)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, refunds, w)
+}
+
+type ReconcileRefundRequest struct {
+	ID int `json:"id"`
+}
+
+// ReconcileRefund settles a refund left pending by a payment gateway timeout.
+func (o *OrderAPI) ReconcileRefund(w http.ResponseWriter, r *http.Request) {
+	var req ReconcileRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionOrderRefund); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	refund, err := o.service.ReconcileRefund(r.Context(), req.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, refund, w)
+}
+
+// writeError writes an error of the order service, payment failures get their own status
+// and any other error the status of its kind.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, payment.ErrDeclined):
+		shared.WriteErrorResponse(w, err, http.StatusPaymentRequired)
+	case errors.Is(err, payment.ErrTimeout):
+		shared.WriteErrorResponse(w, err, http.StatusGatewayTimeout)
+	default:
+		shared.WriteError(w, err)
+	}
+}
+
+// inCurrency returns a price of a request, decoded before its currency was known, as an amount
+// of the currency of the request, DefaultCurrency when it has none.
+func inCurrency(field string, price money.Money, currency string) (money.Money, error) {
+	if currency == "" {
+		return price, nil
+	}
+
+	price, err := price.InCurrency(currency)
+	if err != nil {
+		return money.Money{}, shared.InvalidFieldError(field, err.Error())
+	}
+
+	return price, nil
+}
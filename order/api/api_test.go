@@ -4,44 +4,173 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/PseudoMera/virtual-store/order/migrations"
+	"github.com/PseudoMera/virtual-store/order/payment"
 	"github.com/PseudoMera/virtual-store/order/service"
 	"github.com/PseudoMera/virtual-store/order/store"
+	productmigrations "github.com/PseudoMera/virtual-store/product/migrations"
+	productStore "github.com/PseudoMera/virtual-store/product/store"
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	usermigrations "github.com/PseudoMera/virtual-store/user/migrations"
 	userStore "github.com/PseudoMera/virtual-store/user/store"
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
-	projectRootPath = "../../"
-
-	testTotalPrice = 120.40
-	testStatus     = "pending"
-	testEmail      = "test@test.test"
-	testPassword   = "testPassword!!!"
+	testQuantity = 2
+	testStatus   = "pending"
+	testActor    = "test"
+	testEmail    = "test@test.test"
+	testPassword = "testPassword!!!"
 )
 
-func TestCreateOrder(t *testing.T) {
-	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+var testTotalPrice = money.MustParse("120.40", "")
+
+var testAuthenticator = auth.NewAuthenticator([]byte("testSecret"))
+
+// testDatabases are the databases of the order service and of the services it depends on.
+type testDatabases struct {
+	order   *shared.PostgresDB
+	product *shared.PostgresDB
+	user    *shared.PostgresDB
+}
+
+func setupDatabases(t *testing.T, ctx context.Context) testDatabases {
+	t.Helper()
+
+	dbs, _, err := shared.SetupPostgresClients(ctx, migrations.FS, productmigrations.FS, usermigrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	s := store.NewStore(db.DB())
-	serv := service.NewOrderService(s, slog.Default())
+	return testDatabases{
+		order:   dbs[0],
+		product: dbs[1],
+		user:    dbs[2],
+	}
+}
+
+// storeUsers checks users directly in the user store instead of going through the user service.
+type storeUsers struct {
+	db *userStore.Store
+}
+
+func newStoreUsers(db *shared.PostgresDB) *storeUsers {
+	return &storeUsers{
+		db: userStore.NewStore(db.DB()),
+	}
+}
+
+func (su *storeUsers) CheckUser(ctx context.Context, userID int) error {
+	_, err := su.db.RetrieveUserByID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return service.ErrUserNotFound
+	}
+	return err
+}
+
+func (su *storeUsers) Currency(ctx context.Context, userID int) (string, error) {
+	profile, err := su.db.RetrieveUserProfile(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return money.DefaultCurrency, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return money.CountryCurrency(profile.Country), nil
+}
+
+// storeInventory looks up SKUs and reserves stock directly in the product store instead of going through the product service.
+type storeInventory struct {
+	db *productStore.Store
+}
+
+func newStoreInventory(db *shared.PostgresDB) *storeInventory {
+	return &storeInventory{
+		db: productStore.NewStore(db.DB()),
+	}
+}
+
+func (si *storeInventory) GetSKU(ctx context.Context, skuID, productID int) (*service.SKU, error) {
+	var sku *productStore.SKU
+	var err error
+	if skuID != 0 {
+		sku, err = si.db.RetrieveSKU(ctx, skuID)
+	} else {
+		sku, err = si.db.RetrieveDefaultSKU(ctx, productID)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, service.ErrProductNotFound
+	}
+	if errors.Is(err, productStore.ErrSKURequired) {
+		return nil, service.ErrSKURequired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.SKU{
+		ID:        sku.ID,
+		ProductID: sku.ProductID,
+		Price:     sku.Price,
+		Prices:    sku.Prices,
+		Stock:     sku.Stock,
+	}, nil
+}
+
+func (si *storeInventory) ReserveStock(ctx context.Context, orderID, skuID, quantity int) error {
+	err := si.db.ReserveSKUStock(ctx, orderID, skuID, quantity)
+	if errors.Is(err, productStore.ErrInsufficientStock) {
+		return service.ErrInsufficientStock
+	}
+	return err
+}
+
+func (si *storeInventory) ReleaseStock(ctx context.Context, orderID, skuID, quantity int) error {
+	return si.db.ReleaseSKUStock(ctx, orderID, skuID, quantity)
+}
+
+// setAuthorization sets a valid access token for the given user and permissions on the request.
+func setAuthorization(t *testing.T, req *http.Request, userID int, permissions ...string) {
+	t.Helper()
+
+	token, _, err := testAuthenticator.IssueToken(auth.Identity{
+		UserID:      userID,
+		Email:       testEmail,
+		Permissions: permissions,
+	}, auth.AccessToken, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+func TestCreateOrder(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
 	api := NewOrderAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Post("/api/v1/order", api.CreateOrder)
 
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
-	uStore := userStore.NewStore(db.DB())
+	uStore := userStore.NewStore(dbs.user.DB())
 	userID, err := uStore.StoreUser(ctx, userStore.User{
 		Email:    testEmail,
 		Password: testPassword,
@@ -50,10 +179,24 @@ func TestCreateOrder(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	pStore := productStore.NewStore(dbs.product.DB())
+	productID, err := pStore.StoreProduct(ctx, productStore.Product{
+		Name:  "product",
+		Price: testTotalPrice,
+		Stock: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	createOrder := CreateOrderRequest{
-		UserID:     userID,
-		TotalPrice: testTotalPrice,
-		Status:     testStatus,
+		UserID: userID,
+		Items: []OrderItemRequest{
+			{ProductID: productID, Quantity: testQuantity},
+		},
+		Card: PaymentCardRequest{
+			Number: payment.CardApproved,
+		},
 	}
 	createOrderBytes, err := json.Marshal(createOrder)
 	if err != nil {
@@ -66,6 +209,7 @@ func TestCreateOrder(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -78,25 +222,192 @@ func TestCreateOrder(t *testing.T) {
 	if status := resp.StatusCode; status != http.StatusCreated {
 		t.Fatalf("wanted %d, got %d", http.StatusCreated, status)
 	}
+
+	var created CreateOrderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := s.RetrieveOrder(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Items) != 1 {
+		t.Fatalf("wanted %d, got %d", 1, len(order.Items))
+	}
+	if order.TotalPrice != testTotalPrice.Mul(testQuantity) {
+		t.Fatalf("wanted %s, got %s", testTotalPrice.Mul(testQuantity), order.TotalPrice)
+	}
+	if order.Status != store.Completed {
+		t.Fatalf("wanted %s, got %s", store.Completed, order.Status)
+	}
+
+	product, err := pStore.RetrieveProduct(ctx, productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Stock != 10-testQuantity {
+		t.Fatalf("wanted %d, got %d", 10-testQuantity, product.Stock)
+	}
 }
 
-func TestGetOrder(t *testing.T) {
+func TestCreateOrderInsufficientStock(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
+	api := NewOrderAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Post("/api/v1/order", api.CreateOrder)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	uStore := userStore.NewStore(dbs.user.DB())
+	userID, err := uStore.StoreUser(ctx, userStore.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pStore := productStore.NewStore(dbs.product.DB())
+	productID, err := pStore.StoreProduct(ctx, productStore.Product{
+		Name:  "product",
+		Price: testTotalPrice,
+		Stock: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createOrderBytes, err := json.Marshal(CreateOrderRequest{
+		UserID: userID,
+		Items: []OrderItemRequest{
+			{ProductID: productID, Quantity: testQuantity},
+		},
+		Card: PaymentCardRequest{
+			Number: payment.CardApproved,
+		},
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	s := store.NewStore(db.DB())
-	serv := service.NewOrderService(s, slog.Default())
+	req, err := http.NewRequest("POST", ts.URL+"/api/v1/order", bytes.NewBuffer(createOrderBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusConflict {
+		t.Fatalf("wanted %d, got %d", http.StatusConflict, status)
+	}
+}
+
+func TestCreateOrderPaymentDeclined(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
+	api := NewOrderAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Post("/api/v1/order", api.CreateOrder)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	uStore := userStore.NewStore(dbs.user.DB())
+	userID, err := uStore.StoreUser(ctx, userStore.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pStore := productStore.NewStore(dbs.product.DB())
+	productID, err := pStore.StoreProduct(ctx, productStore.Product{
+		Name:  "product",
+		Price: testTotalPrice,
+		Stock: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createOrderBytes, err := json.Marshal(CreateOrderRequest{
+		UserID: userID,
+		Items: []OrderItemRequest{
+			{ProductID: productID, Quantity: testQuantity},
+		},
+		Card: PaymentCardRequest{
+			Number: payment.CardDeclined,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", ts.URL+"/api/v1/order", bytes.NewBuffer(createOrderBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusPaymentRequired {
+		t.Fatalf("wanted %d, got %d", http.StatusPaymentRequired, status)
+	}
+
+	product, err := pStore.RetrieveProduct(ctx, productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Stock != 10 {
+		t.Fatalf("wanted %d, got %d", 10, product.Stock)
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
 	api := NewOrderAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Get("/api/v1/order", api.GetOrder)
 
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
-	uStore := userStore.NewStore(db.DB())
+	uStore := userStore.NewStore(dbs.user.DB())
 	userID, err := uStore.StoreUser(ctx, userStore.User{
 		Email:    testEmail,
 		Password: testPassword,
@@ -109,7 +420,7 @@ func TestGetOrder(t *testing.T) {
 		UserID:     userID,
 		TotalPrice: testTotalPrice,
 		Status:     testStatus,
-	})
+	}, testActor)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -128,6 +439,7 @@ func TestGetOrder(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -144,21 +456,19 @@ func TestGetOrder(t *testing.T) {
 
 func TestGetOrdersByUser(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
-	if err != nil {
-		t.Fatal(err)
-	}
+	dbs := setupDatabases(t, ctx)
 
-	s := store.NewStore(db.DB())
-	serv := service.NewOrderService(s, slog.Default())
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
 	api := NewOrderAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Get("/api/v1/order", api.GetOrdersByUser)
 
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
-	uStore := userStore.NewStore(db.DB())
+	uStore := userStore.NewStore(dbs.user.DB())
 	userID, err := uStore.StoreUser(ctx, userStore.User{
 		Email:    testEmail,
 		Password: testPassword,
@@ -171,7 +481,7 @@ func TestGetOrdersByUser(t *testing.T) {
 		UserID:     userID,
 		TotalPrice: testTotalPrice,
 		Status:     testStatus,
-	})
+	}, testActor)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -190,6 +500,7 @@ func TestGetOrdersByUser(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -206,21 +517,19 @@ func TestGetOrdersByUser(t *testing.T) {
 
 func TestUpdateOrder(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
-	if err != nil {
-		t.Fatal(err)
-	}
+	dbs := setupDatabases(t, ctx)
 
-	s := store.NewStore(db.DB())
-	serv := service.NewOrderService(s, slog.Default())
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
 	api := NewOrderAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Put("/api/v1/order", api.UpdateOrder)
 
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
-	uStore := userStore.NewStore(db.DB())
+	uStore := userStore.NewStore(dbs.user.DB())
 	userID, err := uStore.StoreUser(ctx, userStore.User{
 		Email:    testEmail,
 		Password: testPassword,
@@ -233,15 +542,15 @@ func TestUpdateOrder(t *testing.T) {
 		UserID:     userID,
 		TotalPrice: testTotalPrice,
 		Status:     testStatus,
-	})
+	}, testActor)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	updateOrder := UpdateOrderRequest{
 		ID:         orderID,
-		Status:     "completed",
-		TotalPrice: 125555,
+		Status:     "pending",
+		TotalPrice: money.MustParse("125555", ""),
 	}
 	updateOrderBytes, err := json.Marshal(updateOrder)
 	if err != nil {
@@ -254,6 +563,7 @@ func TestUpdateOrder(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID, auth.PermissionOrderWrite)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -270,21 +580,19 @@ func TestUpdateOrder(t *testing.T) {
 
 func TestUpdateOrderStatus(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
-	if err != nil {
-		t.Fatal(err)
-	}
+	dbs := setupDatabases(t, ctx)
 
-	s := store.NewStore(db.DB())
-	serv := service.NewOrderService(s, slog.Default())
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
 	api := NewOrderAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Put("/api/v1/order", api.UpdateOrderStatus)
 
 	ts := httptest.NewServer(router)
 	defer ts.Close()
 
-	uStore := userStore.NewStore(db.DB())
+	uStore := userStore.NewStore(dbs.user.DB())
 	userID, err := uStore.StoreUser(ctx, userStore.User{
 		Email:    testEmail,
 		Password: testPassword,
@@ -297,14 +605,14 @@ func TestUpdateOrderStatus(t *testing.T) {
 		UserID:     userID,
 		TotalPrice: testTotalPrice,
 		Status:     testStatus,
-	})
+	}, testActor)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	updateOrder := UpdateOrderStatusRequest{
 		ID:     orderID,
-		Status: "completed",
+		Status: "cancelled",
 	}
 	updateOrderBytes, err := json.Marshal(updateOrder)
 	if err != nil {
@@ -317,6 +625,7 @@ func TestUpdateOrderStatus(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID, auth.PermissionOrderStatus)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -330,3 +639,432 @@ func TestUpdateOrderStatus(t *testing.T) {
 		t.Fatalf("wanted %d, got %d", http.StatusNoContent, status)
 	}
 }
+
+func TestUpdateOrderStatusInvalidTransition(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
+	api := NewOrderAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Put("/api/v1/order/status", api.UpdateOrderStatus)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	uStore := userStore.NewStore(dbs.user.DB())
+	userID, err := uStore.StoreUser(ctx, userStore.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orderID, err := s.StoreOrder(ctx, store.Order{
+		UserID:     userID,
+		TotalPrice: testTotalPrice,
+		Status:     testStatus,
+	}, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pending orders are completed only by the checkout once their payment is captured.
+	for _, status := range []string{"shipped", "completed"} {
+		updateOrderBytes, err := json.Marshal(UpdateOrderStatusRequest{
+			ID:     orderID,
+			Status: status,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest("PUT", ts.URL+"/api/v1/order/status", bytes.NewBuffer(updateOrderBytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Content-type", "application/json")
+		setAuthorization(t, req, userID, auth.PermissionOrderStatus)
+
+		client := &http.Client{}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if got := resp.StatusCode; got != http.StatusConflict {
+			t.Fatalf("wanted %d for %s, got %d", http.StatusConflict, status, got)
+		}
+	}
+}
+
+func TestGetOrderStatusHistory(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
+	api := NewOrderAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Get("/api/v1/order/status/history", api.GetOrderStatusHistory)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	uStore := userStore.NewStore(dbs.user.DB())
+	userID, err := uStore.StoreUser(ctx, userStore.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orderID, err := s.StoreOrder(ctx, store.Order{
+		UserID:     userID,
+		TotalPrice: testTotalPrice,
+		Status:     testStatus,
+	}, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	staffCtx := auth.NewContext(ctx, auth.Identity{UserID: userID + 1})
+	if err := serv.UpdateOrderStatus(staffCtx, orderID, store.Cancelled, "out of stock"); err != nil {
+		t.Fatal(err)
+	}
+
+	getHistoryBytes, err := json.Marshal(GetOrderStatusHistoryRequest{
+		ID: orderID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", ts.URL+"/api/v1/order/status/history", bytes.NewBuffer(getHistoryBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusOK {
+		t.Fatalf("wanted %d, got %d", http.StatusOK, status)
+	}
+
+	var history []store.StatusHistoryEntry
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(history))
+	}
+	if history[1].To != store.Cancelled || history[1].Reason != "out of stock" {
+		t.Fatalf("wanted %s, got %s", store.Cancelled, history[1].To)
+	}
+	if want := fmt.Sprintf("user:%d", userID+1); history[1].Actor != want {
+		t.Fatalf("wanted %s, got %s", want, history[1].Actor)
+	}
+}
+
+func TestGetOrderOfAnotherUser(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
+	api := NewOrderAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Get("/api/v1/order", api.GetOrder)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	uStore := userStore.NewStore(dbs.user.DB())
+	userID, err := uStore.StoreUser(ctx, userStore.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orderID, err := s.StoreOrder(ctx, store.Order{
+		UserID:     userID,
+		TotalPrice: testTotalPrice,
+		Status:     testStatus,
+	}, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getOrderBytes, err := json.Marshal(GetOrderRequest{
+		ID: orderID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", ts.URL+"/api/v1/order", bytes.NewBuffer(getOrderBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID+1)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	// The order of another user is not found, as if it did not exist.
+	if status := resp.StatusCode; status != http.StatusNotFound {
+		t.Fatalf("wanted %d, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestRefundOrderWithoutPermission(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
+	api := NewOrderAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Post("/api/v1/order/refund", api.RefundOrder)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	uStore := userStore.NewStore(dbs.user.DB())
+	userID, err := uStore.StoreUser(ctx, userStore.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orderID, err := s.StoreOrder(ctx, store.Order{
+		UserID: userID,
+		Status: store.Completed,
+	}, testActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refundOrderBytes, err := json.Marshal(RefundOrderRequest{
+		OrderID: orderID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		permissions []string
+		want        int
+	}{
+		{want: http.StatusForbidden},
+		// The order was never paid so there is nothing to refund.
+		{permissions: []string{auth.PermissionOrderRefund}, want: http.StatusConflict},
+	} {
+		req, err := http.NewRequest("POST", ts.URL+"/api/v1/order/refund", bytes.NewBuffer(refundOrderBytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Content-type", "application/json")
+		setAuthorization(t, req, userID, test.permissions...)
+
+		client := &http.Client{}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if status := resp.StatusCode; status != test.want {
+			t.Fatalf("wanted %d, got %d", test.want, status)
+		}
+	}
+}
+
+func TestCheckoutCart(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
+	api := NewOrderAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Post("/api/v1/cart/item", api.AddCartItem)
+	router.Post("/api/v1/cart/checkout", api.CheckoutCart)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	uStore := userStore.NewStore(dbs.user.DB())
+	userID, err := uStore.StoreUser(ctx, userStore.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pStore := productStore.NewStore(dbs.product.DB())
+	productID, err := pStore.StoreProduct(ctx, productStore.Product{
+		Name:  "product",
+		Price: testTotalPrice,
+		Stock: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addCartItemBytes, err := json.Marshal(CartItemRequest{
+		UserID:    userID,
+		ProductID: productID,
+		Quantity:  testQuantity,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", ts.URL+"/api/v1/cart/item", bytes.NewBuffer(addCartItemBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusOK {
+		t.Fatalf("wanted %d, got %d", http.StatusOK, status)
+	}
+
+	var cart store.Cart
+	if err := json.NewDecoder(resp.Body).Decode(&cart); err != nil {
+		t.Fatal(err)
+	}
+	if cart.TotalPrice != testTotalPrice.Mul(testQuantity) {
+		t.Fatalf("wanted %s, got %s", testTotalPrice.Mul(testQuantity), cart.TotalPrice)
+	}
+
+	checkoutCartBytes, err := json.Marshal(CheckoutCartRequest{
+		UserID: userID,
+		Card: PaymentCardRequest{
+			Number: payment.CardApproved,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest("POST", ts.URL+"/api/v1/cart/checkout", bytes.NewBuffer(checkoutCartBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
+
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusCreated {
+		t.Fatalf("wanted %d, got %d", http.StatusCreated, status)
+	}
+
+	var createOrderResp CreateOrderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&createOrderResp); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := s.RetrieveOrder(ctx, createOrderResp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Items) != 1 || order.Items[0].Quantity != testQuantity {
+		t.Fatalf("wanted %d, got %v", testQuantity, order.Items)
+	}
+}
+
+func TestSetExchangeRate(t *testing.T) {
+	ctx := context.Background()
+	dbs := setupDatabases(t, ctx)
+
+	s := store.NewStore(dbs.order.DB())
+	serv := service.NewOrderService(s, newStoreUsers(dbs.user), newStoreInventory(dbs.product), payment.NewMockGateway(payment.MockConfig{}), money.NewRateCache(s, 0), slog.Default())
+	api := NewOrderAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Put("/api/v1/admin/exchange-rate", api.SetExchangeRate)
+
+	tests := []struct {
+		req         SetExchangeRateRequest
+		permissions []string
+		want        int
+	}{
+		{req: SetExchangeRateRequest{Currency: "EUR", Rate: "0.92"}, want: http.StatusForbidden},
+		{req: SetExchangeRateRequest{Currency: "USD", Rate: "1"}, permissions: []string{auth.PermissionRatesWrite}, want: http.StatusBadRequest},
+		{req: SetExchangeRateRequest{Currency: "EUR", Rate: "-1"}, permissions: []string{auth.PermissionRatesWrite}, want: http.StatusBadRequest},
+		{req: SetExchangeRateRequest{Currency: "EUR", Rate: "0.92"}, permissions: []string{auth.PermissionRatesWrite}, want: http.StatusOK},
+	}
+	for _, test := range tests {
+		body, err := json.Marshal(test.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("PUT", "/api/v1/admin/exchange-rate", bytes.NewBuffer(body))
+		setAuthorization(t, req, 1, test.permissions...)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != test.want {
+			t.Fatalf("%+v: wanted %d, got %d", test.req, test.want, rec.Code)
+		}
+	}
+
+	rates, err := s.LoadRates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate, err := rates.Rate("USD", "EUR"); err != nil || rate.String() != "0.92" {
+		t.Fatalf("wanted %s, got %s (%v)", "0.92", rate, err)
+	}
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PseudoMera/virtual-store/order/payment"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+)
+
+type GetCartRequest struct {
+	UserID int `json:"user_id"`
+}
+
+func (o *OrderAPI) GetCart(w http.ResponseWriter, r *http.Request) {
+	var req GetCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.Authorize(r.Context(), req.UserID, auth.PermissionOrderRead); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	cart, err := o.service.GetCart(r.Context(), req.UserID)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, cart, w)
+}
+
+type CartItemRequest struct {
+	UserID    int `json:"user_id"`
+	ProductID int `json:"product_id"`
+	SKUID     int `json:"sku_id"`
+	Quantity  int `json:"quantity"`
+}
+
+func (o *OrderAPI) AddCartItem(w http.ResponseWriter, r *http.Request) {
+	var req CartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.Authorize(r.Context(), req.UserID, auth.PermissionOrderWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	cart, err := o.service.AddCartItem(r.Context(), req.UserID, req.ProductID, req.SKUID, req.Quantity)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, cart, w)
+}
+
+func (o *OrderAPI) UpdateCartItem(w http.ResponseWriter, r *http.Request) {
+	var req CartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.Authorize(r.Context(), req.UserID, auth.PermissionOrderWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	cart, err := o.service.UpdateCartItem(r.Context(), req.UserID, req.ProductID, req.SKUID, req.Quantity)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, cart, w)
+}
+
+type RemoveCartItemRequest struct {
+	UserID    int `json:"user_id"`
+	ProductID int `json:"product_id"`
+	SKUID     int `json:"sku_id"`
+}
+
+func (o *OrderAPI) RemoveCartItem(w http.ResponseWriter, r *http.Request) {
+	var req RemoveCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.Authorize(r.Context(), req.UserID, auth.PermissionOrderWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	cart, err := o.service.RemoveCartItem(r.Context(), req.UserID, req.ProductID, req.SKUID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, cart, w)
+}
+
+type ClearCartRequest struct {
+	UserID int `json:"user_id"`
+}
+
+func (o *OrderAPI) ClearCart(w http.ResponseWriter, r *http.Request) {
+	var req ClearCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.Authorize(r.Context(), req.UserID, auth.PermissionOrderWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := o.service.ClearCart(r.Context(), req.UserID); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type CheckoutCartRequest struct {
+	UserID int                `json:"user_id"`
+	Card   PaymentCardRequest `json:"card"`
+}
+
+func (o *OrderAPI) CheckoutCart(w http.ResponseWriter, r *http.Request) {
+	var req CheckoutCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.Authorize(r.Context(), req.UserID, auth.PermissionOrderWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	id, err := o.service.CheckoutCart(r.Context(), req.UserID, payment.Card{
+		Number:      req.Card.Number,
+		ExpiryMonth: req.Card.ExpiryMonth,
+		ExpiryYear:  req.Card.ExpiryYear,
+		CVC:         req.Card.CVC,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusCreated, CreateOrderResponse{
+		ID: id,
+	}, w)
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+)
+
+type SetExchangeRateRequest struct {
+	Currency string `json:"currency"`
+	Rate     string `json:"rate"`
+}
+
+// SetExchangeRate sets the exchange rate from the default currency to a currency. The caller must
+// hold the rates:write permission.
+func (o *OrderAPI) SetExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var req SetExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionRatesWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	rate, err := o.service.SetExchangeRate(r.Context(), req.Currency, req.Rate)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, rate, w)
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"github.com/PseudoMera/virtual-store/shared/metrics"
+	"github.com/PseudoMera/virtual-store/shared/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -10,6 +12,8 @@ func NewRouter() *chi.Mux {
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
+	r.Use(tracing.Middleware)
+	r.Use(metrics.Middleware)
 	r.Use(middleware.Recoverer)
 
 	return r
@@ -0,0 +1,115 @@
+package grpc
+
+import (
+	context "context"
+
+	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (os *OrderServer) GetCart(ctx context.Context, req *GetCartRequest) (*Cart, error) {
+	if err := auth.Authorize(ctx, int(req.UserID), auth.PermissionOrderRead); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	cart, err := os.service.GetCart(ctx, int(req.UserID))
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return newCart(cart), nil
+}
+
+func (os *OrderServer) AddCartItem(ctx context.Context, req *CartItemRequest) (*Cart, error) {
+	if err := auth.Authorize(ctx, int(req.UserID), auth.PermissionOrderWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	cart, err := os.service.AddCartItem(ctx, int(req.UserID), int(req.ProductID), int(req.SkuID), int(req.Quantity))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return newCart(cart), nil
+}
+
+func (os *OrderServer) UpdateCartItem(ctx context.Context, req *CartItemRequest) (*Cart, error) {
+	if err := auth.Authorize(ctx, int(req.UserID), auth.PermissionOrderWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	cart, err := os.service.UpdateCartItem(ctx, int(req.UserID), int(req.ProductID), int(req.SkuID), int(req.Quantity))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return newCart(cart), nil
+}
+
+func (os *OrderServer) RemoveCartItem(ctx context.Context, req *RemoveCartItemRequest) (*Cart, error) {
+	if err := auth.Authorize(ctx, int(req.UserID), auth.PermissionOrderWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	cart, err := os.service.RemoveCartItem(ctx, int(req.UserID), int(req.ProductID), int(req.SkuID))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return newCart(cart), nil
+}
+
+func (os *OrderServer) ClearCart(ctx context.Context, req *ClearCartRequest) (*SuccessResponse, error) {
+	if err := auth.Authorize(ctx, int(req.UserID), auth.PermissionOrderWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	if err := os.service.ClearCart(ctx, int(req.UserID)); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+func (os *OrderServer) CheckoutCart(ctx context.Context, req *CheckoutCartRequest) (*CreateOrderResponse, error) {
+	if err := auth.Authorize(ctx, int(req.UserID), auth.PermissionOrderWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	id, err := os.service.CheckoutCart(ctx, int(req.UserID), newCard(req.Card))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &CreateOrderResponse{
+		Id: int64(id),
+	}, nil
+}
+
+func newCart(cart *store.Cart) *Cart {
+	items := make([]*CartItem, len(cart.Items))
+	for i := range cart.Items {
+		items[i] = &CartItem{
+			ProductID: int64(cart.Items[i].ProductID),
+			SkuID:     int64(cart.Items[i].SKUID),
+			Quantity:  int32(cart.Items[i].Quantity),
+			Price:     cart.Items[i].Price.Proto(),
+		}
+	}
+
+	parsed := &Cart{
+		UserID:     int64(cart.UserID),
+		Currency:   cart.Currency,
+		Items:      items,
+		TotalPrice: cart.TotalPrice.Proto(),
+	}
+	if !cart.UpdatedAt.IsZero() {
+		parsed.UpdatedAt = timestamppb.New(cart.UpdatedAt)
+	}
+
+	return parsed
+}
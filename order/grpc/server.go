@@ -2,100 +2,110 @@ package grpc
 
 import (
 	context "context"
+	"errors"
+	"time"
 
+	"github.com/PseudoMera/virtual-store/order/payment"
+	"github.com/PseudoMera/virtual-store/order/service"
 	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type OrderServer struct {
-	db *store.Store
+	service *service.OrderService
 	UnimplementedOrderServiceServer
 }
 
-// NewOrderServer returns a GRPC server with the given database
-func NewOrderServer(db *store.Store) *OrderServer {
+// NewOrderServer returns a GRPC server with the given order service
+func NewOrderServer(service *service.OrderService) *OrderServer {
 	return &OrderServer{
-		db: db,
+		service: service,
 	}
 }
 
 func (os *OrderServer) CreateOrder(ctx context.Context, req *CreateOrderRequest) (*CreateOrderResponse, error) {
-	if req.UserID == 0 {
-		return nil, nil
+	if err := auth.Authorize(ctx, int(req.UserID), auth.PermissionOrderWrite); err != nil {
+		return nil, shared.GRPCError(err)
 	}
-	if req.Status == "" {
-		return nil, nil
-	}
-	if req.TotalPrice == 0.0 {
-		return nil, nil
+
+	items := make([]store.OrderItem, len(req.Items))
+	for i := range req.Items {
+		items[i] = store.OrderItem{
+			ProductID: int(req.Items[i].ProductID),
+			SKUID:     int(req.Items[i].SkuID),
+			Quantity:  int(req.Items[i].Quantity),
+		}
 	}
 
-	id, err := os.db.StoreOrder(ctx, store.Order{
-		UserID:     int(req.UserID),
-		Status:     store.OrderStatus(req.Status),
-		TotalPrice: float64(req.TotalPrice),
-	})
+	id, err := os.service.CreateOrder(ctx, int(req.UserID), items, newCard(req.Card))
+	if err != nil {
+		return nil, grpcError(err)
+	}
 
 	return &CreateOrderResponse{
 		Id: int64(id),
-	}, err
+	}, nil
 }
 
 func (os *OrderServer) GetOrder(ctx context.Context, req *GetOrderRequest) (*Order, error) {
-	if req.Id == 0 {
-		return nil, nil
+	order, err := os.service.GetCallerOrder(ctx, int(req.Id), auth.PermissionOrderRead)
+	if err != nil {
+		return nil, shared.GRPCError(err)
 	}
 
-	order, err := os.db.RetrieveOrder(ctx, int(req.Id))
-	return &Order{
-		Id:         int64(order.ID),
-		UserID:     int64(order.UserID),
-		TotalPrice: float32(order.TotalPrice),
-		Status:     string(order.Status),
-	}, err
+	return newOrder(order), nil
 }
 
 func (os *OrderServer) GetOrdersByUser(ctx context.Context, req *GetOrdersByUserRequest) (*GetOrdersByUserResponse, error) {
-	if req.UserID == 0 {
-		return nil, nil
+	if err := auth.Authorize(ctx, int(req.UserID), auth.PermissionOrderRead); err != nil {
+		return nil, shared.GRPCError(err)
 	}
 
-	orders, err := os.db.RetrieveOrdersByUserID(ctx, int(req.UserID))
+	orders, err := os.service.GetOrdersByUser(ctx, int(req.UserID), store.OrderFilter{
+		Status:        store.OrderStatus(req.Status),
+		Currency:      req.Currency,
+		CreatedAfter:  asTime(req.CreatedAfter),
+		CreatedBefore: asTime(req.CreatedBefore),
+	}, newPageRequest(req.Page))
 	if err != nil {
-		return nil, err
+		return nil, shared.GRPCError(err)
 	}
 
-	parsedOrders := make([]*Order, len(orders))
-	for i := range orders {
-		parsedOrders[i] = &Order{
-			Id:         int64(orders[i].ID),
-			UserID:     int64(orders[i].UserID),
-			TotalPrice: float32(orders[i].TotalPrice),
-			Status:     string(orders[i].Status),
-		}
+	parsedOrders := make([]*Order, len(orders.Items))
+	for i := range orders.Items {
+		parsedOrders[i] = newOrder(orders.Items[i])
 	}
 
-	return &GetOrdersByUserResponse{
-		Orders: parsedOrders,
-	}, nil
+	resp := &GetOrdersByUserResponse{
+		Orders:     parsedOrders,
+		NextCursor: orders.NextCursor,
+	}
+	if orders.Total != nil {
+		resp.Total = wrapperspb.Int64(int64(*orders.Total))
+	}
+
+	return resp, nil
 }
 
 func (os *OrderServer) UpdateOrder(ctx context.Context, req *UpdateOrderRequest) (*SuccessResponse, error) {
-	if req.Id == 0 {
-		return nil, nil
-	}
-	if req.Status == "" {
-		return nil, nil
+	if err := auth.CheckPermission(ctx, auth.PermissionOrderWrite); err != nil {
+		return nil, shared.GRPCError(err)
 	}
-	if req.TotalPrice == 0.0 {
-		return nil, nil
+
+	totalPrice, err := money.FromProto(req.TotalPrice)
+	if err != nil {
+		return nil, shared.GRPCError(shared.InvalidFieldError("totalPrice", err.Error()))
 	}
 
-	if err := os.db.UpdateOrder(ctx, int(req.Id), store.Order{
-		ID:         int(req.Id),
-		TotalPrice: float64(req.TotalPrice),
-		Status:     store.OrderStatus(req.Status),
-	}); err != nil {
-		return nil, err
+	if err := os.service.UpdateOrder(ctx, int(req.Id), store.OrderStatus(req.Status), totalPrice); err != nil {
+		return nil, grpcError(err)
 	}
 
 	return &SuccessResponse{
@@ -104,17 +114,208 @@ func (os *OrderServer) UpdateOrder(ctx context.Context, req *UpdateOrderRequest)
 }
 
 func (os *OrderServer) UpdateOrderStatus(ctx context.Context, req *UpdateOrderStatusRequest) (*SuccessResponse, error) {
-	if req.Id == 0 {
-		return nil, nil
-	}
-	if req.Status == "" {
-		return nil, nil
+	if err := auth.CheckPermission(ctx, auth.PermissionOrderStatus); err != nil {
+		return nil, shared.GRPCError(err)
 	}
-	if err := os.db.UpdateOrderStatus(ctx, int(req.Id), store.OrderStatus(req.Status)); err != nil {
-		return nil, err
+
+	if err := os.service.UpdateOrderStatus(ctx, int(req.Id), store.OrderStatus(req.Status), req.Reason); err != nil {
+		return nil, grpcError(err)
 	}
 
 	return &SuccessResponse{
 		Msg: "Success!",
 	}, nil
 }
+
+func (os *OrderServer) GetOrderStatusHistory(ctx context.Context, req *GetOrderStatusHistoryRequest) (*GetOrderStatusHistoryResponse, error) {
+	if _, err := os.service.GetCallerOrder(ctx, int(req.Id), auth.PermissionOrderRead); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	history, err := os.service.GetOrderStatusHistory(ctx, int(req.Id))
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	changes := make([]*OrderStatusChange, len(history))
+	for i, entry := range history {
+		changes[i] = &OrderStatusChange{
+			Id:        int64(entry.ID),
+			ToStatus:  string(entry.To),
+			Actor:     entry.Actor,
+			Reason:    entry.Reason,
+			CreatedAt: timestamppb.New(entry.CreatedAt),
+		}
+		if entry.From != nil {
+			changes[i].FromStatus = string(*entry.From)
+		}
+	}
+
+	return &GetOrderStatusHistoryResponse{
+		History: changes,
+	}, nil
+}
+
+func (os *OrderServer) RefundOrder(ctx context.Context, req *RefundOrderRequest) (*Refund, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionOrderRefund); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	items := make([]store.RefundItem, len(req.Items))
+	for i := range req.Items {
+		items[i] = store.RefundItem{
+			ProductID: int(req.Items[i].ProductID),
+			SKUID:     int(req.Items[i].SkuID),
+			Quantity:  int(req.Items[i].Quantity),
+		}
+	}
+
+	refund, err := os.service.RefundOrder(ctx, int(req.OrderID), items, req.Restock, req.Reason)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return newRefund(refund), nil
+}
+
+func (os *OrderServer) GetRefunds(ctx context.Context, req *GetRefundsRequest) (*GetRefundsResponse, error) {
+	if _, err := os.service.GetCallerOrder(ctx, int(req.OrderID), auth.PermissionOrderRead); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	refunds, err := os.service.GetRefunds(ctx, int(req.OrderID))
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	parsedRefunds := make([]*Refund, len(refunds))
+	for i := range refunds {
+		parsedRefunds[i] = newRefund(refunds[i])
+	}
+
+	return &GetRefundsResponse{
+		Refunds: parsedRefunds,
+	}, nil
+}
+
+// ReconcileRefund settles a refund left pending by a payment gateway timeout.
+func (os *OrderServer) ReconcileRefund(ctx context.Context, req *ReconcileRefundRequest) (*Refund, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionOrderRefund); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	refund, err := os.service.ReconcileRefund(ctx, int(req.Id))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return newRefund(refund), nil
+}
+
+// grpcError converts declined payments into FailedPrecondition errors and payment timeouts
+// into DeadlineExceeded errors, any other error is converted by shared.GRPCError.
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, payment.ErrDeclined):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, payment.ErrTimeout):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return shared.GRPCError(err)
+	}
+}
+
+func newCard(card *PaymentCard) payment.Card {
+	if card == nil {
+		return payment.Card{}
+	}
+
+	return payment.Card{
+		Number:      card.Number,
+		ExpiryMonth: int(card.ExpiryMonth),
+		ExpiryYear:  int(card.ExpiryYear),
+		CVC:         card.Cvc,
+	}
+}
+
+func newOrder(order *store.Order) *Order {
+	items := make([]*OrderItem, len(order.Items))
+	for i := range order.Items {
+		items[i] = &OrderItem{
+			ProductID: int64(order.Items[i].ProductID),
+			SkuID:     int64(order.Items[i].SKUID),
+			Quantity:  int32(order.Items[i].Quantity),
+			Price:     order.Items[i].Price.Proto(),
+			Rate:      newExchangeRate(order.Items[i].Rate),
+		}
+	}
+
+	return &Order{
+		Id:             int64(order.ID),
+		UserID:         int64(order.UserID),
+		Currency:       order.Currency,
+		TotalPrice:     order.TotalPrice.Proto(),
+		RefundedAmount: order.RefundedAmount.Proto(),
+		Status:         string(order.Status),
+		Items:          items,
+	}
+}
+
+// newExchangeRate converts the rate an item was priced at, nil when it needed no conversion.
+func newExchangeRate(rate *money.Rate) *ExchangeRate {
+	if rate == nil {
+		return nil
+	}
+
+	return &ExchangeRate{
+		From: rate.From,
+		To:   rate.To,
+		Rate: rate.String(),
+		AsOf: timestamppb.New(rate.AsOf),
+	}
+}
+
+func newRefund(refund *store.Refund) *Refund {
+	items := make([]*RefundItem, len(refund.Items))
+	for i := range refund.Items {
+		items[i] = &RefundItem{
+			ProductID: int64(refund.Items[i].ProductID),
+			SkuID:     int64(refund.Items[i].SKUID),
+			Quantity:  int32(refund.Items[i].Quantity),
+			Amount:    refund.Items[i].Amount.Proto(),
+		}
+	}
+
+	return &Refund{
+		Id:        int64(refund.ID),
+		OrderID:   int64(refund.OrderID),
+		PaymentID: int64(refund.PaymentID),
+		Amount:    refund.Amount.Proto(),
+		Status:    string(refund.Status),
+		Restock:   refund.Restock,
+		Actor:     refund.Actor,
+		Reason:    refund.Reason,
+		Items:     items,
+		CreatedAt: timestamppb.New(refund.CreatedAt),
+	}
+}
+
+// newPageRequest converts a PageRequest, nil selects the first page with the defaults.
+func newPageRequest(req *PageRequest) page.Request {
+	return page.Request{
+		Limit:     int(req.GetLimit()),
+		Cursor:    req.GetCursor(),
+		Sort:      req.GetSort(),
+		Desc:      req.GetDesc(),
+		WithTotal: req.GetWithTotal(),
+	}
+}
+
+// asTime converts an optional timestamp, nil is the zero time.
+func asTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+
+	return ts.AsTime()
+}
@@ -3,16 +3,33 @@ package main
 import (
 	"context"
 	"fmt"
-	"net"
-	"net/http"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/PseudoMera/virtual-store/order/api"
 	"github.com/PseudoMera/virtual-store/order/grpc"
+	"github.com/PseudoMera/virtual-store/order/migrations"
+	"github.com/PseudoMera/virtual-store/order/payment"
 	"github.com/PseudoMera/virtual-store/order/service"
 	"github.com/PseudoMera/virtual-store/order/store"
+	productgrpc "github.com/PseudoMera/virtual-store/product/grpc"
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/consumer"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	eventmigrations "github.com/PseudoMera/virtual-store/shared/events/migrations"
+	"github.com/PseudoMera/virtual-store/shared/health"
+	"github.com/PseudoMera/virtual-store/shared/idempotency"
+	"github.com/PseudoMera/virtual-store/shared/metrics"
+	"github.com/PseudoMera/virtual-store/shared/migrate"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/outbox"
+	"github.com/PseudoMera/virtual-store/shared/tracing"
+	usergrpc "github.com/PseudoMera/virtual-store/user/grpc"
+	"github.com/go-chi/chi/v5"
 	egrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 const (
@@ -23,38 +40,175 @@ func main() {
 	config := getConfig()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	database, err := shared.NewPostgresDatabase(ctx, config.connectionString)
+	cancel()
 	if err != nil {
 		panic(err)
 	}
-	defer cancel()
 
 	logger := shared.NewLogger()
+	migrator, err := migrate.NewMigrator(database.DB(), migrations.FS, logger)
+	if err != nil {
+		panic(err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == migrate.Command {
+		err := migrator.Run(context.Background(), os.Args[2:], os.Stdout)
+		database.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		panic(err)
+	}
+
+	exporter, err := tracing.NewExporter(context.Background(), config.tracesExporter)
+	if err != nil {
+		panic(err)
+	}
+	tracerProvider := tracing.Setup("order", exporter)
+	if err := metrics.RegisterPool(database.DB()); err != nil {
+		panic(err)
+	}
 	router := api.NewRouter()
 	store := store.NewStore(database.DB())
-	orderService := service.NewOrderService(store, logger)
-	orderAPI := api.NewOrderAPI(orderService)
+	authenticator := auth.NewAuthenticator([]byte(config.jwtSecret))
 
-	router.Post(fmt.Sprintf("%s/order", apiPath), orderAPI.CreateOrder)
-	router.Get(fmt.Sprintf("%s/order", apiPath), orderAPI.GetOrder)
-	router.Get(fmt.Sprintf("%s/user-order", apiPath), orderAPI.GetOrdersByUser)
-	router.Put(fmt.Sprintf("%s/order", apiPath), orderAPI.UpdateOrder)
-	router.Put(fmt.Sprintf("%s/order/status", apiPath), orderAPI.UpdateOrderStatus)
+	productConn, err := egrpc.Dial(config.productGRPCAddr,
+		egrpc.WithTransportCredentials(insecure.NewCredentials()),
+		egrpc.WithChainUnaryInterceptor(tracing.UnaryClientInterceptor()),
+		egrpc.WithChainStreamInterceptor(tracing.StreamClientInterceptor()),
+		egrpc.WithPerRPCCredentials(authenticator.ServiceCredentials("order", auth.PermissionProductStock)),
+	)
+	if err != nil {
+		panic(err)
+	}
 
-	lis, err := net.Listen("tcp", config.grpcServerPort)
+	userConn, err := egrpc.Dial(config.userGRPCAddr,
+		egrpc.WithTransportCredentials(insecure.NewCredentials()),
+		egrpc.WithChainUnaryInterceptor(tracing.UnaryClientInterceptor()),
+		egrpc.WithChainStreamInterceptor(tracing.StreamClientInterceptor()),
+		egrpc.WithPerRPCCredentials(authenticator.ServiceCredentials("order", auth.PermissionUserRead)),
+	)
 	if err != nil {
 		panic(err)
 	}
 
-	var opts []egrpc.ServerOption
-	grpcServer := egrpc.NewServer(opts...)
-	serviceServer := grpc.NewOrderServer(store)
-	grpc.RegisterOrderServiceServer(grpcServer, serviceServer)
+	users := service.NewUserDirectory(usergrpc.NewUserServiceClient(userConn))
+	inventory := service.NewProductInventory(productgrpc.NewProductServiceClient(productConn))
+	gateway := payment.NewMockGateway(payment.MockConfig{})
+	var rateSource money.RateSource = store
+	if config.exchangeRatesFile != "" {
+		rateSource = money.NewFileRates(config.exchangeRatesFile)
+	}
+	rates := money.NewRateCache(rateSource, config.exchangeRatesTTL)
+	orderService := service.NewOrderService(store, users, inventory, gateway, rates, logger)
+	orderAPI := api.NewOrderAPI(orderService)
+	checker := health.NewChecker(grpc.OrderService_ServiceDesc.ServiceName)
+	checker.AddCheck("postgres", database.DB().Ping)
+	checker.AddCheck("product", health.GRPCCheck(productConn, productgrpc.ProductService_ServiceDesc.ServiceName))
+	checker.AddCheck("user", health.GRPCCheck(userConn, usergrpc.UserService_ServiceDesc.ServiceName))
 
-	if err := grpcServer.Serve(lis); err != nil {
+	background, stopBackground := context.WithCancel(context.Background())
+	go checker.Watch(background, 10*time.Second)
+	go orderService.RunCartExpiry(background, time.Hour)
+	go orderService.RunRefundReconciliation(background, time.Minute)
+	go orderService.RunCheckoutRecovery(background, time.Minute)
+	eventBus := database
+	if config.eventsConnectionString != config.connectionString {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		eventBus, err = shared.NewPostgresDatabase(ctx, config.eventsConnectionString)
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+	}
+	eventsMigrator, err := migrate.NewTableMigrator(eventBus.DB(), eventmigrations.FS, eventmigrations.Table, logger)
+	if err != nil {
 		panic(err)
 	}
-
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", config.httpServerPort), router); err != nil {
+	if err := eventsMigrator.Up(context.Background()); err != nil {
 		panic(err)
 	}
+	publisher := outbox.NewLogPublisher(eventBus.DB(), outbox.Channel)
+	go publisher.RunExpiry(background, time.Hour, logger)
+	relay := outbox.NewRelay(database.DB(), "order", publisher, logger)
+	go relay.Run(background, time.Second)
+	eventConsumer := consumer.NewConsumer(database.DB(), "order", consumer.RetryPolicy{
+		MaxAttempts:    config.consumerMaxAttempts,
+		InitialBackoff: config.consumerBackoff,
+	}, logger)
+	eventConsumer.Handle(events.UserDeleted, orderService.HandleUserDeleted)
+	go eventConsumer.Run(background, eventBus.DB(), outbox.Channel, 5*time.Second)
+	go eventConsumer.RunInboxExpiry(background, time.Hour)
+
+	idempotencyKeys := idempotency.NewStore(database.DB(), config.idempotencyTTL)
+	go idempotencyKeys.RunExpiry(background, time.Hour, logger)
+
+	router.Get(health.LivenessPath, checker.Liveness)
+	router.Get(health.ReadinessPath, checker.Readiness)
+	router.Handle(metrics.Path, metrics.Handler())
+	router.Group(func(r chi.Router) {
+		r.Use(authenticator.Middleware)
+		r.With(idempotencyKeys.Middleware).Post(fmt.Sprintf("%s/order", apiPath), orderAPI.CreateOrder)
+		r.Get(fmt.Sprintf("%s/order", apiPath), orderAPI.GetOrder)
+		r.Get(fmt.Sprintf("%s/user-order", apiPath), orderAPI.GetOrdersByUser)
+		r.Put(fmt.Sprintf("%s/order", apiPath), orderAPI.UpdateOrder)
+		r.Put(fmt.Sprintf("%s/order/status", apiPath), orderAPI.UpdateOrderStatus)
+		r.Get(fmt.Sprintf("%s/order/status/history", apiPath), orderAPI.GetOrderStatusHistory)
+		r.With(idempotencyKeys.Middleware).Post(fmt.Sprintf("%s/order/refund", apiPath), orderAPI.RefundOrder)
+		r.Get(fmt.Sprintf("%s/order/refunds", apiPath), orderAPI.GetRefunds)
+		r.Post(fmt.Sprintf("%s/order/refund/reconcile", apiPath), orderAPI.ReconcileRefund)
+		r.Get(fmt.Sprintf("%s/cart", apiPath), orderAPI.GetCart)
+		r.Delete(fmt.Sprintf("%s/cart", apiPath), orderAPI.ClearCart)
+		r.Post(fmt.Sprintf("%s/cart/item", apiPath), orderAPI.AddCartItem)
+		r.Put(fmt.Sprintf("%s/cart/item", apiPath), orderAPI.UpdateCartItem)
+		r.Delete(fmt.Sprintf("%s/cart/item", apiPath), orderAPI.RemoveCartItem)
+		r.With(idempotencyKeys.Middleware).Post(fmt.Sprintf("%s/cart/checkout", apiPath), orderAPI.CheckoutCart)
+		r.Get(fmt.Sprintf("%s/admin/dead-letters", apiPath), eventConsumer.GetDeadLetters)
+		r.Post(fmt.Sprintf("%s/admin/dead-letters/replay", apiPath), eventConsumer.ReplayDeadLetter)
+		r.Put(fmt.Sprintf("%s/admin/exchange-rate", apiPath), orderAPI.SetExchangeRate)
+	})
+
+	publicMethods := health.Methods()
+	opts := []egrpc.ServerOption{
+		egrpc.ChainUnaryInterceptor(
+			tracing.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor(),
+			authenticator.UnaryServerInterceptor(publicMethods...),
+			idempotencyKeys.UnaryServerInterceptor(
+				"/order.OrderService/CreateOrder",
+				"/order.OrderService/RefundOrder",
+				"/order.OrderService/CheckoutCart",
+			),
+		),
+		egrpc.ChainStreamInterceptor(
+			tracing.StreamServerInterceptor(),
+			metrics.StreamServerInterceptor(),
+			authenticator.StreamServerInterceptor(publicMethods...),
+		),
+	}
+	grpcServer := egrpc.NewServer(opts...)
+	serviceServer := grpc.NewOrderServer(orderService)
+	grpc.RegisterOrderServiceServer(grpcServer, serviceServer)
+	checker.Register(grpcServer)
+
+	runner := shared.NewServerRunner(fmt.Sprintf(":%s", config.httpServerPort), router, fmt.Sprintf(":%s", config.grpcServerPort), grpcServer, logger)
+	runner.BeforeShutdown(checker.Shutdown)
+	runner.OnShutdown(stopBackground)
+	runner.OnShutdown(func() { productConn.Close() })
+	runner.OnShutdown(func() { userConn.Close() })
+	runner.OnShutdown(database.Close)
+	if eventBus != database {
+		runner.OnShutdown(eventBus.Close)
+	}
+	runner.OnShutdown(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			logger.Error("error at tracer shutdown", slog.String("error", err.Error()))
+		}
+	})
+	os.Exit(runner.Run(context.Background()))
 }
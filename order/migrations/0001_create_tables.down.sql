@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS idempotency_key;
+DROP TABLE IF EXISTS cart_item;
+DROP TABLE IF EXISTS cart;
+DROP TABLE IF EXISTS refund_item;
+DROP TABLE IF EXISTS refund;
+DROP TABLE IF EXISTS payment;
+DROP TABLE IF EXISTS order_status_history;
+DROP TABLE IF EXISTS user_order_product;
+DROP TABLE IF EXISTS user_order;
+
+DROP TYPE IF EXISTS refund_status;
+DROP TYPE IF EXISTS payment_outcome;
+DROP TYPE IF EXISTS payment_operation;
+DROP TYPE IF EXISTS order_status;
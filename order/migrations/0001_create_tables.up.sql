@@ -0,0 +1,115 @@
+CREATE TYPE order_status AS ENUM('pending', 'completed', 'shipped', 'cancelled');
+CREATE TYPE payment_operation AS ENUM('authorize', 'capture', 'void', 'refund');
+CREATE TYPE payment_outcome AS ENUM('approved', 'declined', 'timeout', 'failed');
+CREATE TYPE refund_status AS ENUM('pending', 'succeeded', 'failed');
+
+CREATE TABLE user_order (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id INT NOT NULL,
+    total_price NUMERIC(12, 2) NOT NULL,
+    refunded_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    status order_status DEFAULT 'pending',
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_order_product (
+    product_id INT NOT NULL,
+    user_order_id INT NOT NULL,
+    FOREIGN KEY (user_order_id) REFERENCES user_order (id) ON DELETE CASCADE,
+    quantity INT NOT NULL,
+    price NUMERIC(12, 2) NOT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE order_status_history (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_order_id INT NOT NULL,
+    FOREIGN KEY (user_order_id) REFERENCES user_order (id) ON DELETE CASCADE,
+    from_status order_status,
+    to_status order_status NOT NULL,
+    actor VARCHAR NOT NULL,
+    reason VARCHAR NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX order_status_history_user_order_id_idx ON order_status_history (user_order_id);
+
+CREATE TABLE payment (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_order_id INT NOT NULL,
+    FOREIGN KEY (user_order_id) REFERENCES user_order (id) ON DELETE CASCADE,
+    operation payment_operation NOT NULL,
+    outcome payment_outcome NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL,
+    authorization_id VARCHAR NOT NULL DEFAULT '',
+    card_last4 VARCHAR(4) NOT NULL DEFAULT '',
+    error VARCHAR NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX payment_user_order_id_idx ON payment (user_order_id);
+
+CREATE TABLE refund (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_order_id INT NOT NULL,
+    FOREIGN KEY (user_order_id) REFERENCES user_order (id) ON DELETE CASCADE,
+    payment_id INT NOT NULL,
+    FOREIGN KEY (payment_id) REFERENCES payment (id) ON DELETE CASCADE,
+    amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    status refund_status NOT NULL DEFAULT 'pending',
+    restock BOOLEAN NOT NULL DEFAULT FALSE,
+    actor VARCHAR NOT NULL,
+    reason VARCHAR NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX refund_user_order_id_idx ON refund (user_order_id);
+
+CREATE TABLE refund_item (
+    refund_id INT NOT NULL,
+    FOREIGN KEY (refund_id) REFERENCES refund (id) ON DELETE CASCADE,
+    product_id INT NOT NULL,
+    PRIMARY KEY (refund_id, product_id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount NUMERIC(12, 2) NOT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE cart (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL UNIQUE,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX cart_updated_at_idx ON cart (updated_at);
+
+CREATE TABLE cart_item (
+    cart_id INT NOT NULL,
+    FOREIGN KEY (cart_id) REFERENCES cart (id) ON DELETE CASCADE,
+    product_id INT NOT NULL,
+    PRIMARY KEY (cart_id, product_id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    price NUMERIC(12, 2) NOT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE idempotency_key (
+    scope VARCHAR NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    PRIMARY KEY (scope, key),
+    request_hash VARCHAR NOT NULL,
+    status INT,
+    response BYTEA,
+    expires_at TIMESTAMP NOT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idempotency_key_expires_at_idx ON idempotency_key (expires_at);
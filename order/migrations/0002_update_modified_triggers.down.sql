@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS update_order_modtime ON user_order;
+DROP TRIGGER IF EXISTS update_order_product_modtime ON user_order_product;
+DROP TRIGGER IF EXISTS update_payment_modtime ON payment;
+DROP TRIGGER IF EXISTS update_refund_modtime ON refund;
+DROP TRIGGER IF EXISTS update_refund_item_modtime ON refund_item;
+DROP TRIGGER IF EXISTS update_cart_modtime ON cart;
+DROP TRIGGER IF EXISTS update_cart_item_modtime ON cart_item;
+DROP TRIGGER IF EXISTS update_idempotency_key_modtime ON idempotency_key;
+
+DROP FUNCTION IF EXISTS update_modified_column();
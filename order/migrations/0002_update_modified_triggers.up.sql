@@ -0,0 +1,19 @@
+/*
+    Updates the updated_at column when a record is modified
+*/
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_order_modtime BEFORE UPDATE ON user_order FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_order_product_modtime BEFORE UPDATE ON user_order_product FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_payment_modtime BEFORE UPDATE ON payment FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_refund_modtime BEFORE UPDATE ON refund FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_refund_item_modtime BEFORE UPDATE ON refund_item FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_cart_modtime BEFORE UPDATE ON cart FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_cart_item_modtime BEFORE UPDATE ON cart_item FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_idempotency_key_modtime BEFORE UPDATE ON idempotency_key FOR EACH ROW EXECUTE FUNCTION update_modified_column();
@@ -0,0 +1,14 @@
+ALTER TABLE refund_item DROP CONSTRAINT refund_item_pkey;
+UPDATE refund_item a SET quantity = s.quantity, amount = s.amount FROM (
+    SELECT refund_id, MIN(sku_id) AS sku_id, SUM(quantity) AS quantity, SUM(amount) AS amount FROM refund_item GROUP BY refund_id, product_id
+) s WHERE a.refund_id = s.refund_id AND a.sku_id = s.sku_id;
+DELETE FROM refund_item a USING refund_item b WHERE a.refund_id = b.refund_id AND a.product_id = b.product_id AND a.sku_id > b.sku_id;
+ALTER TABLE refund_item DROP COLUMN sku_id;
+ALTER TABLE refund_item ADD PRIMARY KEY (refund_id, product_id);
+
+ALTER TABLE cart_item DROP CONSTRAINT cart_item_pkey;
+DELETE FROM cart_item a USING cart_item b WHERE a.cart_id = b.cart_id AND a.product_id = b.product_id AND a.sku_id > b.sku_id;
+ALTER TABLE cart_item DROP COLUMN sku_id;
+ALTER TABLE cart_item ADD PRIMARY KEY (cart_id, product_id);
+
+ALTER TABLE user_order_product DROP COLUMN sku_id;
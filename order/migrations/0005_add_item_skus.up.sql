@@ -0,0 +1,19 @@
+/*
+    Order, cart and refund lines point at a SKU of their product. The SKUs the product service
+    created for its existing products share their product id.
+*/
+ALTER TABLE user_order_product ADD COLUMN sku_id INT;
+UPDATE user_order_product SET sku_id = product_id;
+ALTER TABLE user_order_product ALTER COLUMN sku_id SET NOT NULL;
+
+ALTER TABLE cart_item ADD COLUMN sku_id INT;
+UPDATE cart_item SET sku_id = product_id;
+ALTER TABLE cart_item ALTER COLUMN sku_id SET NOT NULL;
+ALTER TABLE cart_item DROP CONSTRAINT cart_item_pkey;
+ALTER TABLE cart_item ADD PRIMARY KEY (cart_id, sku_id);
+
+ALTER TABLE refund_item ADD COLUMN sku_id INT;
+UPDATE refund_item SET sku_id = product_id;
+ALTER TABLE refund_item ALTER COLUMN sku_id SET NOT NULL;
+ALTER TABLE refund_item DROP CONSTRAINT refund_item_pkey;
+ALTER TABLE refund_item ADD PRIMARY KEY (refund_id, sku_id);
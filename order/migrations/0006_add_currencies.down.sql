@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS exchange_rate;
+
+ALTER TABLE cart DROP COLUMN IF EXISTS currency;
+ALTER TABLE user_order_product DROP COLUMN IF EXISTS rate_as_of;
+ALTER TABLE user_order_product DROP COLUMN IF EXISTS rate;
+ALTER TABLE user_order_product DROP COLUMN IF EXISTS rate_from;
+ALTER TABLE user_order DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,22 @@
+/*
+    Orders and carts are in the currency of their customer, every amount of an order and of its
+    items, payments and refunds is in it. Order lines priced by converting the price of their SKU
+    keep the exchange rate from the currency of the SKU and when it was published.
+*/
+ALTER TABLE user_order ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE user_order_product ADD COLUMN rate_from CHAR(3);
+ALTER TABLE user_order_product ADD COLUMN rate NUMERIC(18, 8) CHECK (rate > 0);
+ALTER TABLE user_order_product ADD COLUMN rate_as_of TIMESTAMP;
+ALTER TABLE cart ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+/*
+    Exchange rates from USD, one dollar is worth rate units of the currency. They are loaded when
+    no exchange rates file is configured.
+*/
+CREATE TABLE exchange_rate (
+    currency CHAR(3) PRIMARY KEY,
+    rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_exchange_rate_modtime BEFORE UPDATE ON exchange_rate FOR EACH ROW EXECUTE FUNCTION update_modified_column();
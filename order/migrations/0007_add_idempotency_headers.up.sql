@@ -0,0 +1,5 @@
+/*
+    Headers set by the handler of a request sent with an idempotency key, such as Content-Type,
+    replayed with its stored response.
+*/
+ALTER TABLE idempotency_key ADD COLUMN headers JSONB;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS user_order_user_id_total_price_id_idx;
+DROP INDEX IF EXISTS user_order_user_id_created_at_id_idx;
+DROP INDEX IF EXISTS user_order_user_id_id_idx;
@@ -0,0 +1,7 @@
+/*
+    Lists of orders are filtered by user and paginated by keyset on their sort field and id, see
+    the page package. Each sort field has an index matching its ORDER BY.
+*/
+CREATE INDEX user_order_user_id_id_idx ON user_order (user_id, id);
+CREATE INDEX user_order_user_id_created_at_id_idx ON user_order (user_id, created_at, id);
+CREATE INDEX user_order_user_id_total_price_id_idx ON user_order (user_id, total_price, id);
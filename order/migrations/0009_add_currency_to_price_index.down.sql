@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_order_user_id_currency_total_price_id_idx;
+CREATE INDEX user_order_user_id_total_price_id_idx ON user_order (user_id, total_price, id);
@@ -0,0 +1,6 @@
+/*
+    Sorting orders by total price requires a currency filter, totals of different currencies
+    cannot be compared, so the index of that sort key starts with the currency.
+*/
+DROP INDEX IF EXISTS user_order_user_id_total_price_id_idx;
+CREATE INDEX user_order_user_id_currency_total_price_id_idx ON user_order (user_id, currency, total_price, id);
@@ -0,0 +1,11 @@
+// Package migrations embeds the database migrations of the order service.
+// Migrations are named <version>_<name>.up.sql and <version>_<name>.down.sql and are applied
+// in version order by the migrate package.
+package migrations
+
+import "embed"
+
+// FS holds every up and down migration.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,189 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+// Test card numbers understood by the MockGateway.
+const (
+	CardApproved = "4242424242424242"
+	CardDeclined = "4000000000000002"
+	CardTimeout  = "4000000000000119"
+)
+
+const defaultMockTimeout = 30 * time.Second
+
+// MockConfig decides the outcome of the payments sent to a MockGateway.
+// Cards in DeclineCards or TimeoutCards, the CardDeclined and CardTimeout test cards
+// and amounts above DeclineAbove or TimeoutAbove in the same currency are declined
+// or time out, any other authorization is approved. Zero thresholds are disabled.
+type MockConfig struct {
+	DeclineCards []string
+	TimeoutCards []string
+	DeclineAbove money.Money
+	TimeoutAbove money.Money
+	// Timeout is how long a timing out call blocks if the context has no earlier deadline.
+	// Defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+type mockAuthorization struct {
+	amount   money.Money
+	captured money.Money
+	refunded money.Money
+	voided   bool
+}
+
+// MockGateway is an in memory PaymentGateway for local development and tests.
+type MockGateway struct {
+	config MockConfig
+
+	mu             sync.Mutex
+	authorizations map[string]*mockAuthorization
+	// refunds holds the references of the refunds that were made.
+	refunds map[string]bool
+}
+
+// NewMockGateway returns a MockGateway with the given configuration.
+func NewMockGateway(config MockConfig) *MockGateway {
+	if config.Timeout == 0 {
+		config.Timeout = defaultMockTimeout
+	}
+
+	return &MockGateway{
+		config:         config,
+		authorizations: make(map[string]*mockAuthorization),
+		refunds:        make(map[string]bool),
+	}
+}
+
+func (m *MockGateway) Authorize(ctx context.Context, req AuthorizeRequest) (string, error) {
+	if !req.Amount.IsPositive() {
+		return "", ErrInvalidAmount
+	}
+	if m.timesOut(req) {
+		return "", m.wait(ctx)
+	}
+	if m.declines(req) {
+		return "", ErrDeclined
+	}
+
+	id, err := newAuthorizationID()
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.authorizations[id] = &mockAuthorization{
+		amount:   req.Amount,
+		captured: money.New(0, req.Amount.Currency),
+		refunded: money.New(0, req.Amount.Currency),
+	}
+
+	return id, nil
+}
+
+func (m *MockGateway) Capture(ctx context.Context, authorizationID string, amount money.Money) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	authorization, ok := m.authorizations[authorizationID]
+	if !ok {
+		return ErrUnknownAuthorization
+	}
+	if authorization.voided || authorization.captured.IsPositive() {
+		return ErrInvalidState
+	}
+	if !amount.IsPositive() || !amount.SameCurrency(authorization.amount) || amount.Cmp(authorization.amount) > 0 {
+		return ErrInvalidAmount
+	}
+	authorization.captured = amount
+
+	return nil
+}
+
+func (m *MockGateway) Void(ctx context.Context, authorizationID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	authorization, ok := m.authorizations[authorizationID]
+	if !ok {
+		return ErrUnknownAuthorization
+	}
+	if authorization.captured.IsPositive() {
+		return ErrInvalidState
+	}
+	authorization.voided = true
+
+	return nil
+}
+
+func (m *MockGateway) Refund(ctx context.Context, authorizationID, reference string, amount money.Money) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.refunds[reference] {
+		return nil
+	}
+	authorization, ok := m.authorizations[authorizationID]
+	if !ok {
+		return ErrUnknownAuthorization
+	}
+	if authorization.captured.IsZero() {
+		return ErrInvalidState
+	}
+	if !amount.IsPositive() || !amount.SameCurrency(authorization.captured) || authorization.refunded.Add(amount).Cmp(authorization.captured) > 0 {
+		return ErrInvalidAmount
+	}
+	authorization.refunded = authorization.refunded.Add(amount)
+	m.refunds[reference] = true
+
+	return nil
+}
+
+func (m *MockGateway) declines(req AuthorizeRequest) bool {
+	return req.Card.Number == CardDeclined ||
+		slices.Contains(m.config.DeclineCards, req.Card.Number) ||
+		exceeds(req.Amount, m.config.DeclineAbove)
+}
+
+func (m *MockGateway) timesOut(req AuthorizeRequest) bool {
+	return req.Card.Number == CardTimeout ||
+		slices.Contains(m.config.TimeoutCards, req.Card.Number) ||
+		exceeds(req.Amount, m.config.TimeoutAbove)
+}
+
+// wait blocks like an unresponsive gateway until ctx is done or the configured timeout elapses.
+func (m *MockGateway) wait(ctx context.Context) error {
+	timer := time.NewTimer(m.config.Timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+
+	return ErrTimeout
+}
+
+// exceeds reports whether amount is above a threshold of the same currency, a zero threshold is disabled.
+func exceeds(amount, threshold money.Money) bool {
+	return threshold.IsPositive() && amount.SameCurrency(threshold) && amount.Cmp(threshold) > 0
+}
+
+func newAuthorizationID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "auth_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,114 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+func TestMockGatewayOutcomes(t *testing.T) {
+	gateway := NewMockGateway(MockConfig{
+		DeclineCards: []string{"1111"},
+		DeclineAbove: money.MustParse("1000", ""),
+		TimeoutAbove: money.MustParse("5000", ""),
+		Timeout:      10 * time.Millisecond,
+	})
+
+	tests := []struct {
+		name   string
+		number string
+		amount string
+		want   error
+	}{
+		{"approved", CardApproved, "10", nil},
+		{"declined test card", CardDeclined, "10", ErrDeclined},
+		{"declined configured card", "1111", "10", ErrDeclined},
+		{"declined amount", CardApproved, "1001", ErrDeclined},
+		{"timeout test card", CardTimeout, "10", ErrTimeout},
+		{"timeout amount", CardApproved, "5001", ErrTimeout},
+		{"invalid amount", CardApproved, "0", ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		id, err := gateway.Authorize(context.Background(), AuthorizeRequest{
+			OrderID: 1,
+			Amount:  money.MustParse(tt.amount, ""),
+			Card:    Card{Number: tt.number},
+		})
+		if !errors.Is(err, tt.want) {
+			t.Fatalf("%s: wanted %v, got %v", tt.name, tt.want, err)
+		}
+		if tt.want == nil && id == "" {
+			t.Fatalf("%s: wanted an authorization id", tt.name)
+		}
+	}
+}
+
+func TestMockGatewayTimeoutHonoursContext(t *testing.T) {
+	gateway := NewMockGateway(MockConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := gateway.Authorize(ctx, AuthorizeRequest{Amount: money.MustParse("10", ""), Card: Card{Number: CardTimeout}})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("wanted %v, got %v", ErrTimeout, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("wanted the call to return with the context, took %s", elapsed)
+	}
+}
+
+func TestMockGatewayLifecycle(t *testing.T) {
+	ctx := context.Background()
+	gateway := NewMockGateway(MockConfig{})
+
+	id, err := gateway.Authorize(ctx, AuthorizeRequest{Amount: money.MustParse("100", ""), Card: Card{Number: CardApproved}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gateway.Refund(ctx, id, "first", money.MustParse("10", "")); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidState, err)
+	}
+	if err := gateway.Capture(ctx, id, money.MustParse("101", "")); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidAmount, err)
+	}
+	if err := gateway.Capture(ctx, id, money.MustParse("100", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Void(ctx, id); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidState, err)
+	}
+	if err := gateway.Refund(ctx, id, "first", money.MustParse("60.1", "")); err != nil {
+		t.Fatal(err)
+	}
+	// Resending a refund does not refund it again.
+	if err := gateway.Refund(ctx, id, "first", money.MustParse("60.1", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Refund(ctx, id, "second", money.MustParse("39.9", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Refund(ctx, id, "third", money.MustParse("0.01", "")); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidAmount, err)
+	}
+
+	voided, err := gateway.Authorize(ctx, AuthorizeRequest{Amount: money.MustParse("100", ""), Card: Card{Number: CardApproved}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Void(ctx, voided); err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.Capture(ctx, voided, money.MustParse("100", "")); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidState, err)
+	}
+	if err := gateway.Void(ctx, "missing"); !errors.Is(err, ErrUnknownAuthorization) {
+		t.Fatalf("wanted %v, got %v", ErrUnknownAuthorization, err)
+	}
+}
@@ -0,0 +1,60 @@
+package payment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+var (
+	// ErrDeclined is returned when the gateway refuses a payment.
+	ErrDeclined = errors.New("payment declined")
+	// ErrTimeout is returned when the gateway does not answer in time.
+	// The outcome of the operation is unknown.
+	ErrTimeout = errors.New("payment gateway timed out")
+	// ErrUnknownAuthorization is returned for operations on an authorization the gateway does not know.
+	ErrUnknownAuthorization = errors.New("unknown payment authorization")
+	// ErrInvalidAmount is returned when an amount is not positive or exceeds what can be captured or refunded.
+	ErrInvalidAmount = errors.New("invalid payment amount")
+	// ErrInvalidState is returned when an operation is not allowed for the current state of an authorization,
+	// such as capturing a voided authorization.
+	ErrInvalidState = errors.New("operation not allowed for the payment state")
+)
+
+// Card is the payment method of an order.
+type Card struct {
+	Number      string
+	ExpiryMonth int
+	ExpiryYear  int
+	CVC         string
+}
+
+// Last4 returns the last four digits of the card number, the only part of it that is ever stored.
+func (c Card) Last4() string {
+	if len(c.Number) < 4 {
+		return c.Number
+	}
+
+	return c.Number[len(c.Number)-4:]
+}
+
+// AuthorizeRequest holds the amount to hold on a card for an order.
+type AuthorizeRequest struct {
+	OrderID int
+	Amount  money.Money
+	Card    Card
+}
+
+// PaymentGateway authorizes and settles card payments.
+// Authorize holds the amount on the card, Capture settles all or part of the held amount,
+// Void releases an authorization that was not captured and Refund gives back all or part
+// of the captured amount. Authorizations are identified by the ID returned by Authorize.
+// A refund is made once per reference, sending it again with the same reference returns
+// its outcome without refunding again, so a refund whose outcome is unknown can be resent.
+type PaymentGateway interface {
+	Authorize(ctx context.Context, req AuthorizeRequest) (string, error)
+	Capture(ctx context.Context, authorizationID string, amount money.Money) error
+	Void(ctx context.Context, authorizationID string) error
+	Refund(ctx context.Context, authorizationID, reference string, amount money.Money) error
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/order/payment"
+	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/jackc/pgx/v5"
+)
+
+// CartIdleTimeout is how long a cart can go without changes before it expires.
+const CartIdleTimeout = 72 * time.Hour
+
+var (
+	// ErrCartEmpty is returned when checking out a cart without items.
+	ErrCartEmpty = shared.ConflictError("cart is empty")
+	// ErrCartPriceChanged is returned when checking out a cart whose prices changed since the items were added.
+	// The cart is updated with the current prices so it can be reviewed and checked out again.
+	ErrCartPriceChanged = shared.ConflictError("cart prices changed, review the cart before checking out")
+	// ErrCartItemNotFound is returned when removing a product or SKU that is not in the cart.
+	ErrCartItemNotFound = shared.NotFoundError("product is not in the cart")
+)
+
+// GetCart returns the cart of the given user, an empty one if the user has none or it expired.
+func (o *OrderService) GetCart(ctx context.Context, userID int) (*store.Cart, error) {
+	if userID == 0 {
+		o.logger.InfoContext(ctx, "error at GetCart", slog.String("error", errEmptyUserID.Error()))
+		return nil, errEmptyUserID
+	}
+
+	return o.activeCart(ctx, userID)
+}
+
+// AddCartItem adds quantity units of a SKU to the cart of the given user and returns the cart.
+// The SKU is given by id or, for a product without options, by the id of its product.
+// The price is taken from the product service in the currency of the user, like the prices of an
+// order, and the new quantity must be in stock.
+func (o *OrderService) AddCartItem(ctx context.Context, userID, productID, skuID, quantity int) (*store.Cart, error) {
+	if err := validateCartItem(userID, productID, skuID); err != nil {
+		o.logger.InfoContext(ctx, "error at AddCartItem", slog.String("error", err.Error()))
+		return nil, err
+	}
+	if quantity <= 0 {
+		o.logger.InfoContext(ctx, "error at AddCartItem", slog.String("error", errInvalidQuantity.Error()))
+		return nil, errInvalidQuantity
+	}
+
+	cart, err := o.activeCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sku, err := o.getSKU(ctx, skuID, productID)
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at AddCartItem", slog.String("error", err.Error()))
+		return nil, err
+	}
+	for _, item := range cart.Items {
+		if item.SKUID == sku.ID {
+			quantity += item.Quantity
+			break
+		}
+	}
+
+	if err := o.setCartItem(ctx, cart, sku, quantity); err != nil {
+		o.logger.InfoContext(ctx, "error at AddCartItem", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return o.db.RetrieveCart(ctx, userID)
+}
+
+// UpdateCartItem sets the quantity of a SKU in the cart of the given user and returns the cart.
+// The SKU is given like in AddCartItem, a zero quantity removes it from the cart.
+func (o *OrderService) UpdateCartItem(ctx context.Context, userID, productID, skuID, quantity int) (*store.Cart, error) {
+	if quantity == 0 {
+		return o.RemoveCartItem(ctx, userID, productID, skuID)
+	}
+	if err := validateCartItem(userID, productID, skuID); err != nil {
+		o.logger.InfoContext(ctx, "error at UpdateCartItem", slog.String("error", err.Error()))
+		return nil, err
+	}
+	if quantity < 0 {
+		o.logger.InfoContext(ctx, "error at UpdateCartItem", slog.String("error", errInvalidQuantity.Error()))
+		return nil, errInvalidQuantity
+	}
+
+	cart, err := o.activeCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sku, err := o.getSKU(ctx, skuID, productID)
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at UpdateCartItem", slog.String("error", err.Error()))
+		return nil, err
+	}
+	if err := o.setCartItem(ctx, cart, sku, quantity); err != nil {
+		o.logger.InfoContext(ctx, "error at UpdateCartItem", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return o.db.RetrieveCart(ctx, userID)
+}
+
+// RemoveCartItem removes a SKU from the cart of the given user and returns the cart.
+// The SKU is given by id or by the id of its product, which must then have a single SKU in the
+// cart or ErrSKURequired is returned.
+func (o *OrderService) RemoveCartItem(ctx context.Context, userID, productID, skuID int) (*store.Cart, error) {
+	if err := validateCartItem(userID, productID, skuID); err != nil {
+		o.logger.InfoContext(ctx, "error at RemoveCartItem", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	cart, err := o.activeCart(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if skuID == 0 {
+		for _, item := range cart.Items {
+			if item.ProductID != productID {
+				continue
+			}
+			if skuID != 0 {
+				o.logger.InfoContext(ctx, "error at RemoveCartItem", slog.String("error", ErrSKURequired.Error()))
+				return nil, ErrSKURequired
+			}
+			skuID = item.SKUID
+		}
+	}
+	err = o.db.DeleteCartItem(ctx, userID, skuID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		o.logger.InfoContext(ctx, "error at RemoveCartItem", slog.String("error", ErrCartItemNotFound.Error()))
+		return nil, ErrCartItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return o.db.RetrieveCart(ctx, userID)
+}
+
+// ClearCart removes the cart of the given user.
+func (o *OrderService) ClearCart(ctx context.Context, userID int) error {
+	if userID == 0 {
+		o.logger.InfoContext(ctx, "error at ClearCart", slog.String("error", errEmptyUserID.Error()))
+		return errEmptyUserID
+	}
+
+	return o.db.DeleteCart(ctx, userID)
+}
+
+// CheckoutCart creates an order with the items of the cart of the given user and pays it like CreateOrder.
+// The cart prices are checked against the product service first, in the current currency of the
+// user, if any changed the cart is updated and ErrCartPriceChanged is returned. The ordered items are
+// removed from the cart once the order is completed.
+func (o *OrderService) CheckoutCart(ctx context.Context, userID int, card payment.Card) (int, error) {
+	if userID == 0 {
+		o.logger.InfoContext(ctx, "error at CheckoutCart", slog.String("error", errEmptyUserID.Error()))
+		return 0, errEmptyUserID
+	}
+
+	cart, err := o.activeCart(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if len(cart.Items) == 0 {
+		o.logger.InfoContext(ctx, "error at CheckoutCart", slog.String("error", ErrCartEmpty.Error()))
+		return 0, ErrCartEmpty
+	}
+
+	currency, err := o.users.Currency(ctx, userID)
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at CheckoutCart", slog.String("error", err.Error()))
+		return 0, err
+	}
+	changed, err := o.repriceCart(ctx, cart, currency, 0)
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at CheckoutCart", slog.String("error", err.Error()))
+		return 0, err
+	}
+	if len(changed) > 0 {
+		if err := o.db.StoreCartItems(ctx, userID, currency, changed...); err != nil {
+			return 0, err
+		}
+		o.logger.InfoContext(ctx, "error at CheckoutCart", slog.String("error", ErrCartPriceChanged.Error()))
+		return 0, ErrCartPriceChanged
+	}
+
+	items := make([]store.OrderItem, len(cart.Items))
+	for i, item := range cart.Items {
+		items[i] = store.OrderItem{
+			ProductID: item.ProductID,
+			SKUID:     item.SKUID,
+			Quantity:  item.Quantity,
+		}
+	}
+
+	id, err := o.CreateOrder(ctx, userID, items, card)
+	if err != nil {
+		return 0, err
+	}
+
+	// Items added or changed during the checkout were not ordered and stay in the cart.
+	if err := o.db.DeleteOrderedCartItems(ctx, userID, currency, cart.Items...); err != nil {
+		o.logger.ErrorContext(ctx, "error at CheckoutCart", slog.Int("order", id), slog.String("error", err.Error()))
+	}
+
+	return id, nil
+}
+
+// ExpireCarts removes the carts that were idle for longer than CartIdleTimeout and returns how many were removed.
+func (o *OrderService) ExpireCarts(ctx context.Context) (int64, error) {
+	return o.db.DeleteIdleCarts(ctx, CartIdleTimeout)
+}
+
+// RunCartExpiry expires idle carts every interval until ctx is done.
+func (o *OrderService) RunCartExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := o.ExpireCarts(ctx)
+			if err != nil {
+				o.logger.ErrorContext(ctx, "error at RunCartExpiry", slog.String("error", err.Error()))
+				continue
+			}
+			if expired > 0 {
+				o.logger.InfoContext(ctx, "expired idle carts", slog.Int64("carts", expired))
+			}
+		}
+	}
+}
+
+// activeCart returns the cart of the given user, removing it first if it expired.
+func (o *OrderService) activeCart(ctx context.Context, userID int) (*store.Cart, error) {
+	if _, err := o.db.DeleteIdleCarts(ctx, CartIdleTimeout, userID); err != nil {
+		return nil, err
+	}
+
+	cart, err := o.db.RetrieveCart(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &store.Cart{UserID: userID}, nil
+	}
+
+	return cart, err
+}
+
+// setCartItem stores a SKU in the cart with its current price in the currency of the user after
+// checking the user and the SKU stock. If the currency of the user changed the other items of the
+// cart are priced in it too.
+func (o *OrderService) setCartItem(ctx context.Context, cart *store.Cart, sku *SKU, quantity int) error {
+	if err := o.users.CheckUser(ctx, cart.UserID); err != nil {
+		return err
+	}
+	if sku.Stock < quantity {
+		return ErrInsufficientStock
+	}
+
+	currency, err := o.users.Currency(ctx, cart.UserID)
+	if err != nil {
+		return err
+	}
+	price, _, err := o.skuPrice(ctx, sku, currency)
+	if err != nil {
+		return err
+	}
+	items := []store.CartItem{{
+		ProductID: sku.ProductID,
+		SKUID:     sku.ID,
+		Quantity:  quantity,
+		Price:     price,
+	}}
+	if cart.Currency != "" && cart.Currency != currency {
+		repriced, err := o.repriceCart(ctx, cart, currency, sku.ID)
+		if err != nil {
+			return err
+		}
+		items = append(items, repriced...)
+	}
+
+	return o.db.StoreCartItems(ctx, cart.UserID, currency, items...)
+}
+
+// repriceCart returns the items of the cart, except the given SKU, whose current price in the given
+// currency is not the price they were added at, with their current price.
+func (o *OrderService) repriceCart(ctx context.Context, cart *store.Cart, currency string, skipSKUID int) ([]store.CartItem, error) {
+	var changed []store.CartItem
+	for _, item := range cart.Items {
+		if item.SKUID == skipSKUID {
+			continue
+		}
+		sku, err := o.getSKU(ctx, item.SKUID, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		price, _, err := o.skuPrice(ctx, sku, currency)
+		if err != nil {
+			return nil, err
+		}
+		if price != item.Price {
+			item.Price = price
+			changed = append(changed, item)
+		}
+	}
+
+	return changed, nil
+}
+
+func validateCartItem(userID, productID, skuID int) error {
+	if userID == 0 {
+		return errEmptyUserID
+	}
+	if productID == 0 && skuID == 0 {
+		return errEmptyProductID
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PseudoMera/virtual-store/order/payment"
+	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+func TestCartCheckout(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+	second := storeTestProduct(t, ctx, pStore, "second")
+
+	if _, err := serv.CheckoutCart(ctx, userID, approvedCard); !errors.Is(err, ErrCartEmpty) {
+		t.Fatalf("wanted %v, got %v", ErrCartEmpty, err)
+	}
+
+	if _, err := serv.AddCartItem(ctx, userID, first, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := serv.AddCartItem(ctx, userID, first, 0, testProductStock); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("wanted %v, got %v", ErrInsufficientStock, err)
+	}
+	if _, err := serv.AddCartItem(ctx, userID, second, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := serv.UpdateCartItem(ctx, userID, second, 0, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := serv.GetCart(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cart.Items) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(cart.Items))
+	}
+	if cart.TotalPrice != money.MustParse("50", "") {
+		t.Fatalf("wanted %d, got %s", 50, cart.TotalPrice)
+	}
+
+	id, err := serv.CheckoutCart(ctx, userID, approvedCard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := serv.GetOrder(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != store.Completed || order.TotalPrice != cart.TotalPrice {
+		t.Fatalf("wanted %s %s, got %s %s", store.Completed, cart.TotalPrice, order.Status, order.TotalPrice)
+	}
+	assertStock(t, ctx, pStore, first, testProductStock-2)
+	assertStock(t, ctx, pStore, second, testProductStock-3)
+
+	cart, err = serv.GetCart(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cart.Items) != 0 {
+		t.Fatalf("wanted %d, got %d", 0, len(cart.Items))
+	}
+}
+
+func TestCartCheckoutPriceChanged(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	if _, err := serv.AddCartItem(ctx, userID, first, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	product, err := pStore.RetrieveProduct(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product.Price = money.MustParse("12.5", "")
+	if err := pStore.UpdateProduct(ctx, *product); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := serv.CheckoutCart(ctx, userID, approvedCard); !errors.Is(err, ErrCartPriceChanged) {
+		t.Fatalf("wanted %v, got %v", ErrCartPriceChanged, err)
+	}
+
+	cart, err := serv.GetCart(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.TotalPrice != money.MustParse("12.5", "") {
+		t.Fatalf("wanted %s, got %s", "12.50", cart.TotalPrice)
+	}
+
+	// The reviewed cart can be checked out.
+	if _, err := serv.CheckoutCart(ctx, userID, approvedCard); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoveCartItem(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	if _, err := serv.AddCartItem(ctx, userID, first, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := serv.UpdateCartItem(ctx, userID, first, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cart.Items) != 0 {
+		t.Fatalf("wanted %d, got %d", 0, len(cart.Items))
+	}
+
+	if _, err := serv.RemoveCartItem(ctx, userID, first, 0); !errors.Is(err, ErrCartItemNotFound) {
+		t.Fatalf("wanted %v, got %v", ErrCartItemNotFound, err)
+	}
+	if _, err := serv.AddCartItem(ctx, userID, first+1, 0, 1); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("wanted %v, got %v", ErrProductNotFound, err)
+	}
+}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/order/migrations"
+	"github.com/PseudoMera/virtual-store/order/payment"
+	"github.com/PseudoMera/virtual-store/order/store"
+	productgrpc "github.com/PseudoMera/virtual-store/product/grpc"
+	productmigrations "github.com/PseudoMera/virtual-store/product/migrations"
+	productStore "github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
+	usergrpc "github.com/PseudoMera/virtual-store/user/grpc"
+	usermigrations "github.com/PseudoMera/virtual-store/user/migrations"
+	userStore "github.com/PseudoMera/virtual-store/user/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	testProductStock = 5
+)
+
+var approvedCard = payment.Card{
+	Number: payment.CardApproved,
+}
+
+// setupCheckout starts the product and user gRPC servers over an in memory listener, each with
+// its own database, and returns an order service checking users and reserving stock through them
+// and paying with a mock gateway alongside the product store and the id of a stored user.
+func setupCheckout(t *testing.T, ctx context.Context, config payment.MockConfig) (*OrderService, *productStore.Store, int) {
+	t.Helper()
+
+	dbs, _, err := shared.SetupPostgresClients(ctx, migrations.FS, productmigrations.FS, usermigrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orderDB, productDB, userDB := dbs[0], dbs[1], dbs[2]
+
+	authenticator := auth.NewAuthenticator([]byte("testSecret"))
+	pStore := productStore.NewStore(productDB.DB())
+	uStore := userStore.NewStore(userDB.DB())
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.UnaryInterceptor(authenticator.UnaryServerInterceptor()))
+	productgrpc.RegisterProductServiceServer(server, productgrpc.NewProductServer(pStore))
+	usergrpc.RegisterUserServiceServer(server, usergrpc.NewUserServer(uStore, nil))
+	go server.Serve(lis) //nolint:errcheck
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(authenticator.ServiceCredentials("order", auth.PermissionProductStock, auth.PermissionUserRead)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	userID, err := uStore.StoreUser(ctx, userStore.User{
+		Email:    "email",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := NewUserDirectory(usergrpc.NewUserServiceClient(conn))
+	inventory := NewProductInventory(productgrpc.NewProductServiceClient(conn))
+	gateway := payment.NewMockGateway(config)
+	orderStore := store.NewStore(orderDB.DB())
+	return NewOrderService(orderStore, users, inventory, gateway, money.NewRateCache(orderStore, 0), slog.Default()), pStore, userID
+}
+
+func storeTestProduct(t *testing.T, ctx context.Context, pStore *productStore.Store, name string) int {
+	t.Helper()
+
+	id, err := pStore.StoreProduct(ctx, productStore.Product{
+		Name:  name,
+		Price: money.MustParse("10", ""),
+		Stock: testProductStock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func assertPayments(t *testing.T, ctx context.Context, serv *OrderService, orderID int, operations []store.PaymentOperation, outcomes []store.PaymentOutcome) {
+	t.Helper()
+
+	payments, err := serv.db.RetrievePaymentsByOrderID(ctx, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != len(operations) {
+		t.Fatalf("wanted %d, got %d", len(operations), len(payments))
+	}
+	for i := range payments {
+		if payments[i].Operation != operations[i] || payments[i].Outcome != outcomes[i] {
+			t.Fatalf("wanted %s %s, got %s %s", operations[i], outcomes[i], payments[i].Operation, payments[i].Outcome)
+		}
+	}
+}
+
+func assertStock(t *testing.T, ctx context.Context, pStore *productStore.Store, productID, want int) {
+	t.Helper()
+
+	product, err := pStore.RetrieveProduct(ctx, productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Stock != want {
+		t.Fatalf("wanted %d, got %d", want, product.Stock)
+	}
+}
+
+func TestCheckoutReservesStock(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+	second := storeTestProduct(t, ctx, pStore, "second")
+
+	id, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{ProductID: first, Quantity: 2},
+		{ProductID: second, Quantity: testProductStock},
+	}, approvedCard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStock(t, ctx, pStore, first, testProductStock-2)
+	assertStock(t, ctx, pStore, second, 0)
+
+	order, err := serv.GetOrder(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != store.Completed {
+		t.Fatalf("wanted %s, got %s", store.Completed, order.Status)
+	}
+
+	assertPayments(t, ctx, serv, id, []store.PaymentOperation{store.PaymentAuthorize, store.PaymentCapture}, []store.PaymentOutcome{store.PaymentApproved, store.PaymentApproved})
+}
+
+func TestCheckoutCompensatesOnDeclinedPayment(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	_, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{ProductID: first, Quantity: 2},
+	}, payment.Card{Number: payment.CardDeclined})
+	if !errors.Is(err, payment.ErrDeclined) {
+		t.Fatalf("wanted %v, got %v", payment.ErrDeclined, err)
+	}
+
+	assertStock(t, ctx, pStore, first, testProductStock)
+
+	orders, err := serv.GetOrdersByUser(ctx, userID, store.OrderFilter{}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orders.Items[0].Status != store.Cancelled {
+		t.Fatalf("wanted %s, got %s", store.Cancelled, orders.Items[0].Status)
+	}
+
+	assertPayments(t, ctx, serv, orders.Items[0].ID, []store.PaymentOperation{store.PaymentAuthorize}, []store.PaymentOutcome{store.PaymentDeclined})
+}
+
+func TestCheckoutCompensatesOnPaymentTimeout(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{
+		TimeoutAbove: money.MustParse("15", ""),
+		Timeout:      50 * time.Millisecond,
+	})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	_, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{ProductID: first, Quantity: 2},
+	}, approvedCard)
+	if !errors.Is(err, payment.ErrTimeout) {
+		t.Fatalf("wanted %v, got %v", payment.ErrTimeout, err)
+	}
+
+	assertStock(t, ctx, pStore, first, testProductStock)
+
+	orders, err := serv.GetOrdersByUser(ctx, userID, store.OrderFilter{}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orders.Items[0].Status != store.Cancelled {
+		t.Fatalf("wanted %s, got %s", store.Cancelled, orders.Items[0].Status)
+	}
+
+	assertPayments(t, ctx, serv, orders.Items[0].ID, []store.PaymentOperation{store.PaymentAuthorize}, []store.PaymentOutcome{store.PaymentTimeout})
+}
+
+func TestCheckoutCompensatesOnInsufficientStock(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+	second := storeTestProduct(t, ctx, pStore, "second")
+
+	_, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{ProductID: first, Quantity: 2},
+		{ProductID: second, Quantity: testProductStock + 1},
+	}, approvedCard)
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("wanted %v, got %v", ErrInsufficientStock, err)
+	}
+
+	assertStock(t, ctx, pStore, first, testProductStock)
+	assertStock(t, ctx, pStore, second, testProductStock)
+
+	orders, err := serv.GetOrdersByUser(ctx, userID, store.OrderFilter{}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders.Items) != 1 {
+		t.Fatalf("wanted %d, got %d", 1, len(orders.Items))
+	}
+	if orders.Items[0].Status != store.Cancelled {
+		t.Fatalf("wanted %s, got %s", store.Cancelled, orders.Items[0].Status)
+	}
+}
+
+func TestCheckoutUnknownProduct(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	_, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{ProductID: first, Quantity: 1},
+		{ProductID: first + 100, Quantity: 1},
+	}, approvedCard)
+	if err == nil {
+		t.Fatal("wanted an error, got nil")
+	}
+
+	assertStock(t, ctx, pStore, first, testProductStock)
+}
+
+func TestCheckoutUnknownUser(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	productID := storeTestProduct(t, ctx, pStore, "product")
+
+	_, err := serv.CreateOrder(ctx, userID+100, []store.OrderItem{
+		{ProductID: productID, Quantity: 1},
+	}, approvedCard)
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("wanted %v, got %v", ErrUserNotFound, err)
+	}
+
+	if _, err := serv.AddCartItem(ctx, userID+100, productID, 0, 1); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("wanted %v, got %v", ErrUserNotFound, err)
+	}
+
+	assertStock(t, ctx, pStore, productID, testProductStock)
+}
+
+func TestCancelOrderReleasesStock(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	id, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{ProductID: first, Quantity: 3},
+	}, approvedCard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStock(t, ctx, pStore, first, testProductStock-3)
+
+	if err := serv.UpdateOrderStatus(ctx, id, store.Cancelled, "changed my mind"); err != nil {
+		t.Fatal(err)
+	}
+	assertStock(t, ctx, pStore, first, testProductStock)
+
+	// Cancelling again must not give the stock back twice.
+	if err := serv.UpdateOrderStatus(ctx, id, store.Cancelled, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidTransition, err)
+	}
+	assertStock(t, ctx, pStore, first, testProductStock)
+}
+
+// abandonCheckout stores a pending order for one unit of the given product and reserves its stock
+// and authorizes its payment, capturing it too if capture is true, like a checkout whose process
+// stopped right after those steps.
+func abandonCheckout(t *testing.T, ctx context.Context, serv *OrderService, userID, productID int, capture bool) int {
+	t.Helper()
+
+	items, err := serv.priceItems(ctx, []store.OrderItem{{ProductID: productID, Quantity: 1}}, money.DefaultCurrency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := serv.db.StoreOrder(ctx, store.Order{
+		UserID:   userID,
+		Currency: money.DefaultCurrency,
+		Status:   store.Pending,
+		Items:    items,
+	}, systemActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := serv.inventory.ReserveStock(ctx, id, items[0].SKUID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := serv.db.RetrieveOrder(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizationID, err := serv.authorizePayment(ctx, order, approvedCard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capture {
+		if err := serv.capturePayment(ctx, order, authorizationID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return id
+}
+
+func TestRecoverCheckout(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	authorized := abandonCheckout(t, ctx, serv, userID, first, false)
+	captured := abandonCheckout(t, ctx, serv, userID, first, true)
+	assertStock(t, ctx, pStore, first, testProductStock-2)
+
+	ids, err := serv.db.RetrieveStalePendingOrders(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(ids))
+	}
+	for _, id := range ids {
+		if err := serv.RecoverCheckout(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		order, err := serv.GetOrder(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order.Status != store.Cancelled {
+			t.Fatalf("wanted %s, got %s", store.Cancelled, order.Status)
+		}
+		// Recovering an order that is no longer pending does nothing.
+		if err := serv.RecoverCheckout(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertStock(t, ctx, pStore, first, testProductStock)
+
+	assertPayments(t, ctx, serv, authorized,
+		[]store.PaymentOperation{store.PaymentAuthorize, store.PaymentVoid},
+		[]store.PaymentOutcome{store.PaymentApproved, store.PaymentApproved})
+	// A captured authorization cannot be voided, it is refunded instead.
+	assertPayments(t, ctx, serv, captured,
+		[]store.PaymentOperation{store.PaymentAuthorize, store.PaymentCapture, store.PaymentVoid, store.PaymentRefund},
+		[]store.PaymentOutcome{store.PaymentApproved, store.PaymentApproved, store.PaymentFailed, store.PaymentApproved})
+}
+
+func TestCheckoutSKUs(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	shirt, err := pStore.StoreProduct(ctx, productStore.Product{
+		Name:    "shirt",
+		Price:   money.MustParse("10", ""),
+		Options: []string{"size"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := pStore.StoreSKU(ctx, productStore.SKU{
+		ProductID: shirt,
+		Options:   map[string]string{"size": "S"},
+		Price:     money.MustParse("10", ""),
+		Stock:     testProductStock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := pStore.StoreSKU(ctx, productStore.SKU{
+		ProductID: shirt,
+		Options:   map[string]string{"size": "L"},
+		Price:     money.MustParse("12", ""),
+		Stock:     testProductStock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := serv.CreateOrder(ctx, userID, []store.OrderItem{{ProductID: shirt, Quantity: 1}}, approvedCard); !errors.Is(err, ErrSKURequired) {
+		t.Fatalf("wanted %v, got %v", ErrSKURequired, err)
+	}
+	if _, err := serv.CreateOrder(ctx, userID, []store.OrderItem{{ProductID: first, SKUID: small, Quantity: 1}}, approvedCard); !errors.Is(err, errSKUMismatch) {
+		t.Fatalf("wanted %v, got %v", errSKUMismatch, err)
+	}
+
+	id, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{SKUID: small, Quantity: 1},
+		{ProductID: shirt, SKUID: large, Quantity: 2},
+		{SKUID: small, Quantity: 1},
+	}, approvedCard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := serv.GetOrder(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Items) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(order.Items))
+	}
+	if order.TotalPrice != money.MustParse("44", "") {
+		t.Fatalf("wanted %d, got %s", 44, order.TotalPrice)
+	}
+	assertStock(t, ctx, pStore, shirt, 2*testProductStock-4)
+
+	sku, err := pStore.RetrieveSKU(ctx, large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sku.Stock != testProductStock-2 {
+		t.Fatalf("wanted %d, got %d", testProductStock-2, sku.Stock)
+	}
+
+	if _, err := serv.RefundOrder(ctx, id, []store.RefundItem{{ProductID: shirt, Quantity: 1}}, true, ""); !errors.Is(err, ErrSKURequired) {
+		t.Fatalf("wanted %v, got %v", ErrSKURequired, err)
+	}
+	refund, err := serv.RefundOrder(ctx, id, []store.RefundItem{{SKUID: large, Quantity: 2}}, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Amount != money.MustParse("24", "") {
+		t.Fatalf("wanted %d, got %s", 24, refund.Amount)
+	}
+	assertStock(t, ctx, pStore, shirt, 2*testProductStock-2)
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/PseudoMera/virtual-store/shared/events"
+)
+
+// HandleUserDeleted removes the cart of a deleted user. Its orders are kept for the records.
+func (o *OrderService) HandleUserDeleted(ctx context.Context, event events.Event) error {
+	var payload events.UserDeletedPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return err
+	}
+
+	return o.db.DeleteCart(ctx, payload.UserID)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+
+	productgrpc "github.com/PseudoMera/virtual-store/product/grpc"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrInsufficientStock is returned when a product does not have enough stock for an order.
+	ErrInsufficientStock = shared.ConflictError("insufficient stock")
+	// ErrProductNotFound is returned when an order references a product or SKU that does not exist.
+	ErrProductNotFound = shared.NotFoundError("product not found")
+	// ErrSKURequired is returned when an item references a product with options without one of its SKUs.
+	ErrSKURequired = shared.InvalidFieldError("sku_id", "the product has options, select one of its skus")
+)
+
+// SKU is the current price and stock of a SKU of a product in the inventory. Price is in the
+// currency of its product and Prices holds its prices in other currencies.
+type SKU struct {
+	ID        int
+	ProductID int
+	Price     money.Money
+	Prices    money.Prices
+	Stock     int
+}
+
+// Inventory looks up SKUs and reserves their stock for orders.
+// GetSKU returns the SKU with the given id or, if skuID is zero, the single SKU of the product
+// with the given id. It returns ErrProductNotFound for unknown SKUs and products and
+// ErrSKURequired for a product with options.
+// ReleaseStock gives back up to quantity units of a reservation, all of it for a zero quantity.
+// Reserving and releasing everything must be idempotent per order and SKU so they can be
+// retried and used as compensations.
+type Inventory interface {
+	GetSKU(ctx context.Context, skuID, productID int) (*SKU, error)
+	ReserveStock(ctx context.Context, orderID, skuID, quantity int) error
+	ReleaseStock(ctx context.Context, orderID, skuID, quantity int) error
+}
+
+type productInventory struct {
+	client productgrpc.ProductServiceClient
+}
+
+// NewProductInventory returns an Inventory backed by the product service gRPC API.
+// The client connection must carry credentials holding the product:stock permission.
+func NewProductInventory(client productgrpc.ProductServiceClient) Inventory {
+	return &productInventory{
+		client: client,
+	}
+}
+
+func (pi *productInventory) GetSKU(ctx context.Context, skuID, productID int) (*SKU, error) {
+	resp, err := pi.client.GetSKU(ctx, &productgrpc.GetSKURequest{
+		Id:        int64(skuID),
+		ProductID: int64(productID),
+	})
+	// The SKU is always selected, an invalid argument can only be a product with options.
+	if status.Code(err) == codes.InvalidArgument {
+		return nil, ErrSKURequired
+	}
+	if err != nil {
+		return nil, readError(inventoryError(err))
+	}
+
+	price, err := money.FromProto(resp.Sku.Price)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := money.PricesFromProto(resp.Sku.Prices)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SKU{
+		ID:        int(resp.Sku.Id),
+		ProductID: int(resp.Sku.ProductID),
+		Price:     price,
+		Prices:    prices,
+		Stock:     int(resp.Sku.Stock),
+	}, nil
+}
+
+func (pi *productInventory) ReserveStock(ctx context.Context, orderID, skuID, quantity int) error {
+	_, err := pi.client.ReserveStock(ctx, &productgrpc.ReserveStockRequest{
+		OrderID:  int64(orderID),
+		SkuID:    int64(skuID),
+		Quantity: int32(quantity),
+	})
+
+	return inventoryError(err)
+}
+
+func (pi *productInventory) ReleaseStock(ctx context.Context, orderID, skuID, quantity int) error {
+	_, err := pi.client.ReleaseStock(ctx, &productgrpc.ReleaseStockRequest{
+		OrderID:  int64(orderID),
+		SkuID:    int64(skuID),
+		Quantity: int32(quantity),
+	})
+
+	return inventoryError(err)
+}
+
+// inventoryError converts the product service status codes into the service errors.
+func inventoryError(err error) error {
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.FailedPrecondition:
+		return ErrInsufficientStock
+	case codes.NotFound:
+		return ErrProductNotFound
+	default:
+		return err
+	}
+}
+
+// readError marks the errors of a read from another service that is unavailable as safe to send
+// again, reads are made before the order service changes anything so nothing was changed.
+func readError(err error) error {
+	if status.Code(err) == codes.Unavailable {
+		return shared.UnavailableError(err)
+	}
+
+	return err
+}
@@ -0,0 +1,11 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var ordersCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "orders_created_total",
+	Help: "Number of orders checked out, by the status they ended in.",
+}, []string{"status"})
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/order/payment"
+	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+// paymentTimeout bounds every call to the payment gateway.
+const paymentTimeout = 10 * time.Second
+
+var errEmptyCardNumber = shared.InvalidFieldError("card.number", "card number field cannot be empty")
+
+// authorizePayment holds the order total on the card and returns the authorization id.
+func (o *OrderService) authorizePayment(ctx context.Context, order *store.Order, card payment.Card) (string, error) {
+	attempt := &store.Payment{
+		OrderID:   order.ID,
+		Operation: store.PaymentAuthorize,
+		Amount:    order.TotalPrice,
+		CardLast4: card.Last4(),
+	}
+	err := o.callGateway(ctx, attempt, func(ctx context.Context) error {
+		id, err := o.gateway.Authorize(ctx, payment.AuthorizeRequest{
+			OrderID: order.ID,
+			Amount:  order.TotalPrice,
+			Card:    card,
+		})
+		attempt.AuthorizationID = id
+		return err
+	})
+
+	return attempt.AuthorizationID, err
+}
+
+// capturePayment settles the order total held by the authorization.
+func (o *OrderService) capturePayment(ctx context.Context, order *store.Order, authorizationID string) error {
+	attempt := &store.Payment{
+		OrderID:         order.ID,
+		Operation:       store.PaymentCapture,
+		Amount:          order.TotalPrice,
+		AuthorizationID: authorizationID,
+	}
+	return o.callGateway(ctx, attempt, func(ctx context.Context) error {
+		return o.gateway.Capture(ctx, authorizationID, order.TotalPrice)
+	})
+}
+
+// voidPayment releases an authorization that was not captured.
+func (o *OrderService) voidPayment(ctx context.Context, order *store.Order, authorizationID string) error {
+	attempt := &store.Payment{
+		OrderID:         order.ID,
+		Operation:       store.PaymentVoid,
+		Amount:          order.TotalPrice,
+		AuthorizationID: authorizationID,
+	}
+	return o.callGateway(ctx, attempt, func(ctx context.Context) error {
+		return o.gateway.Void(ctx, authorizationID)
+	})
+}
+
+// refundPayment gives back the given amount of a captured authorization. Sending a refund again
+// with the same reference never refunds it twice.
+func (o *OrderService) refundPayment(ctx context.Context, orderID int, authorizationID, reference string, amount money.Money) error {
+	attempt := &store.Payment{
+		OrderID:         orderID,
+		Operation:       store.PaymentRefund,
+		Amount:          amount,
+		AuthorizationID: authorizationID,
+	}
+	return o.callGateway(ctx, attempt, func(ctx context.Context) error {
+		return o.gateway.Refund(ctx, authorizationID, reference, amount)
+	})
+}
+
+// callGateway runs a payment gateway call with a timeout and records the attempt and its outcome.
+// Failing to record an attempt is logged but does not change the result of the call.
+func (o *OrderService) callGateway(ctx context.Context, attempt *store.Payment, call func(ctx context.Context) error) error {
+	callCtx, cancel := context.WithTimeout(ctx, paymentTimeout)
+	err := call(callCtx)
+	cancel()
+
+	attempt.Outcome = paymentOutcome(err)
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	if _, recordErr := o.db.StorePayment(context.WithoutCancel(ctx), *attempt); recordErr != nil {
+		o.logger.ErrorContext(ctx, "error at callGateway",
+			slog.Int("order", attempt.OrderID),
+			slog.String("operation", string(attempt.Operation)),
+			slog.String("error", recordErr.Error()))
+	}
+
+	return err
+}
+
+func paymentOutcome(err error) store.PaymentOutcome {
+	switch {
+	case err == nil:
+		return store.PaymentApproved
+	case errors.Is(err, payment.ErrDeclined):
+		return store.PaymentDeclined
+	case errors.Is(err, payment.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return store.PaymentTimeout
+	default:
+		return store.PaymentFailed
+	}
+}
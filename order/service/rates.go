@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+var (
+	errInvalidRateCurrency = shared.InvalidFieldError("currency", fmt.Sprintf("currency must be an ISO 4217 code other than %s", money.DefaultCurrency))
+	errInvalidRate         = shared.InvalidFieldError("rate", "rate must be a positive decimal number")
+)
+
+// SetExchangeRate stores the exchange rate from money.DefaultCurrency to the given currency, one
+// unit of the default currency is worth value units of it, such as "0.92". The rates of the
+// exchange_rate table are used unless an exchange rates file is configured, orders are priced
+// with the new rate once the cached rates expire.
+func (o *OrderService) SetExchangeRate(ctx context.Context, currency, value string) (money.Rate, error) {
+	if !money.ValidCurrency(currency) || currency == money.DefaultCurrency {
+		o.logger.InfoContext(ctx, "error at SetExchangeRate", slog.String("error", errInvalidRateCurrency.Error()))
+		return money.Rate{}, errInvalidRateCurrency
+	}
+	rate, err := money.ParseRate(money.DefaultCurrency, currency, value, time.Now())
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at SetExchangeRate", slog.String("error", err.Error()))
+		return money.Rate{}, errInvalidRate
+	}
+
+	return rate, o.db.StoreRate(ctx, rate)
+}
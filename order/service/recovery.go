@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/order/payment"
+	"github.com/PseudoMera/virtual-store/order/store"
+)
+
+const (
+	// checkoutTimeout bounds the steps of a checkout, the compensations of a failed step still run after it.
+	checkoutTimeout = 5 * time.Minute
+	// checkoutRecoveryAge is how long an order stays pending before its checkout is recovered, long
+	// enough for the checkout that left it pending to be over.
+	checkoutRecoveryAge = 2 * checkoutTimeout
+)
+
+// RecoverCheckout undoes the checkout of an order left pending by a checkout that never finished,
+// e.g. because the process stopped in the middle of it. The stock reserved for its items is
+// released, its payment authorization is voided, or refunded if it was captured, and the order is
+// cancelled. Every step can be repeated, an order whose recovery fails stays pending and is
+// recovered again. Orders that are not pending are left as they are.
+func (o *OrderService) RecoverCheckout(ctx context.Context, id int) error {
+	if id == 0 {
+		o.logger.InfoContext(ctx, "error at RecoverCheckout", slog.String("error", errEmptyId.Error()))
+		return errEmptyId
+	}
+
+	order, err := o.db.RetrieveOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	if order.Status != store.Pending {
+		return nil
+	}
+
+	if err := errors.Join(o.releaseStock(ctx, order), o.releasePayment(ctx, order)); err != nil {
+		o.logger.ErrorContext(ctx, "error at RecoverCheckout", slog.Int("order", id), slog.String("error", err.Error()))
+		return err
+	}
+
+	err = o.db.UpdateOrderStatus(ctx, id, store.StatusChange{
+		From:   &store.Pending,
+		To:     store.Cancelled,
+		Actor:  systemActor,
+		Reason: "checkout abandoned",
+	})
+	if errors.Is(err, store.ErrStatusChanged) {
+		// It was cancelled concurrently.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ordersCreated.WithLabelValues(string(store.Cancelled)).Inc()
+
+	return nil
+}
+
+// RecoverCheckouts recovers the orders that have been pending for longer than checkoutRecoveryAge
+// and returns how many were cancelled. Orders whose recovery fails are retried on the next call.
+func (o *OrderService) RecoverCheckouts(ctx context.Context) (int, error) {
+	ids, err := o.db.RetrieveStalePendingOrders(ctx, checkoutRecoveryAge)
+	if err != nil {
+		return 0, err
+	}
+
+	var recovered int
+	var errs []error
+	for _, id := range ids {
+		if err := o.RecoverCheckout(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("order %d: %w", id, err))
+			continue
+		}
+		recovered++
+	}
+
+	return recovered, errors.Join(errs...)
+}
+
+// RunCheckoutRecovery recovers the checkouts left pending every interval until ctx is done.
+func (o *OrderService) RunCheckoutRecovery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			recovered, err := o.RecoverCheckouts(ctx)
+			if err != nil {
+				o.logger.ErrorContext(ctx, "error at RunCheckoutRecovery", slog.String("error", err.Error()))
+			}
+			if recovered > 0 {
+				o.logger.InfoContext(ctx, "recovered pending checkouts", slog.Int("orders", recovered))
+			}
+		}
+	}
+}
+
+// releasePayment voids the approved authorization of an order, or refunds it in full under the
+// reference the checkout uses if it was captured, so it is never refunded twice.
+// An authorization whose call timed out has no id and is left to expire at the gateway.
+func (o *OrderService) releasePayment(ctx context.Context, order *store.Order) error {
+	payments, err := o.db.RetrievePaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	var authorizationID string
+	for _, p := range payments {
+		if p.Operation == store.PaymentAuthorize && p.Outcome == store.PaymentApproved {
+			authorizationID = p.AuthorizationID
+		}
+	}
+	if authorizationID == "" {
+		return nil
+	}
+
+	err = o.voidPayment(ctx, order, authorizationID)
+	if !errors.Is(err, payment.ErrInvalidState) {
+		return err
+	}
+
+	// The authorization was captured, which a void cannot undo.
+	return o.refundPayment(ctx, order.ID, authorizationID, fmt.Sprintf("order_%d", order.ID), order.TotalPrice)
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/order/payment"
+	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/jackc/pgx/v5"
+)
+
+// refundReconcileAge is how long a refund stays pending before it is reconciled, long enough for
+// the gateway call that left it pending to be over.
+const refundReconcileAge = time.Minute
+
+var (
+	// ErrNotRefundable is returned when refunding an order that was not paid or is still pending.
+	ErrNotRefundable = shared.ConflictError("only paid orders that are completed, shipped or cancelled can be refunded")
+	// errRefundNotSettled is returned when a refund was made by the gateway but could not be completed.
+	errRefundNotSettled = errors.New("refund not settled")
+)
+
+// RefundOrder refunds the given quantities of the order items, or everything that was not
+// refunded yet if there are no items. Items are given by SKU id or by product id, which must
+// then have a single SKU in the order or ErrSKURequired is returned. The refund is recorded before the payment gateway is
+// called so concurrent refunds can never give back more than was paid, the error wraps
+// store.ErrRefundExceeded if they would. If restock is true the product service gives the refunded
+// quantities back to the stock once the money is refunded, from the RefundSucceeded event.
+// A refund whose gateway call times out stays pending since the money may have been refunded,
+// it is settled by ReconcileRefund. A cancelled order can still be refunded what the cancellation
+// failed to refund, its stock was released by the cancellation so it is never restocked.
+func (o *OrderService) RefundOrder(ctx context.Context, id int, items []store.RefundItem, restock bool, reason string) (*store.Refund, error) {
+	if id == 0 {
+		o.logger.InfoContext(ctx, "error at RefundOrder", slog.String("error", errEmptyId.Error()))
+		return nil, errEmptyId
+	}
+	items, err := mergeRefundItems(items)
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at RefundOrder", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	order, err := o.db.RetrieveOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == store.Pending {
+		o.logger.InfoContext(ctx, "error at RefundOrder", slog.String("error", ErrNotRefundable.Error()))
+		return nil, ErrNotRefundable
+	}
+	if order.Status == store.Cancelled {
+		restock = false
+	}
+	items, err = resolveRefundItems(order, items)
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at RefundOrder", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return o.refund(ctx, order, items, restock, reason)
+}
+
+// GetRefunds returns the refunds of the order with the given id, oldest first.
+func (o *OrderService) GetRefunds(ctx context.Context, id int) ([]*store.Refund, error) {
+	if id == 0 {
+		o.logger.InfoContext(ctx, "error at GetRefunds", slog.String("error", errEmptyId.Error()))
+		return nil, errEmptyId
+	}
+
+	return o.db.RetrieveRefunds(ctx, id)
+}
+
+func (o *OrderService) refund(ctx context.Context, order *store.Order, items []store.RefundItem, restock bool, reason string) (*store.Refund, error) {
+	capture, err := o.db.RetrieveCapturePayment(ctx, order.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		o.logger.InfoContext(ctx, "error at refund", slog.String("error", ErrNotRefundable.Error()))
+		return nil, ErrNotRefundable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refund, err := o.db.StoreRefund(ctx, store.Refund{
+		OrderID:   order.ID,
+		PaymentID: capture.ID,
+		Restock:   restock,
+		Actor:     actor(ctx),
+		Reason:    reason,
+		Items:     items,
+	})
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at refund", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	err = o.settleRefund(ctx, refund, capture.AuthorizationID)
+	if errors.Is(err, errRefundNotSettled) {
+		// The money was refunded, the refund is completed by the reconciliation.
+		return refund, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// ReconcileRefund settles a refund left pending by a payment gateway call that timed out. The
+// refund is sent again under the same reference, so the gateway refunds it at most once, and
+// completed or failed with the outcome. Refunds that are not pending are returned as they are.
+// A refund the gateway refused is returned failed without an error.
+func (o *OrderService) ReconcileRefund(ctx context.Context, id int) (*store.Refund, error) {
+	if id == 0 {
+		o.logger.InfoContext(ctx, "error at ReconcileRefund", slog.String("error", errEmptyId.Error()))
+		return nil, errEmptyId
+	}
+
+	refund, err := o.db.RetrieveRefund(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != store.RefundPending {
+		return refund, nil
+	}
+
+	capture, err := o.db.RetrieveCapturePayment(ctx, refund.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.settleRefund(ctx, refund, capture.AuthorizationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// It was settled concurrently.
+		return o.db.RetrieveRefund(ctx, id)
+	}
+	if err != nil && refund.Status != store.RefundFailed {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// ReconcileRefunds reconciles the refunds that have been pending for longer than refundReconcileAge
+// and returns how many were settled. Refunds that stay pending are retried on the next call.
+func (o *OrderService) ReconcileRefunds(ctx context.Context) (int, error) {
+	ids, err := o.db.RetrievePendingRefunds(ctx, refundReconcileAge)
+	if err != nil {
+		return 0, err
+	}
+
+	var settled int
+	var errs []error
+	for _, id := range ids {
+		refund, err := o.ReconcileRefund(ctx, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("refund %d: %w", id, err))
+			continue
+		}
+		if refund.Status != store.RefundPending {
+			settled++
+		}
+	}
+
+	return settled, errors.Join(errs...)
+}
+
+// RunRefundReconciliation reconciles the pending refunds every interval until ctx is done.
+func (o *OrderService) RunRefundReconciliation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			settled, err := o.ReconcileRefunds(ctx)
+			if err != nil {
+				o.logger.ErrorContext(ctx, "error at RunRefundReconciliation", slog.String("error", err.Error()))
+			}
+			if settled > 0 {
+				o.logger.InfoContext(ctx, "reconciled pending refunds", slog.Int("refunds", settled))
+			}
+		}
+	}
+}
+
+// settleRefund sends a pending refund to the payment gateway and completes it, or fails it if the
+// gateway refused it. A refund whose call times out stays pending since the money may have been
+// refunded, as does one that could not be completed, which returns errRefundNotSettled.
+func (o *OrderService) settleRefund(ctx context.Context, refund *store.Refund, authorizationID string) error {
+	err := o.refundPayment(ctx, refund.OrderID, authorizationID, fmt.Sprintf("refund_%d", refund.ID), refund.Amount)
+	if errors.Is(err, payment.ErrTimeout) {
+		o.logger.ErrorContext(ctx, "error at settleRefund", slog.Int("refund", refund.ID), slog.String("error", err.Error()))
+		return err
+	}
+	if err != nil {
+		if failErr := o.db.FailRefund(context.WithoutCancel(ctx), refund.ID); failErr != nil {
+			err = errors.Join(err, failErr)
+		} else {
+			refund.Status = store.RefundFailed
+		}
+		o.logger.InfoContext(ctx, "error at settleRefund", slog.Int("refund", refund.ID), slog.String("error", err.Error()))
+		return err
+	}
+
+	if err := o.db.CompleteRefund(context.WithoutCancel(ctx), refund.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		o.logger.ErrorContext(ctx, "error at settleRefund", slog.Int("refund", refund.ID), slog.String("error", err.Error()))
+		return fmt.Errorf("%w: %w", errRefundNotSettled, err)
+	}
+	refund.Status = store.RefundSucceeded
+
+	return nil
+}
+
+// refundOutstanding refunds whatever was not refunded yet of a paid order that was cancelled.
+// The stock is given back by the cancellation itself.
+func (o *OrderService) refundOutstanding(ctx context.Context, order *store.Order, reason string) error {
+	if order.TotalPrice.Cmp(order.RefundedAmount) <= 0 {
+		return nil
+	}
+
+	// Nothing is left to refund if a concurrent refund took the rest.
+	_, err := o.refund(ctx, order, nil, false, reason)
+	if errors.Is(err, ErrNotRefundable) || errors.Is(err, store.ErrRefundExceeded) {
+		return nil
+	}
+
+	return err
+}
+
+// mergeRefundItems validates the given refund items and merges the quantities of items for the same SKU,
+// or for the same product when no SKU is given.
+func mergeRefundItems(items []store.RefundItem) ([]store.RefundItem, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	orderItems := make([]store.OrderItem, len(items))
+	for i, item := range items {
+		orderItems[i] = store.OrderItem{ProductID: item.ProductID, SKUID: item.SKUID, Quantity: item.Quantity}
+	}
+	orderItems, err := mergeItems(orderItems)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make([]store.RefundItem, len(orderItems))
+	for i, item := range orderItems {
+		merged[i] = store.RefundItem{ProductID: item.ProductID, SKUID: item.SKUID, Quantity: item.Quantity}
+	}
+
+	return merged, nil
+}
+
+// resolveRefundItems sets the SKU and product of every refund item from the order items and merges
+// the items that turn out to be the same SKU. Items for a SKU the order does not have keep their
+// id and are rejected by the store.
+func resolveRefundItems(order *store.Order, items []store.RefundItem) ([]store.RefundItem, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	resolved := make([]store.RefundItem, len(items))
+	for i, item := range items {
+		resolved[i] = item
+		for _, orderItem := range order.Items {
+			if item.SKUID != 0 && orderItem.SKUID != item.SKUID {
+				continue
+			}
+			if item.SKUID == 0 && orderItem.ProductID != item.ProductID {
+				continue
+			}
+			if item.ProductID != 0 && orderItem.ProductID != item.ProductID {
+				return nil, fmt.Errorf("sku %d: %w", item.SKUID, errSKUMismatch)
+			}
+			if resolved[i].SKUID != 0 && item.SKUID == 0 {
+				return nil, fmt.Errorf("product %d: %w", item.ProductID, ErrSKURequired)
+			}
+			resolved[i].ProductID, resolved[i].SKUID = orderItem.ProductID, orderItem.SKUID
+		}
+		if resolved[i].SKUID == 0 {
+			return nil, fmt.Errorf("product %d: %w", item.ProductID, store.ErrRefundExceeded)
+		}
+	}
+
+	return mergeRefundItems(resolved)
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/PseudoMera/virtual-store/order/payment"
+	"github.com/PseudoMera/virtual-store/order/store"
+	productStore "github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+// restockRefund gives back the stock of a refund like the product service does on its RefundSucceeded event.
+func restockRefund(t *testing.T, ctx context.Context, pStore *productStore.Store, refund *store.Refund) {
+	t.Helper()
+
+	items := make([]productStore.StockItem, len(refund.Items))
+	for i, item := range refund.Items {
+		items[i] = productStore.StockItem{SKUID: item.SKUID, Quantity: item.Quantity}
+	}
+	if err := pStore.ReleaseRefundStock(ctx, refund.ID, refund.OrderID, items); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefundOrderItems(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+	second := storeTestProduct(t, ctx, pStore, "second")
+
+	id, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{ProductID: first, Quantity: 3},
+		{ProductID: second, Quantity: 1},
+	}, approvedCard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := serv.RefundOrder(ctx, id, []store.RefundItem{
+		{ProductID: first, Quantity: 2},
+	}, true, "damaged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Amount != money.MustParse("20", "") {
+		t.Fatalf("wanted %d, got %s", 20, refund.Amount)
+	}
+	if refund.Status != store.RefundSucceeded {
+		t.Fatalf("wanted %s, got %s", store.RefundSucceeded, refund.Status)
+	}
+	assertStock(t, ctx, pStore, first, testProductStock-3)
+	restockRefund(t, ctx, pStore, refund)
+	assertStock(t, ctx, pStore, first, testProductStock-1)
+	// A redelivered event does not restock twice.
+	restockRefund(t, ctx, pStore, refund)
+	assertStock(t, ctx, pStore, first, testProductStock-1)
+
+	// Only one unit of the first product is left to refund.
+	_, err = serv.RefundOrder(ctx, id, []store.RefundItem{
+		{ProductID: first, Quantity: 2},
+	}, false, "")
+	if !errors.Is(err, store.ErrRefundExceeded) {
+		t.Fatalf("wanted %v, got %v", store.ErrRefundExceeded, err)
+	}
+
+	// Refunding without items refunds everything that is left.
+	refund, err = serv.RefundOrder(ctx, id, nil, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Amount != money.MustParse("20", "") {
+		t.Fatalf("wanted %d, got %s", 20, refund.Amount)
+	}
+	if len(refund.Items) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(refund.Items))
+	}
+	assertStock(t, ctx, pStore, first, testProductStock-1)
+
+	order, err := serv.GetOrder(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.RefundedAmount != order.TotalPrice {
+		t.Fatalf("wanted %s, got %s", order.TotalPrice, order.RefundedAmount)
+	}
+
+	if _, err := serv.RefundOrder(ctx, id, nil, false, ""); !errors.Is(err, store.ErrRefundExceeded) {
+		t.Fatalf("wanted %v, got %v", store.ErrRefundExceeded, err)
+	}
+
+	refunds, err := serv.GetRefunds(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(refunds))
+	}
+	assertPayments(t, ctx, serv, id,
+		[]store.PaymentOperation{store.PaymentAuthorize, store.PaymentCapture, store.PaymentRefund, store.PaymentRefund},
+		[]store.PaymentOutcome{store.PaymentApproved, store.PaymentApproved, store.PaymentApproved, store.PaymentApproved})
+}
+
+func TestRefundUnpaidOrder(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	id, err := serv.db.StoreOrder(ctx, store.Order{
+		UserID: userID,
+		Status: store.Pending,
+		Items: []store.OrderItem{
+			{ProductID: first, Quantity: 1},
+		},
+	}, systemActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := serv.RefundOrder(ctx, id, nil, false, ""); !errors.Is(err, ErrNotRefundable) {
+		t.Fatalf("wanted %v, got %v", ErrNotRefundable, err)
+	}
+}
+
+func TestCancelCompletedOrderRefunds(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	id, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{ProductID: first, Quantity: 3},
+	}, approvedCard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := serv.RefundOrder(ctx, id, []store.RefundItem{
+		{ProductID: first, Quantity: 1},
+	}, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restockRefund(t, ctx, pStore, refund)
+	assertStock(t, ctx, pStore, first, testProductStock-2)
+
+	if err := serv.UpdateOrderStatus(ctx, id, store.Cancelled, "changed my mind"); err != nil {
+		t.Fatal(err)
+	}
+	// The unit restocked by the refund must not be given back twice.
+	assertStock(t, ctx, pStore, first, testProductStock)
+
+	order, err := serv.GetOrder(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.RefundedAmount != order.TotalPrice {
+		t.Fatalf("wanted %s, got %s", order.TotalPrice, order.RefundedAmount)
+	}
+}
+
+func TestConcurrentCancelAndShip(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	for i := 0; i < testProductStock; i++ {
+		id, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+			{ProductID: first, Quantity: 1},
+		}, approvedCard)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Only one of the updates moves the order, the order is refunded only if it was cancelled.
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for j, status := range []store.OrderStatus{store.Cancelled, store.Shipped} {
+			wg.Add(1)
+			go func(j int, status store.OrderStatus) {
+				defer wg.Done()
+				errs[j] = serv.UpdateOrderStatus(ctx, id, status, "")
+			}(j, status)
+		}
+		wg.Wait()
+
+		order, err := serv.GetOrder(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refunds, err := serv.GetRefunds(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch order.Status {
+		case store.Cancelled:
+			if errs[0] != nil || !errors.Is(errs[1], ErrInvalidTransition) {
+				t.Fatalf("wanted the cancellation to win, got %v and %v", errs[0], errs[1])
+			}
+			if len(refunds) != 1 || order.RefundedAmount != order.TotalPrice {
+				t.Fatalf("wanted %s refunded once, got %s in %d refunds", order.TotalPrice, order.RefundedAmount, len(refunds))
+			}
+		case store.Shipped:
+			if errs[1] != nil || !errors.Is(errs[0], ErrInvalidTransition) {
+				t.Fatalf("wanted the shipment to win, got %v and %v", errs[1], errs[0])
+			}
+			if len(refunds) != 0 || !order.RefundedAmount.IsZero() {
+				t.Fatalf("wanted no refund, got %s in %d refunds", order.RefundedAmount, len(refunds))
+			}
+		default:
+			t.Fatalf("wanted %s or %s, got %s", store.Cancelled, store.Shipped, order.Status)
+		}
+	}
+}
+
+func TestReconcileRefund(t *testing.T) {
+	ctx := context.Background()
+	serv, pStore, userID := setupCheckout(t, ctx, payment.MockConfig{})
+	first := storeTestProduct(t, ctx, pStore, "first")
+
+	id, err := serv.CreateOrder(ctx, userID, []store.OrderItem{
+		{ProductID: first, Quantity: 3},
+	}, approvedCard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := serv.GetOrder(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	capture, err := serv.db.RetrieveCapturePayment(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A refund made by the gateway whose answer never came back.
+	pending, err := serv.db.StoreRefund(ctx, store.Refund{
+		OrderID:   id,
+		PaymentID: capture.ID,
+		Actor:     "test",
+		Items: []store.RefundItem{
+			{SKUID: order.Items[0].SKUID, Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := serv.gateway.Refund(ctx, capture.AuthorizationID, fmt.Sprintf("refund_%d", pending.ID), pending.Amount); err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := serv.ReconcileRefund(ctx, pending.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Status != store.RefundSucceeded {
+		t.Fatalf("wanted %s, got %s", store.RefundSucceeded, refund.Status)
+	}
+	refund, err = serv.ReconcileRefund(ctx, pending.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Status != store.RefundSucceeded {
+		t.Fatalf("wanted %s, got %s", store.RefundSucceeded, refund.Status)
+	}
+
+	// The gateway refunded it once, so what is left can still be refunded.
+	refund, err = serv.RefundOrder(ctx, id, nil, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Amount != money.MustParse("20", "") {
+		t.Fatalf("wanted %d, got %s", 20, refund.Amount)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// sagaStep is a local transaction of a saga alongside the action that undoes it.
+// Compensations must be idempotent, they may run for a step whose action failed.
+type sagaStep struct {
+	name       string
+	action     func(ctx context.Context) error
+	compensate func(ctx context.Context) error
+}
+
+// saga runs a sequence of steps spanning several services.
+// There is no distributed transaction, consistency is restored by compensating
+// the steps that already ran when a later one fails.
+type saga struct {
+	name   string
+	steps  []sagaStep
+	logger *slog.Logger
+}
+
+func newSaga(name string, logger *slog.Logger) *saga {
+	return &saga{
+		name:   name,
+		logger: logger,
+	}
+}
+
+// addStep appends a step to the saga. A nil compensation means the step needs no undo.
+func (s *saga) addStep(name string, action, compensate func(ctx context.Context) error) {
+	s.steps = append(s.steps, sagaStep{
+		name:       name,
+		action:     action,
+		compensate: compensate,
+	})
+}
+
+// run executes the steps in order. When a step fails, its compensation and the
+// compensations of every previous step run in reverse order. The failed step is
+// compensated too since the outcome of a failed remote call is unknown.
+// The returned error wraps the step error and any compensation error.
+func (s *saga) run(ctx context.Context) error {
+	for i, step := range s.steps {
+		if err := step.action(ctx); err != nil {
+			s.logger.InfoContext(ctx, "error at saga step",
+				slog.String("saga", s.name),
+				slog.String("step", step.name),
+				slog.String("error", err.Error()))
+
+			return errors.Join(fmt.Errorf("%s: %w", step.name, err), s.compensate(ctx, i))
+		}
+	}
+
+	return nil
+}
+
+// compensate undoes the steps up to and including the one at index last.
+// Compensations run even if ctx was cancelled, otherwise a cancelled request
+// would leave the saga half applied.
+func (s *saga) compensate(ctx context.Context, last int) error {
+	ctx = context.WithoutCancel(ctx)
+
+	var errs []error
+	for i := last; i >= 0; i-- {
+		step := s.steps[i]
+		if step.compensate == nil {
+			continue
+		}
+		if err := step.compensate(ctx); err != nil {
+			s.logger.ErrorContext(ctx, "error compensating saga step",
+				slog.String("saga", s.name),
+				slog.String("step", step.name),
+				slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("compensating %s: %w", step.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
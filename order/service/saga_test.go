@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+func TestSagaCompensatesInReverseOrder(t *testing.T) {
+	errStep := errors.New("step failed")
+
+	var calls []string
+	step := func(name string, err error) (func(context.Context) error, func(context.Context) error) {
+		return func(context.Context) error {
+				calls = append(calls, name)
+				return err
+			}, func(context.Context) error {
+				calls = append(calls, "undo "+name)
+				return nil
+			}
+	}
+
+	s := newSaga("test", slog.Default())
+	first, undoFirst := step("first", nil)
+	s.addStep("first", first, undoFirst)
+	second, _ := step("second", nil)
+	s.addStep("second", second, nil)
+	third, undoThird := step("third", errStep)
+	s.addStep("third", third, undoThird)
+	fourth, undoFourth := step("fourth", nil)
+	s.addStep("fourth", fourth, undoFourth)
+
+	err := s.run(context.Background())
+	if !errors.Is(err, errStep) {
+		t.Fatalf("wanted %v, got %v", errStep, err)
+	}
+
+	want := []string{"first", "second", "third", "undo third", "undo first"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("wanted %v, got %v", want, calls)
+	}
+}
+
+func TestSagaReportsCompensationErrors(t *testing.T) {
+	errStep := errors.New("step failed")
+	errUndo := errors.New("undo failed")
+
+	s := newSaga("test", slog.Default())
+	s.addStep("first", func(context.Context) error { return nil }, func(context.Context) error { return errUndo })
+	s.addStep("second", func(context.Context) error { return errStep }, nil)
+
+	err := s.run(context.Background())
+	if !errors.Is(err, errStep) {
+		t.Fatalf("wanted %v, got %v", errStep, err)
+	}
+	if !errors.Is(err, errUndo) {
+		t.Fatalf("wanted %v, got %v", errUndo, err)
+	}
+}
+
+func TestSagaCompensatesWithCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	compensated := false
+	s := newSaga("test", slog.Default())
+	s.addStep("first", func(context.Context) error { return nil }, func(ctx context.Context) error {
+		compensated = ctx.Err() == nil
+		return nil
+	})
+	s.addStep("second", func(context.Context) error {
+		cancel()
+		return context.Canceled
+	}, nil)
+
+	if err := s.run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("wanted %v, got %v", context.Canceled, err)
+	}
+	if !compensated {
+		t.Fatal("wanted compensation to run with a live context")
+	}
+}
@@ -3,108 +3,508 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 
+	"github.com/PseudoMera/virtual-store/order/payment"
 	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
+	"github.com/jackc/pgx/v5"
 )
 
 var (
-	errEmptyUserID     = errors.New("user id field cannot be empty")
-	errEmptyTotalPrice = errors.New("total price field cannot be empty")
-	errEmptyId         = errors.New("id field cannot be empty")
-	errEmptyStatus     = errors.New("status field cannot be empty")
+	errEmptyUserID     = shared.InvalidFieldError("user_id", "user id field cannot be empty")
+	errEmptyTotalPrice = shared.InvalidFieldError("total_price", "total price field cannot be empty")
+	errEmptyId         = shared.InvalidFieldError("id", "id field cannot be empty")
+	errEmptyStatus     = shared.InvalidFieldError("status", "status field cannot be empty")
+	errEmptyItems      = shared.InvalidFieldError("items", "items field cannot be empty")
+	errEmptyProductID  = shared.InvalidFieldError("product_id", "item product_id or sku_id field cannot be empty")
+	errSKUMismatch     = shared.InvalidFieldError("sku_id", "item sku does not belong to the item product")
+	errInvalidQuantity = shared.InvalidFieldError("quantity", "item quantity must be greater than zero")
+	errPaidOrderTotal  = shared.ConflictError("total price of a paid order cannot change, refund it instead")
+	errTotalCurrency   = shared.InvalidFieldError("total_price", "total price must be in the currency of the order")
 )
 
 type OrderService struct {
-	db     *store.Store
-	logger *slog.Logger
+	db        *store.Store
+	users     Users
+	inventory Inventory
+	gateway   payment.PaymentGateway
+	rates     money.RateProvider
+	logger    *slog.Logger
 }
 
-// NewOrderService returns a OrderService with the given db and logger.
+// NewOrderService returns a OrderService with the given db, users, inventory, payment gateway,
+// exchange rates and logger.
 // The order service can be used to interact with the database through
 // the Store struct. The service also has validation for the methods
 // so it's better to use this than directly interacting with the Store struct.
-func NewOrderService(db *store.Store, logger *slog.Logger) *OrderService {
+func NewOrderService(db *store.Store, users Users, inventory Inventory, gateway payment.PaymentGateway, rates money.RateProvider, logger *slog.Logger) *OrderService {
 	return &OrderService{
-		db:     db,
-		logger: logger,
+		db:        db,
+		users:     users,
+		inventory: inventory,
+		gateway:   gateway,
+		rates:     rates,
+		logger:    logger,
 	}
 }
 
-// CreateOrder creates a new order with the given userID, totalPrice and status.
-func (o *OrderService) CreateOrder(ctx context.Context, userID int, totalPrice float64, status store.OrderStatus) (int, error) {
+// CreateOrder checks out a new order with the given userID and items and pays it with the given card.
+// Items only need the SKU id, or the product id for a product without options, and the
+// quantity, items for the same SKU are merged.
+// The user is checked against the user service and the item prices and the order total are
+// computed from the current SKU prices in the currency of the user, returning ErrUserNotFound,
+// ErrProductNotFound or ErrSKURequired. A SKU without a price in that currency is converted at the
+// current exchange rate, which is stored with the item.
+// The stock of every item is reserved in the inventory and the total is authorized and
+// captured on the card, the order is completed only after the capture succeeds.
+// If any step fails the order is cancelled, the steps already made are undone and the
+// error wraps ErrInsufficientStock, ErrProductNotFound, payment.ErrDeclined or payment.ErrTimeout.
+// The steps must finish within checkoutTimeout, an order left pending by a checkout that never
+// finished is cancelled by RecoverCheckout.
+func (o *OrderService) CreateOrder(ctx context.Context, userID int, items []store.OrderItem, card payment.Card) (int, error) {
 	if userID == 0 {
-		o.logger.Info("error at CreateOrder", slog.String("error", errEmptyId.Error()))
+		o.logger.InfoContext(ctx, "error at CreateOrder", slog.String("error", errEmptyUserID.Error()))
 		return 0, errEmptyUserID
 	}
-	if totalPrice == 0.0 {
-		o.logger.Info("error at CreateOrder", slog.String("error", errEmptyTotalPrice.Error()))
-		return 0, errEmptyTotalPrice
+	if card.Number == "" {
+		o.logger.InfoContext(ctx, "error at CreateOrder", slog.String("error", errEmptyCardNumber.Error()))
+		return 0, errEmptyCardNumber
 	}
-	if status == "" {
-		o.logger.Info("error at CreateOrder", slog.String("error", errEmptyStatus.Error()))
-		return 0, errEmptyStatus
+	items, err := mergeItems(items)
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at CreateOrder", slog.String("error", err.Error()))
+		return 0, err
+	}
+	if err := o.users.CheckUser(ctx, userID); err != nil {
+		o.logger.InfoContext(ctx, "error at CreateOrder", slog.String("error", err.Error()))
+		return 0, err
+	}
+	currency, err := o.users.Currency(ctx, userID)
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at CreateOrder", slog.String("error", err.Error()))
+		return 0, err
+	}
+	items, err = o.priceItems(ctx, items, currency)
+	if err != nil {
+		o.logger.InfoContext(ctx, "error at CreateOrder", slog.String("error", err.Error()))
+		return 0, err
 	}
 
-	return o.db.StoreOrder(ctx, store.Order{
-		UserID:     userID,
-		TotalPrice: totalPrice,
-		Status:     status,
+	var id int
+	checkout := newSaga("checkout", o.logger)
+	checkout.addStep("store order", func(ctx context.Context) error {
+		var err error
+		id, err = o.db.StoreOrder(ctx, store.Order{
+			UserID:   userID,
+			Currency: currency,
+			Status:   store.Pending,
+			Items:    items,
+		}, actor(ctx))
+		return err
+	}, func(ctx context.Context) error {
+		if id == 0 {
+			return nil
+		}
+		return o.db.UpdateOrderStatus(ctx, id, store.StatusChange{
+			From:   &store.Pending,
+			To:     store.Cancelled,
+			Actor:  systemActor,
+			Reason: "checkout failed",
+		})
+	})
+	for _, item := range items {
+		item := item
+		checkout.addStep(fmt.Sprintf("reserve sku %d", item.SKUID), func(ctx context.Context) error {
+			return o.inventory.ReserveStock(ctx, id, item.SKUID, item.Quantity)
+		}, func(ctx context.Context) error {
+			return o.inventory.ReleaseStock(ctx, id, item.SKUID, 0)
+		})
+	}
+
+	var order *store.Order
+	var authorizationID string
+	checkout.addStep("authorize payment", func(ctx context.Context) error {
+		var err error
+		order, err = o.db.RetrieveOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		authorizationID, err = o.authorizePayment(ctx, order, card)
+		return err
+	}, func(ctx context.Context) error {
+		if authorizationID == "" {
+			return nil
+		}
+		return o.voidPayment(ctx, order, authorizationID)
+	})
+
+	captured := false
+	checkout.addStep("capture payment", func(ctx context.Context) error {
+		if err := o.capturePayment(ctx, order, authorizationID); err != nil {
+			return err
+		}
+		captured = true
+		return nil
+	}, func(ctx context.Context) error {
+		if !captured {
+			return nil
+		}
+		// The void of the authorization step cannot undo a capture.
+		if err := o.refundPayment(ctx, order.ID, authorizationID, fmt.Sprintf("order_%d", order.ID), order.TotalPrice); err != nil {
+			return err
+		}
+		authorizationID = ""
+		return nil
 	})
+
+	checkout.addStep("complete order", func(ctx context.Context) error {
+		return o.db.UpdateOrderStatus(ctx, id, store.StatusChange{
+			From:   &store.Pending,
+			To:     store.Completed,
+			Actor:  systemActor,
+			Reason: "payment captured",
+		})
+	}, nil)
+
+	checkoutCtx, cancel := context.WithTimeout(ctx, checkoutTimeout)
+	defer cancel()
+	if err := checkout.run(checkoutCtx); err != nil {
+		if id != 0 {
+			ordersCreated.WithLabelValues(string(store.Cancelled)).Inc()
+		}
+		o.logger.InfoContext(ctx, "error at CreateOrder", slog.String("error", err.Error()))
+		return 0, err
+	}
+	ordersCreated.WithLabelValues(string(store.Completed)).Inc()
+
+	return id, nil
 }
 
 // GetOrder returns the order associated with the given id.
 func (o *OrderService) GetOrder(ctx context.Context, id int) (*store.Order, error) {
 	if id == 0 {
-		o.logger.Info("error at GetOrder", slog.String("error", errEmptyId.Error()))
+		o.logger.InfoContext(ctx, "error at GetOrder", slog.String("error", errEmptyId.Error()))
 		return nil, errEmptyId
 	}
 
 	return o.db.RetrieveOrder(ctx, id)
 }
 
-// GetOrdersByUser returns all the orders for a given user id.
-func (o *OrderService) GetOrdersByUser(ctx context.Context, userID int) ([]*store.Order, error) {
+// GetCallerOrder returns the order with the given id if the caller in ctx is its user or holds any
+// of the given permissions, like auth.Authorize. Orders the caller may not see are not found, so
+// the ids of the orders of other users cannot be probed.
+func (o *OrderService) GetCallerOrder(ctx context.Context, id int, permissions ...string) (*store.Order, error) {
+	if _, ok := auth.FromContext(ctx); !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	order, err := o.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, order.UserID, permissions...); err != nil {
+		o.logger.InfoContext(ctx, "error at GetCallerOrder", slog.Int("order", id), slog.String("error", err.Error()))
+		return nil, shared.DBError(pgx.ErrNoRows, "order")
+	}
+
+	return order, nil
+}
+
+// GetOrdersByUser returns a page of the orders of the given user matching the filter.
+func (o *OrderService) GetOrdersByUser(ctx context.Context, userID int, filter store.OrderFilter, req page.Request) (*page.Page[*store.Order], error) {
 	if userID == 0 {
-		o.logger.Info("error at GetOrdersByUser", slog.String("error", errEmptyUserID.Error()))
+		o.logger.InfoContext(ctx, "error at GetOrdersByUser", slog.String("error", errEmptyUserID.Error()))
 		return nil, errEmptyUserID
 	}
+	if filter.Status != "" {
+		if err := validateStatus(filter.Status); err != nil {
+			o.logger.InfoContext(ctx, "error at GetOrdersByUser", slog.String("error", err.Error()))
+			return nil, err
+		}
+	}
 
-	return o.db.RetrieveOrdersByUserID(ctx, userID)
+	return o.db.RetrieveOrdersByUserID(ctx, userID, filter, req)
 }
 
-// UpdateOrder updates the order with the given id.
-func (o *OrderService) UpdateOrder(ctx context.Context, id int, status store.OrderStatus, totalPrice float64) error {
+// UpdateOrder updates the total price of the order with the given id and moves it to the given status.
+// The total price can only change before the order is paid and must be in the currency of the order.
+// Changing the status follows the same rules as UpdateOrderStatus.
+func (o *OrderService) UpdateOrder(ctx context.Context, id int, status store.OrderStatus, totalPrice money.Money) error {
 	if id == 0 {
-		o.logger.Info("error at UpdateOrder", slog.String("error", errEmptyId.Error()))
+		o.logger.InfoContext(ctx, "error at UpdateOrder", slog.String("error", errEmptyId.Error()))
 		return errEmptyId
 	}
 	if status == "" {
-		o.logger.Info("error at UpdateOrder", slog.String("error", errEmptyStatus.Error()))
+		o.logger.InfoContext(ctx, "error at UpdateOrder", slog.String("error", errEmptyStatus.Error()))
 		return errEmptyStatus
 	}
-	if totalPrice == 0.0 {
-		o.logger.Info("error at UpdateOrder", slog.String("error", errEmptyTotalPrice.Error()))
+	if err := validateStatus(status); err != nil {
+		o.logger.InfoContext(ctx, "error at UpdateOrder", slog.String("error", err.Error()))
+		return err
+	}
+	if totalPrice.IsZero() {
+		o.logger.InfoContext(ctx, "error at UpdateOrder", slog.String("error", errEmptyTotalPrice.Error()))
 		return errEmptyTotalPrice
 	}
 
-	return o.db.UpdateOrder(ctx, id, store.Order{
+	order, err := o.db.RetrieveOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !totalPrice.SameCurrency(order.TotalPrice) {
+		o.logger.InfoContext(ctx, "error at UpdateOrder", slog.String("error", errTotalCurrency.Error()))
+		return errTotalCurrency
+	}
+	if order.Status != store.Pending && totalPrice.Cmp(order.TotalPrice) != 0 {
+		o.logger.InfoContext(ctx, "error at UpdateOrder", slog.String("error", errPaidOrderTotal.Error()))
+		return errPaidOrderTotal
+	}
+
+	var change *store.StatusChange
+	if status != order.Status {
+		if err := validateTransition(order.Status, status); err != nil {
+			o.logger.InfoContext(ctx, "error at UpdateOrder", slog.String("error", err.Error()))
+			return err
+		}
+		change = &store.StatusChange{
+			From:  &order.Status,
+			To:    status,
+			Actor: actor(ctx),
+		}
+	}
+
+	err = o.db.UpdateOrder(ctx, id, store.Order{
 		ID:         id,
-		Status:     status,
 		TotalPrice: totalPrice,
-	})
+	}, change)
+	if errors.Is(err, store.ErrStatusChanged) {
+		return o.statusConflict(ctx, id, status)
+	}
+	if err != nil {
+		return err
+	}
+	if change != nil && status == store.Cancelled {
+		return o.cancelled(ctx, order, "order cancelled")
+	}
+
+	return nil
 }
 
-// UpdateOrderStatus updates the order status with the given id.
-func (o *OrderService) UpdateOrderStatus(ctx context.Context, id int, status store.OrderStatus) error {
+// UpdateOrderStatus moves the order with the given id to the given status and records
+// the change in the status history. Returns a TransitionError if the order cannot move
+// from its current status to the given one, which includes completing a pending order since
+// only the checkout does that once the payment is captured. Cancelling an order releases its stock and
+// cancelling a completed order refunds what was not refunded yet, once the order is cancelled so a
+// concurrent transition cannot leave a refunded order completed or shipped.
+func (o *OrderService) UpdateOrderStatus(ctx context.Context, id int, status store.OrderStatus, reason string) error {
 	if id == 0 {
-		o.logger.Info("error at UpdateOrder", slog.String("error", errEmptyId.Error()))
+		o.logger.InfoContext(ctx, "error at UpdateOrderStatus", slog.String("error", errEmptyId.Error()))
 		return errEmptyId
 	}
 	if status == "" {
-		o.logger.Info("error at UpdateOrder", slog.String("error", errEmptyStatus.Error()))
+		o.logger.InfoContext(ctx, "error at UpdateOrderStatus", slog.String("error", errEmptyStatus.Error()))
 		return errEmptyStatus
 	}
+	if err := validateStatus(status); err != nil {
+		o.logger.InfoContext(ctx, "error at UpdateOrderStatus", slog.String("error", err.Error()))
+		return err
+	}
+
+	order, err := o.db.RetrieveOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := validateTransition(order.Status, status); err != nil {
+		o.logger.InfoContext(ctx, "error at UpdateOrderStatus", slog.String("error", err.Error()))
+		return err
+	}
+
+	err = o.db.UpdateOrderStatus(ctx, id, store.StatusChange{
+		From:   &order.Status,
+		To:     status,
+		Actor:  actor(ctx),
+		Reason: reason,
+	})
+	if errors.Is(err, store.ErrStatusChanged) {
+		return o.statusConflict(ctx, id, status)
+	}
+	if err != nil {
+		return err
+	}
+	if status == store.Cancelled {
+		return o.cancelled(ctx, order, reason)
+	}
+
+	return nil
+}
+
+// GetOrderStatusHistory returns every status change of the order with the given id, oldest first.
+func (o *OrderService) GetOrderStatusHistory(ctx context.Context, id int) ([]*store.StatusHistoryEntry, error) {
+	if id == 0 {
+		o.logger.InfoContext(ctx, "error at GetOrderStatusHistory", slog.String("error", errEmptyId.Error()))
+		return nil, errEmptyId
+	}
+
+	return o.db.RetrieveOrderStatusHistory(ctx, id)
+}
+
+// statusConflict reports a transition that lost a race with a concurrent one
+// against the status the order ended up in.
+func (o *OrderService) statusConflict(ctx context.Context, id int, to store.OrderStatus) error {
+	order, err := o.db.RetrieveOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = &TransitionError{From: order.Status, To: to}
+	o.logger.InfoContext(ctx, "error at statusConflict", slog.String("error", err.Error()))
+	return err
+}
+
+// cancelled refunds what was not refunded yet of an order that was just cancelled, if it was paid,
+// and releases its stock. The order is already cancelled, so both run even if ctx is done.
+func (o *OrderService) cancelled(ctx context.Context, order *store.Order, reason string) error {
+	ctx = context.WithoutCancel(ctx)
+
+	var errs []error
+	if order.Status == store.Completed {
+		if err := o.refundOutstanding(ctx, order, reason); err != nil {
+			o.logger.ErrorContext(ctx, "error at cancelled", slog.Int("order", order.ID), slog.String("error", err.Error()))
+			errs = append(errs, err)
+		}
+	}
+	if err := o.releaseStock(ctx, order); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// releaseStock gives back the stock reserved for every item of a cancelled order.
+// Failures are logged, releasing is idempotent so it can be retried safely.
+func (o *OrderService) releaseStock(ctx context.Context, order *store.Order) error {
+	var errs []error
+	for _, item := range order.Items {
+		if err := o.inventory.ReleaseStock(ctx, order.ID, item.SKUID, 0); err != nil {
+			o.logger.ErrorContext(ctx, "error at releaseStock", slog.Int("order", order.ID), slog.Int("sku", item.SKUID), slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("sku %d: %w", item.SKUID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// priceItems resolves the SKU of every item and sets its price to the current SKU price in the
+// given currency. Items given by product id and by SKU id may turn out to be the same SKU, they are merged.
+func (o *OrderService) priceItems(ctx context.Context, items []store.OrderItem, currency string) ([]store.OrderItem, error) {
+	priced := make([]store.OrderItem, 0, len(items))
+	positions := make(map[int]int, len(items))
+	for _, item := range items {
+		sku, err := o.getSKU(ctx, item.SKUID, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+
+		if i, ok := positions[sku.ID]; ok {
+			priced[i].Quantity += item.Quantity
+			continue
+		}
+		price, rate, err := o.skuPrice(ctx, sku, currency)
+		if err != nil {
+			return nil, err
+		}
+		positions[sku.ID] = len(priced)
+		priced = append(priced, store.OrderItem{
+			ProductID: sku.ProductID,
+			SKUID:     sku.ID,
+			Quantity:  item.Quantity,
+			Price:     price,
+			Rate:      rate,
+		})
+	}
+
+	return priced, nil
+}
+
+// skuPrice returns the price of a SKU in the given currency: its own price if it is in that
+// currency, the price it has set for it or else its price converted at the current exchange
+// rate, which is returned along with it.
+func (o *OrderService) skuPrice(ctx context.Context, sku *SKU, currency string) (money.Money, *money.Rate, error) {
+	if sku.Price.CurrencyCode() == currency {
+		return sku.Price, nil, nil
+	}
+	if price, ok := sku.Prices[currency]; ok {
+		return price, nil, nil
+	}
+
+	rate, err := o.rates.Rate(ctx, sku.Price.CurrencyCode(), currency)
+	if err != nil {
+		return money.Money{}, nil, fmt.Errorf("sku %d: %w", sku.ID, err)
+	}
+	price, err := rate.Convert(sku.Price)
+	if err != nil {
+		return money.Money{}, nil, fmt.Errorf("sku %d: %w", sku.ID, err)
+	}
+
+	return price, &rate, nil
+}
+
+// getSKU returns the SKU of an item, checking it belongs to the item product if both are given.
+func (o *OrderService) getSKU(ctx context.Context, skuID, productID int) (*SKU, error) {
+	sku, err := o.inventory.GetSKU(ctx, skuID, productID)
+	if err != nil {
+		if skuID != 0 {
+			return nil, fmt.Errorf("sku %d: %w", skuID, err)
+		}
+		return nil, fmt.Errorf("product %d: %w", productID, err)
+	}
+	if productID != 0 && sku.ProductID != productID {
+		return nil, fmt.Errorf("sku %d: %w", skuID, errSKUMismatch)
+	}
+
+	return sku, nil
+}
+
+// itemKey identifies the items for the same SKU, or for the same product when the SKU is not given yet.
+type itemKey struct {
+	productID int
+	skuID     int
+}
+
+// mergeItems validates the given items and merges the quantities of items for the same SKU,
+// or for the same product when no SKU is given.
+func mergeItems(items []store.OrderItem) ([]store.OrderItem, error) {
+	if len(items) == 0 {
+		return nil, errEmptyItems
+	}
+
+	merged := make([]store.OrderItem, 0, len(items))
+	positions := make(map[itemKey]int, len(items))
+	for _, item := range items {
+		if item.ProductID == 0 && item.SKUID == 0 {
+			return nil, errEmptyProductID
+		}
+		if item.Quantity <= 0 {
+			return nil, errInvalidQuantity
+		}
+
+		key := itemKey{productID: item.ProductID, skuID: item.SKUID}
+		if i, ok := positions[key]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		positions[key] = len(merged)
+		merged = append(merged, store.OrderItem{
+			ProductID: item.ProductID,
+			SKUID:     item.SKUID,
+			Quantity:  item.Quantity,
+		})
+	}
 
-	return o.db.UpdateOrderStatus(ctx, id, status)
+	return merged, nil
 }
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+func TestMergeItems(t *testing.T) {
+	items, err := mergeItems([]store.OrderItem{
+		{ProductID: 1, Quantity: 1},
+		{ProductID: 2, Quantity: 3},
+		{ProductID: 1, Quantity: 2, Price: money.MustParse("99", "")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(items))
+	}
+	if items[0].ProductID != 1 || items[0].Quantity != 3 {
+		t.Fatalf("wanted %d of product %d, got %d of product %d", 3, 1, items[0].Quantity, items[0].ProductID)
+	}
+	if !items[0].Price.IsZero() {
+		t.Fatalf("wanted client prices to be ignored, got %s", items[0].Price)
+	}
+
+	items, err = mergeItems([]store.OrderItem{
+		{SKUID: 4, Quantity: 1},
+		{ProductID: 1, SKUID: 4, Quantity: 1},
+		{SKUID: 4, Quantity: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Quantity != 3 {
+		t.Fatalf("wanted %d items with %d of sku %d, got %v", 2, 3, 4, items)
+	}
+
+	if _, err := mergeItems(nil); !errors.Is(err, errEmptyItems) {
+		t.Fatalf("wanted %v, got %v", errEmptyItems, err)
+	}
+	if _, err := mergeItems([]store.OrderItem{{ProductID: 1}}); !errors.Is(err, errInvalidQuantity) {
+		t.Fatalf("wanted %v, got %v", errInvalidQuantity, err)
+	}
+	if _, err := mergeItems([]store.OrderItem{{Quantity: 1}}); !errors.Is(err, errEmptyProductID) {
+		t.Fatalf("wanted %v, got %v", errEmptyProductID, err)
+	}
+}
+
+// testRates is a money.RateSource of fixed rates.
+type testRates money.Rates
+
+func (tr *testRates) LoadRates(ctx context.Context) (*money.Rates, error) {
+	return (*money.Rates)(tr), nil
+}
+
+func TestSKUPrice(t *testing.T) {
+	ctx := context.Background()
+	o := &OrderService{
+		rates: money.NewRateCache(&testRates{
+			Base:   "USD",
+			Values: map[string]*big.Rat{"EUR": big.NewRat(92, 100)},
+		}, 0),
+	}
+	sku := &SKU{
+		ID:     1,
+		Price:  money.MustParse("10", "USD"),
+		Prices: money.Prices{"GBP": money.MustParse("8.50", "GBP")},
+	}
+
+	tests := []struct {
+		currency string
+		want     money.Money
+		rate     string
+	}{
+		{currency: "USD", want: money.MustParse("10", "USD")},
+		{currency: "GBP", want: money.MustParse("8.50", "GBP")},
+		{currency: "EUR", want: money.MustParse("9.20", "EUR"), rate: "0.92"},
+	}
+	for _, test := range tests {
+		price, rate, err := o.skuPrice(ctx, sku, test.currency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price != test.want {
+			t.Fatalf("%s: wanted %s, got %s", test.currency, test.want, price)
+		}
+		if (rate == nil) != (test.rate == "") || (rate != nil && rate.String() != test.rate) {
+			t.Fatalf("%s: wanted a rate of %q, got %v", test.currency, test.rate, rate)
+		}
+	}
+
+	if _, _, err := o.skuPrice(ctx, sku, "JPY"); !errors.Is(err, money.ErrUnknownRate) {
+		t.Fatalf("wanted %v, got %v", money.ErrUnknownRate, err)
+	}
+}
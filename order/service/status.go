@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+)
+
+// systemActor is recorded in the status history for transitions made by the order service itself.
+const systemActor = "system"
+
+var (
+	// ErrInvalidTransition is wrapped by every TransitionError.
+	ErrInvalidTransition = shared.ConflictError("invalid order status transition")
+
+	errInvalidStatus = shared.InvalidFieldError("status", "status must be one of pending, completed, shipped or cancelled")
+)
+
+// transitions holds the statuses every status can be moved to by UpdateOrderStatus and UpdateOrder.
+// Pending orders are completed only by the checkout once their payment is captured, shipped and
+// cancelled orders are final.
+var transitions = map[store.OrderStatus][]store.OrderStatus{
+	store.Pending:   {store.Cancelled},
+	store.Completed: {store.Shipped, store.Cancelled},
+	store.Shipped:   {},
+	store.Cancelled: {},
+}
+
+// TransitionError is returned when an order cannot move from its current status to the requested one.
+type TransitionError struct {
+	From store.OrderStatus
+	To   store.OrderStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change order status from %s to %s", e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// validateStatus checks that status is a known order status.
+func validateStatus(status store.OrderStatus) error {
+	if _, ok := transitions[status]; !ok {
+		return errInvalidStatus
+	}
+
+	return nil
+}
+
+// validateTransition checks that an order can move from one status to the other.
+func validateTransition(from, to store.OrderStatus) error {
+	if err := validateStatus(to); err != nil {
+		return err
+	}
+	if !slices.Contains(transitions[from], to) {
+		return &TransitionError{From: from, To: to}
+	}
+
+	return nil
+}
+
+// actor returns who is performing the operation in ctx for the status history.
+func actor(ctx context.Context) string {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return systemActor
+	}
+	if identity.UserID == 0 {
+		return fmt.Sprintf("service:%s", identity.Email)
+	}
+
+	return fmt.Sprintf("user:%d", identity.UserID)
+}
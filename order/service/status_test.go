@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PseudoMera/virtual-store/order/store"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+)
+
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		from  store.OrderStatus
+		to    store.OrderStatus
+		valid bool
+	}{
+		{store.Pending, store.Completed, false},
+		{store.Pending, store.Cancelled, true},
+		{store.Pending, store.Shipped, false},
+		{store.Pending, store.Pending, false},
+		{store.Completed, store.Shipped, true},
+		{store.Completed, store.Cancelled, true},
+		{store.Completed, store.Pending, false},
+		{store.Shipped, store.Cancelled, false},
+		{store.Cancelled, store.Shipped, false},
+		{store.Cancelled, store.Pending, false},
+	}
+
+	for _, tt := range tests {
+		err := validateTransition(tt.from, tt.to)
+		if tt.valid && err != nil {
+			t.Fatalf("wanted %s to %s to be valid, got %v", tt.from, tt.to, err)
+		}
+		if tt.valid {
+			continue
+		}
+
+		var transitionErr *TransitionError
+		if !errors.As(err, &transitionErr) {
+			t.Fatalf("wanted a TransitionError for %s to %s, got %v", tt.from, tt.to, err)
+		}
+		if !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("wanted %v, got %v", ErrInvalidTransition, err)
+		}
+	}
+}
+
+func TestValidateUnknownStatus(t *testing.T) {
+	if err := validateTransition(store.Pending, "refunded"); !errors.Is(err, errInvalidStatus) {
+		t.Fatalf("wanted %v, got %v", errInvalidStatus, err)
+	}
+}
+
+func TestActor(t *testing.T) {
+	ctx := context.Background()
+	if got := actor(ctx); got != systemActor {
+		t.Fatalf("wanted %s, got %s", systemActor, got)
+	}
+
+	userCtx := auth.NewContext(ctx, auth.Identity{UserID: 7})
+	if got := actor(userCtx); got != "user:7" {
+		t.Fatalf("wanted %s, got %s", "user:7", got)
+	}
+
+	serviceCtx := auth.NewContext(ctx, auth.Identity{Email: "payment"})
+	if got := actor(serviceCtx); got != "service:payment" {
+		t.Fatalf("wanted %s, got %s", "service:payment", got)
+	}
+}
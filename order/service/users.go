@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	usergrpc "github.com/PseudoMera/virtual-store/user/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUserNotFound is returned when an order or cart references a user that does not exist.
+var ErrUserNotFound = shared.NotFoundError("user not found")
+
+// Users checks the users that orders and carts belong to. The users live in the database of the
+// user service, so the order database cannot reference them with foreign keys.
+// CheckUser returns ErrUserNotFound for unknown users. Currency returns the currency a user buys
+// in, the currency of the country of their profile or money.DefaultCurrency.
+type Users interface {
+	CheckUser(ctx context.Context, userID int) error
+	Currency(ctx context.Context, userID int) (string, error)
+}
+
+type userDirectory struct {
+	client usergrpc.UserServiceClient
+}
+
+// NewUserDirectory returns Users backed by the user service gRPC API.
+// The client connection must carry credentials holding the user:read permission.
+func NewUserDirectory(client usergrpc.UserServiceClient) Users {
+	return &userDirectory{
+		client: client,
+	}
+}
+
+func (ud *userDirectory) CheckUser(ctx context.Context, userID int) error {
+	_, err := ud.client.GetUserByID(ctx, &usergrpc.GetUserByIDRequest{
+		Id: int64(userID),
+	})
+	if status.Code(err) == codes.NotFound {
+		return ErrUserNotFound
+	}
+
+	return readError(err)
+}
+
+func (ud *userDirectory) Currency(ctx context.Context, userID int) (string, error) {
+	profile, err := ud.client.GetUserProfile(ctx, &usergrpc.GetUserProfileRequest{
+		Id: int64(userID),
+	})
+	// Users without a profile have no country.
+	if status.Code(err) == codes.NotFound {
+		return money.DefaultCurrency, nil
+	}
+	if err != nil {
+		return "", readError(err)
+	}
+
+	return money.CountryCurrency(profile.Country), nil
+}
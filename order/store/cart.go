@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/jackc/pgx/v5"
+)
+
+// Cart holds the products a user intends to order.
+// Every user has at most one cart, its updated_at is refreshed whenever its items change
+// so idle carts can be expired. Its items are priced in its Currency.
+type Cart struct {
+	ID         int
+	UserID     int
+	Currency   string
+	TotalPrice money.Money
+	Items      []CartItem
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// CartItem is a quantity of a SKU of a product in a cart.
+// Price is the SKU price when the item was last added or updated.
+type CartItem struct {
+	ProductID int
+	SKUID     int
+	Quantity  int
+	Price     money.Money
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RetrieveCart returns the cart of the given user with its items and total price.
+// Returns pgx.ErrNoRows if the user has no cart.
+func (s *Store) RetrieveCart(ctx context.Context, userID int) (*Cart, error) {
+	cart := new(Cart)
+	err := s.db.QueryRow(ctx, "SELECT id, user_id, currency, created_at, updated_at FROM cart WHERE user_id = $1", userID).Scan(&cart.ID, &cart.UserID, &cart.Currency, &cart.CreatedAt, &cart.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	cart.TotalPrice = money.New(0, cart.Currency)
+
+	rows, err := s.db.Query(ctx, "SELECT product_id, sku_id, quantity, price, created_at, updated_at FROM cart_item WHERE cart_id = $1 ORDER BY created_at, sku_id", cart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var item CartItem
+	item.Price.Currency = cart.Currency
+	_, err = pgx.ForEachRow(rows, []any{&item.ProductID, &item.SKUID, &item.Quantity, &item.Price, &item.CreatedAt, &item.UpdatedAt}, func() error {
+		cart.Items = append(cart.Items, item)
+		cart.TotalPrice = cart.TotalPrice.Add(item.Price.Mul(item.Quantity))
+		return nil
+	})
+
+	return cart, err
+}
+
+// StoreCartItems sets the quantity and price of SKUs in the cart of the given user, creating the
+// cart if the user has none. The prices are in the given currency, which becomes the currency of
+// the cart. When it changes the items that are not given, priced in the previous currency, are removed.
+func (s *Store) StoreCartItems(ctx context.Context, userID int, currency string, items ...CartItem) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	cartID, err := touchCart(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	var previousCurrency string
+	err = tx.QueryRow(ctx, "UPDATE cart c SET currency = $2 FROM (SELECT id, currency FROM cart WHERE id = $1 FOR UPDATE) previous WHERE c.id = previous.id RETURNING previous.currency", cartID, currency).Scan(&previousCurrency)
+	if err != nil {
+		return err
+	}
+
+	skuIDs := make([]int, len(items))
+	for i, item := range items {
+		if item.Price.CurrencyCode() != currency {
+			return fmt.Errorf("sku %d: %w: price in %s for a cart in %s", item.SKUID, money.ErrInvalidCurrency, item.Price.CurrencyCode(), currency)
+		}
+		_, err = tx.Exec(ctx, "INSERT INTO cart_item(cart_id, product_id, sku_id, quantity, price) VALUES($1, $2, $3, $4, $5) ON CONFLICT (cart_id, sku_id) DO UPDATE SET quantity = EXCLUDED.quantity, price = EXCLUDED.price", cartID, item.ProductID, item.SKUID, item.Quantity, item.Price)
+		if err != nil {
+			return err
+		}
+		skuIDs[i] = item.SKUID
+	}
+	if previousCurrency != currency {
+		if _, err := tx.Exec(ctx, "DELETE FROM cart_item WHERE cart_id = $1 AND sku_id <> ALL($2)", cartID, skuIDs); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteCartItem removes a SKU from the cart of the given user.
+// Returns pgx.ErrNoRows if the SKU is not in the cart.
+func (s *Store) DeleteCartItem(ctx context.Context, userID, skuID int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, "DELETE FROM cart_item USING cart WHERE cart.id = cart_item.cart_id AND cart.user_id = $1 AND cart_item.sku_id = $2", userID, skuID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if _, err := touchCart(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteOrderedCartItems removes the given items from the cart of the given user once they were
+// ordered. Items whose quantity, price or currency changed since they were read were not ordered as
+// they are and stay in the cart. The cart is removed when no items are left.
+func (s *Store) DeleteOrderedCartItems(ctx context.Context, userID int, currency string, items ...CartItem) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	for _, item := range items {
+		_, err := tx.Exec(ctx, "DELETE FROM cart_item USING cart WHERE cart.id = cart_item.cart_id AND cart.user_id = $1 AND cart.currency = $2 AND cart_item.sku_id = $3 AND cart_item.quantity = $4 AND cart_item.price = $5", userID, currency, item.SKUID, item.Quantity, item.Price)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM cart WHERE user_id = $1 AND NOT EXISTS (SELECT 1 FROM cart_item WHERE cart_item.cart_id = cart.id)", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteCart removes the cart of the given user and its items, if any.
+func (s *Store) DeleteCart(ctx context.Context, userID int) error {
+	_, err := s.db.Exec(ctx, "DELETE FROM cart WHERE user_id = $1", userID)
+	return err
+}
+
+// DeleteIdleCarts removes the carts that were not updated for longer than idle and returns how many were removed.
+// If userIDs are given only their carts are considered.
+func (s *Store) DeleteIdleCarts(ctx context.Context, idle time.Duration, userIDs ...int) (int64, error) {
+	tag, err := s.db.Exec(ctx, "DELETE FROM cart WHERE updated_at < CURRENT_TIMESTAMP - make_interval(secs => $1) AND (COALESCE(cardinality($2::int[]), 0) = 0 OR user_id = ANY($2))", idle.Seconds(), userIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// touchCart creates the cart of the given user or refreshes its updated_at and returns its id.
+func touchCart(ctx context.Context, tx pgx.Tx, userID int) (int, error) {
+	var id int
+	err := tx.QueryRow(ctx, "INSERT INTO cart(user_id) VALUES($1) ON CONFLICT (user_id) DO UPDATE SET updated_at = CURRENT_TIMESTAMP RETURNING id", userID).Scan(&id)
+
+	return id, err
+}
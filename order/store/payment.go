@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+type PaymentOperation string
+
+var (
+	PaymentAuthorize PaymentOperation = "authorize"
+	PaymentCapture   PaymentOperation = "capture"
+	PaymentVoid      PaymentOperation = "void"
+	PaymentRefund    PaymentOperation = "refund"
+)
+
+type PaymentOutcome string
+
+var (
+	PaymentApproved PaymentOutcome = "approved"
+	PaymentDeclined PaymentOutcome = "declined"
+	PaymentTimeout  PaymentOutcome = "timeout"
+	PaymentFailed   PaymentOutcome = "failed"
+)
+
+// paymentColumns are the columns scanned into paymentFields, the amount is in the currency of the order.
+const paymentColumns = "id, user_order_id, operation, outcome, (SELECT currency FROM user_order WHERE user_order.id = payment.user_order_id), amount, authorization_id, card_last4, error, created_at, updated_at"
+
+// Payment is an attempt of a payment gateway operation for an order and its outcome.
+// Only the last four digits of the card are stored and the amount is in the currency of the order.
+type Payment struct {
+	ID              int
+	OrderID         int
+	Operation       PaymentOperation
+	Outcome         PaymentOutcome
+	Amount          money.Money
+	AuthorizationID string
+	CardLast4       string
+	Error           string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// paymentFields returns the scan targets of paymentColumns.
+func paymentFields(payment *Payment) []any {
+	return []any{&payment.ID, &payment.OrderID, &payment.Operation, &payment.Outcome, &payment.Amount.Currency, &payment.Amount, &payment.AuthorizationID, &payment.CardLast4, &payment.Error, &payment.CreatedAt, &payment.UpdatedAt}
+}
+
+func (s *Store) StorePayment(ctx context.Context, payment Payment) (int, error) {
+	var id int
+	err := s.db.QueryRow(ctx, "INSERT INTO payment(user_order_id, operation, outcome, amount, authorization_id, card_last4, error) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		payment.OrderID,
+		string(payment.Operation),
+		string(payment.Outcome),
+		payment.Amount,
+		payment.AuthorizationID,
+		payment.CardLast4,
+		payment.Error,
+	).Scan(&id)
+
+	return id, err
+}
+
+// RetrievePaymentsByOrderID returns every payment attempt of the given order, oldest first.
+func (s *Store) RetrievePaymentsByOrderID(ctx context.Context, orderID int) ([]*Payment, error) {
+	rows, err := s.db.Query(ctx, "SELECT "+paymentColumns+" FROM payment WHERE user_order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*Payment
+	for rows.Next() {
+		payment := new(Payment)
+		if err := rows.Scan(paymentFields(payment)...); err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, rows.Err()
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/jackc/pgx/v5"
+)
+
+// LoadRates implements money.RateSource with the exchange_rate table, which holds the rates from
+// money.DefaultCurrency. The rates are as of the last time any of them was updated.
+func (s *Store) LoadRates(ctx context.Context) (*money.Rates, error) {
+	rows, err := s.db.Query(ctx, "SELECT currency, rate, updated_at FROM exchange_rate")
+	if err != nil {
+		return nil, err
+	}
+
+	rates := &money.Rates{
+		Base:   money.DefaultCurrency,
+		Values: make(map[string]*big.Rat),
+	}
+	var rate money.Rate
+	var updatedAt time.Time
+	_, err = pgx.ForEachRow(rows, []any{&rate.To, &rate, &updatedAt}, func() error {
+		rates.Values[rate.To] = rate.Value
+		if updatedAt.After(rates.AsOf) {
+			rates.AsOf = updatedAt
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+// StoreRate sets a rate from money.DefaultCurrency in the exchange_rate table.
+func (s *Store) StoreRate(ctx context.Context, rate money.Rate) error {
+	if rate.From != money.DefaultCurrency {
+		return fmt.Errorf("%w: rates are from %s, got %s", money.ErrInvalidRate, money.DefaultCurrency, rate.From)
+	}
+
+	_, err := s.db.Exec(ctx, "INSERT INTO exchange_rate(currency, rate) VALUES($1, $2) ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate", rate.To, rate)
+
+	return err
+}
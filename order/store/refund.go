@@ -0,0 +1,304 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/outbox"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrRefundExceeded is returned when a refund asks for more than what is left to refund of an order.
+var ErrRefundExceeded = shared.ConflictError("refund exceeds what is left to refund")
+
+type RefundStatus string
+
+var (
+	RefundPending   RefundStatus = "pending"
+	RefundSucceeded RefundStatus = "succeeded"
+	RefundFailed    RefundStatus = "failed"
+)
+
+// Refund gives back money of the captured payment of an order.
+// Failed refunds do not count towards the refunded amount of the order. Amounts are in
+// the currency of the order.
+type Refund struct {
+	ID        int
+	OrderID   int
+	PaymentID int
+	Amount    money.Money
+	Status    RefundStatus
+	Restock   bool
+	Actor     string
+	Reason    string
+	Items     []RefundItem
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RefundItem is a quantity of an order item being refunded.
+// Amount is the quantity times the price paid for the item.
+type RefundItem struct {
+	ProductID int
+	SKUID     int
+	Quantity  int
+	Amount    money.Money
+}
+
+// RetrieveCapturePayment returns the successful capture of the given order.
+// Returns pgx.ErrNoRows if the order was never paid.
+func (s *Store) RetrieveCapturePayment(ctx context.Context, orderID int) (*Payment, error) {
+	payment := new(Payment)
+	err := s.db.QueryRow(ctx, "SELECT "+paymentColumns+" FROM payment WHERE user_order_id = $1 AND operation = 'capture' AND outcome = 'approved' ORDER BY id DESC LIMIT 1", orderID).Scan(paymentFields(payment)...)
+
+	return payment, err
+}
+
+// StoreRefund records a pending refund of the given items of an order, or of everything
+// that was not refunded yet if there are no items, and adds it to the refunded amount
+// of the order. The order row is locked so concurrent refunds cannot exceed what was paid.
+// Returns the refund with its items and amounts or ErrRefundExceeded.
+func (s *Store) StoreRefund(ctx context.Context, refund Refund) (*Refund, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var totalPrice, refundedAmount money.Money
+	err = tx.QueryRow(ctx, "SELECT currency, currency, total_price, refunded_amount FROM user_order WHERE id = $1 FOR UPDATE", refund.OrderID).Scan(&totalPrice.Currency, &refundedAmount.Currency, &totalPrice, &refundedAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	refundable, err := refundableItems(ctx, tx, refund.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := refund.Items
+	if len(items) == 0 {
+		for _, item := range refundable {
+			if item.Quantity > 0 {
+				items = append(items, RefundItem{ProductID: item.ProductID, SKUID: item.SKUID, Quantity: item.Quantity})
+			}
+		}
+		slices.SortFunc(items, func(a, b RefundItem) int {
+			return a.SKUID - b.SKUID
+		})
+	}
+	if len(items) == 0 {
+		return nil, ErrRefundExceeded
+	}
+
+	refund.Items = make([]RefundItem, len(items))
+	refund.Amount = money.New(0, totalPrice.Currency)
+	for i, item := range items {
+		left, ok := refundable[item.SKUID]
+		if !ok || item.Quantity > left.Quantity {
+			return nil, fmt.Errorf("sku %d: %w", item.SKUID, ErrRefundExceeded)
+		}
+		amount := left.Price.Mul(item.Quantity)
+		refund.Items[i] = RefundItem{
+			ProductID: left.ProductID,
+			SKUID:     item.SKUID,
+			Quantity:  item.Quantity,
+			Amount:    amount,
+		}
+		refund.Amount = refund.Amount.Add(amount)
+	}
+	if refundedAmount.Add(refund.Amount).Cmp(totalPrice) > 0 {
+		return nil, ErrRefundExceeded
+	}
+
+	refund.Status = RefundPending
+	err = tx.QueryRow(ctx, "INSERT INTO refund(user_order_id, payment_id, amount, status, restock, actor, reason) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at",
+		refund.OrderID,
+		refund.PaymentID,
+		refund.Amount,
+		string(refund.Status),
+		refund.Restock,
+		refund.Actor,
+		refund.Reason,
+	).Scan(&refund.ID, &refund.CreatedAt, &refund.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range refund.Items {
+		_, err = tx.Exec(ctx, "INSERT INTO refund_item(refund_id, product_id, sku_id, quantity, amount) VALUES($1, $2, $3, $4, $5)", refund.ID, item.ProductID, item.SKUID, item.Quantity, item.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE user_order SET refunded_amount = refunded_amount + $2 WHERE id = $1", refund.OrderID, refund.Amount); err != nil {
+		return nil, err
+	}
+
+	return &refund, tx.Commit(ctx)
+}
+
+// CompleteRefund marks a pending refund as succeeded and writes a RefundSucceeded event with its
+// items, from which the product service restocks them when the refund asked for it.
+func (s *Store) CompleteRefund(ctx context.Context, id int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	payload := events.RefundSucceededPayload{RefundID: id}
+	err = tx.QueryRow(ctx, "UPDATE refund r SET status = 'succeeded' FROM user_order o WHERE r.id = $1 AND r.status = 'pending' AND o.id = r.user_order_id RETURNING r.user_order_id, o.currency, r.amount, r.restock", id).Scan(&payload.OrderID, &payload.Amount.Currency, &payload.Amount, &payload.Restock)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT product_id, sku_id, quantity FROM refund_item WHERE refund_id = $1 ORDER BY sku_id", id)
+	if err != nil {
+		return err
+	}
+	var item events.RefundItemPayload
+	_, err = pgx.ForEachRow(rows, []any{&item.ProductID, &item.SKUID, &item.Quantity}, func() error {
+		payload.Items = append(payload.Items, item)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := outbox.Add(ctx, tx, events.AggregateOrder, payload.OrderID, events.RefundSucceeded, payload); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// FailRefund marks a pending refund as failed and takes it out of the refunded amount of its order.
+func (s *Store) FailRefund(ctx context.Context, id int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var orderID int
+	var amount money.Money
+	err = tx.QueryRow(ctx, "UPDATE refund SET status = 'failed' WHERE id = $1 AND status = 'pending' RETURNING user_order_id, amount", id).Scan(&orderID, &amount)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE user_order SET refunded_amount = refunded_amount - $2 WHERE id = $1", orderID, amount); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RetrieveRefunds returns the refunds of the given order with their items, oldest first.
+func (s *Store) RetrieveRefunds(ctx context.Context, orderID int) ([]*Refund, error) {
+	rows, err := s.db.Query(ctx, "SELECT r.id, r.user_order_id, r.payment_id, o.currency, r.amount, r.status, r.restock, r.actor, r.reason, r.created_at, r.updated_at FROM refund r JOIN user_order o ON o.id = r.user_order_id WHERE r.user_order_id = $1 ORDER BY r.id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []*Refund
+	positions := make(map[int]int)
+	for rows.Next() {
+		refund := new(Refund)
+		err = rows.Scan(
+			&refund.ID,
+			&refund.OrderID,
+			&refund.PaymentID,
+			&refund.Amount.Currency,
+			&refund.Amount,
+			&refund.Status,
+			&refund.Restock,
+			&refund.Actor,
+			&refund.Reason,
+			&refund.CreatedAt,
+			&refund.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		positions[refund.ID] = len(refunds)
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	itemRows, err := s.db.Query(ctx, "SELECT ri.refund_id, ri.product_id, ri.sku_id, ri.quantity, o.currency, ri.amount FROM refund_item ri JOIN refund r ON r.id = ri.refund_id JOIN user_order o ON o.id = r.user_order_id WHERE r.user_order_id = $1 ORDER BY ri.refund_id, ri.sku_id", orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var refundID int
+	var item RefundItem
+	_, err = pgx.ForEachRow(itemRows, []any{&refundID, &item.ProductID, &item.SKUID, &item.Quantity, &item.Amount.Currency, &item.Amount}, func() error {
+		refund := refunds[positions[refundID]]
+		refund.Items = append(refund.Items, item)
+		return nil
+	})
+
+	return refunds, err
+}
+
+// RetrieveRefund returns the refund with the given id with its items or a not found error.
+func (s *Store) RetrieveRefund(ctx context.Context, id int) (*Refund, error) {
+	var orderID int
+	if err := s.db.QueryRow(ctx, "SELECT user_order_id FROM refund WHERE id = $1", id).Scan(&orderID); err != nil {
+		return nil, shared.DBError(err, "refund")
+	}
+
+	refunds, err := s.RetrieveRefunds(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, refund := range refunds {
+		if refund.ID == id {
+			return refund, nil
+		}
+	}
+
+	return nil, shared.DBError(pgx.ErrNoRows, "refund")
+}
+
+// RetrievePendingRefunds returns the ids of the refunds that have been pending for longer than age, oldest first.
+func (s *Store) RetrievePendingRefunds(ctx context.Context, age time.Duration) ([]int, error) {
+	rows, err := s.db.Query(ctx, "SELECT id FROM refund WHERE status = 'pending' AND updated_at < CURRENT_TIMESTAMP - make_interval(secs => $1) ORDER BY id", age.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+// refundableItems returns the items of an order by SKU id with the quantities that were not
+// refunded yet. Failed refunds give their quantities back.
+func refundableItems(ctx context.Context, tx pgx.Tx, orderID int) (map[int]OrderItem, error) {
+	rows, err := tx.Query(ctx, `SELECT p.product_id, p.sku_id, p.quantity - COALESCE((
+		SELECT SUM(ri.quantity) FROM refund_item ri JOIN refund r ON r.id = ri.refund_id
+		WHERE r.user_order_id = p.user_order_id AND ri.sku_id = p.sku_id AND r.status <> 'failed'
+	), 0), o.currency, p.price FROM user_order_product p JOIN user_order o ON o.id = p.user_order_id WHERE p.user_order_id = $1`, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[int]OrderItem)
+	var item OrderItem
+	_, err = pgx.ForEachRow(rows, []any{&item.ProductID, &item.SKUID, &item.Quantity, &item.Price.Currency, &item.Price}, func() error {
+		items[item.SKUID] = item
+		return nil
+	})
+
+	return items, err
+}
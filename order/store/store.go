@@ -2,8 +2,16 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/outbox"
+	"github.com/PseudoMera/virtual-store/shared/page"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -19,6 +27,9 @@ func NewStore(db *pgxpool.Pool) *Store {
 	}
 }
 
+// ErrStatusChanged is returned when the order status is no longer the one a transition starts from.
+var ErrStatusChanged = shared.ConflictError("order status changed concurrently")
+
 type OrderStatus string
 
 var (
@@ -28,60 +39,383 @@ var (
 	Cancelled OrderStatus = "cancelled"
 )
 
+// orderColumns are the columns scanned into orderFields.
+const orderColumns = "id, user_id, currency, currency, total_price, refunded_amount, status, created_at, updated_at"
+
+// Order is an order of a user. Every amount of the order is in its Currency, the currency of the
+// user when it was placed. RefundedAmount is the part of the TotalPrice that was refunded or is
+// being refunded.
 type Order struct {
-	ID         int
-	UserID     int
-	TotalPrice float64
-	Status     OrderStatus
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	ID             int
+	UserID         int
+	Currency       string
+	TotalPrice     money.Money
+	RefundedAmount money.Money
+	Status         OrderStatus
+	Items          []OrderItem
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// OrderItem is a line of an order for a SKU of a product. Price is the unit price
+// of the SKU at the time the order was placed, in the currency of the order. Rate is
+// the exchange rate the price was converted with, nil if the SKU had a price in it.
+type OrderItem struct {
+	ProductID int
+	SKUID     int
+	Quantity  int
+	Price     money.Money
+	Rate      *money.Rate
+}
+
+// orderFields returns the scan targets of orderColumns. The currency is scanned into the amounts
+// first, they are read in it.
+func orderFields(order *Order) []any {
+	return []any{&order.ID, &order.UserID, &order.TotalPrice.Currency, &order.RefundedAmount.Currency, &order.TotalPrice, &order.RefundedAmount, &order.Status, &order.CreatedAt, &order.UpdatedAt}
+}
+
+// StatusChange is a transition of an order status.
+// From is nil for the initial status of a new order.
+type StatusChange struct {
+	From   *OrderStatus
+	To     OrderStatus
+	Actor  string
+	Reason string
 }
 
-func (s *Store) StoreOrder(ctx context.Context, order Order) (int, error) {
+// StatusHistoryEntry is a recorded StatusChange of an order.
+type StatusHistoryEntry struct {
+	ID        int
+	OrderID   int
+	From      *OrderStatus
+	To        OrderStatus
+	Actor     string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// StoreOrder creates a new order with its items in a single transaction.
+// The caller sets the SKU and the price of every item to a snapshot of the current SKU price in
+// the currency of the order, money.DefaultCurrency if it is empty, the products live in the product
+// service database. The order total
+// is computed from the item prices, any TotalPrice set by the caller is ignored.
+// The initial status is recorded in the status history with the given actor and published as an OrderStatusChanged event.
+func (s *Store) StoreOrder(ctx context.Context, order Order, actor string) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if order.Currency == "" {
+		order.Currency = money.DefaultCurrency
+	}
+
 	var id int
-	err := s.db.QueryRow(ctx, "INSERT INTO user_order(user_id, total_price, status) VALUES($1, $2, $3) RETURNING id", order.UserID, order.TotalPrice, string(order.Status)).Scan(&id)
-	return id, err
+	err = tx.QueryRow(ctx, "INSERT INTO user_order(user_id, currency, total_price, status) VALUES($1, $2, 0, $3) RETURNING id", order.UserID, order.Currency, string(order.Status)).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range order.Items {
+		if item.Price.CurrencyCode() != order.Currency {
+			return 0, fmt.Errorf("sku %d: %w: price in %s for an order in %s", item.SKUID, money.ErrInvalidCurrency, item.Price.CurrencyCode(), order.Currency)
+		}
+		var rateFrom *string
+		var rateAsOf *time.Time
+		if item.Rate != nil {
+			asOf := item.Rate.AsOf.UTC()
+			rateFrom, rateAsOf = &item.Rate.From, &asOf
+		}
+		_, err = tx.Exec(ctx, "INSERT INTO user_order_product(product_id, sku_id, user_order_id, quantity, price, rate_from, rate, rate_as_of) VALUES($1, $2, $3, $4, $5, $6, $7, $8)", item.ProductID, item.SKUID, id, item.Quantity, item.Price, rateFrom, item.Rate, rateAsOf)
+		if err != nil {
+			return 0, fmt.Errorf("sku %d: %w", item.SKUID, err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE user_order SET total_price = (SELECT COALESCE(SUM(price * quantity), 0) FROM user_order_product WHERE user_order_id = $1) WHERE id = $1", id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := storeStatusChange(ctx, tx, id, order.UserID, StatusChange{To: order.Status, Actor: actor}); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit(ctx)
 }
 
 func (s *Store) RetrieveOrder(ctx context.Context, id int) (*Order, error) {
 	order := new(Order)
-	err := s.db.QueryRow(ctx, "SELECT id, user_id, total_price, status, created_at, updated_at FROM user_order WHERE id = $1", id).Scan(&order.ID, &order.UserID, &order.TotalPrice, &order.Status, &order.CreatedAt, &order.UpdatedAt)
-	return order, err
+	err := s.db.QueryRow(ctx, "SELECT "+orderColumns+" FROM user_order WHERE id = $1", id).Scan(orderFields(order)...)
+	if err != nil {
+		return order, shared.DBError(err, "order")
+	}
+	order.Currency = order.TotalPrice.Currency
+
+	items, err := s.retrieveOrderItems(ctx, order.ID)
+	if err != nil {
+		return order, err
+	}
+	order.Items = items[order.ID]
+
+	return order, nil
+}
+
+// OrderFilter selects the orders of a list. Zero values do not filter. Sorting by total price
+// requires Currency, totals of different currencies cannot be compared.
+type OrderFilter struct {
+	Status        OrderStatus
+	Currency      string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// orderSortFields are the fields orders can be sorted by, id is the default.
+var orderSortFields = map[string]page.SortField{
+	"id":          {Column: "id", Type: "int"},
+	"created_at":  {Column: "created_at", Type: "timestamp"},
+	"total_price": {Column: "total_price", Type: "numeric"},
 }
 
-func (s *Store) RetrieveOrdersByUserID(ctx context.Context, userID int) ([]*Order, error) {
-	rows, err := s.db.Query(ctx, "SELECT * FROM user_order WHERE user_id = $1", userID)
+// RetrieveOrdersByUserID retrieves a page of the orders of the given user matching the filter, with their items.
+func (s *Store) RetrieveOrdersByUserID(ctx context.Context, userID int, filter OrderFilter, req page.Request) (*page.Page[*Order], error) {
+	if req.Sort == "total_price" && filter.Currency == "" {
+		return nil, page.ErrSortCurrency
+	}
+
+	var q page.Query
+	q.Where("user_id = " + q.Arg(userID))
+	if filter.Status != "" {
+		q.Where("status = " + q.Arg(string(filter.Status)))
+	}
+	if filter.Currency != "" {
+		q.Where("currency = " + q.Arg(filter.Currency))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q.Where("created_at >= " + q.Arg(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q.Where("created_at < " + q.Arg(filter.CreatedBefore))
+	}
+
+	var total *int
+	if req.WithTotal {
+		var count int
+		if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM user_order"+q.Conditions(), q.Args()...).Scan(&count); err != nil {
+			return nil, err
+		}
+		total = &count
+	}
+
+	orderBy, err := req.Apply(&q, orderSortFields, "id")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(ctx, "SELECT "+orderColumns+" FROM user_order"+q.Conditions()+orderBy, q.Args()...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var orders []*Order
+	var ids []int
 	for rows.Next() {
 		order := new(Order)
+		if err := rows.Scan(orderFields(order)...); err != nil {
+			return nil, err
+		}
+		order.Currency = order.TotalPrice.Currency
+		orders = append(orders, order)
+		ids = append(ids, order.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := s.retrieveOrderItems(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		order.Items = items[order.ID]
+	}
+
+	result := page.New(orders, req, orderKey)
+	result.Total = total
+	return result, nil
+}
+
+// orderKey returns the cursor key of an order for the given sort field.
+func orderKey(order *Order, sort string) (string, int) {
+	switch sort {
+	case "created_at":
+		return order.CreatedAt.Format(time.RFC3339Nano), order.ID
+	case "total_price":
+		return order.TotalPrice.String(), order.ID
+	default:
+		return "", order.ID
+	}
+}
+
+// retrieveOrderItems retrieves the items of the given orders grouped by order id, with the
+// exchange rates their prices were converted with.
+func (s *Store) retrieveOrderItems(ctx context.Context, orderIDs ...int) (map[int][]OrderItem, error) {
+	items := make(map[int][]OrderItem, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return items, nil
+	}
+
+	rows, err := s.db.Query(ctx, "SELECT p.user_order_id, p.product_id, p.sku_id, p.quantity, o.currency, p.price, COALESCE(p.rate_from, ''), p.rate, COALESCE(p.rate_as_of, o.created_at) FROM user_order_product p JOIN user_order o ON o.id = p.user_order_id WHERE p.user_order_id = ANY($1) ORDER BY p.user_order_id, p.product_id, p.sku_id", orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var orderID int
+	var item OrderItem
+	var rateFrom string
+	var rateAsOf time.Time
+	_, err = pgx.ForEachRow(rows, []any{&orderID, &item.ProductID, &item.SKUID, &item.Quantity, &item.Price.Currency, &item.Price, &rateFrom, &item.Rate, &rateAsOf}, func() error {
+		if item.Rate != nil {
+			item.Rate.From, item.Rate.To, item.Rate.AsOf = rateFrom, item.Price.Currency, rateAsOf
+		}
+		items[orderID] = append(items[orderID], item)
+		return nil
+	})
+
+	return items, err
+}
+
+// UpdateOrder updates the total price of the order with the given id, which must be in the
+// currency of the order, and, if change is not nil, transitions its status like UpdateOrderStatus
+// in the same transaction.
+func (s *Store) UpdateOrder(ctx context.Context, id int, order Order, change *StatusChange) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, "UPDATE user_order SET total_price = $2 WHERE id = $1", id, order.TotalPrice)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return shared.DBError(pgx.ErrNoRows, "order")
+	}
+
+	if change != nil {
+		if err := transitionStatus(ctx, tx, id, *change); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UpdateOrderStatus moves the order with the given id from change.From to change.To and records it
+// in the status history. Returns ErrStatusChanged if the order is no longer in change.From.
+func (s *Store) UpdateOrderStatus(ctx context.Context, id int, change StatusChange) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err := transitionStatus(ctx, tx, id, change); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RetrieveStalePendingOrders returns the ids of the orders that have been pending for longer than age, oldest first.
+func (s *Store) RetrieveStalePendingOrders(ctx context.Context, age time.Duration) ([]int, error) {
+	rows, err := s.db.Query(ctx, "SELECT id FROM user_order WHERE status = 'pending' AND created_at < CURRENT_TIMESTAMP - make_interval(secs => $1) ORDER BY id", age.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+// RetrieveOrderStatusHistory returns the status changes of the given order, oldest first.
+func (s *Store) RetrieveOrderStatusHistory(ctx context.Context, id int) ([]*StatusHistoryEntry, error) {
+	rows, err := s.db.Query(ctx, "SELECT id, user_order_id, from_status, to_status, actor, reason, created_at FROM order_status_history WHERE user_order_id = $1 ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*StatusHistoryEntry
+	for rows.Next() {
+		entry := new(StatusHistoryEntry)
 		err = rows.Scan(
-			&order.ID,
-			&order.UserID,
-			&order.TotalPrice,
-			&order.Status,
-			&order.CreatedAt,
-			&order.UpdatedAt,
+			&entry.ID,
+			&entry.OrderID,
+			&entry.From,
+			&entry.To,
+			&entry.Actor,
+			&entry.Reason,
+			&entry.CreatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
-		orders = append(orders, order)
+		history = append(history, entry)
 	}
 
-	return orders, nil
+	return history, rows.Err()
 }
 
-func (s *Store) UpdateOrder(ctx context.Context, id int, order Order) error {
-	_, err := s.db.Exec(ctx, "UPDATE user_order SET status = $2, total_price = $3 WHERE id = $1", id, string(order.Status), order.TotalPrice)
-	return err
+// transitionStatus updates the order status only if it still is change.From, so concurrent
+// transitions cannot both succeed, and records the change in the status history.
+func transitionStatus(ctx context.Context, tx pgx.Tx, id int, change StatusChange) error {
+	if change.From == nil {
+		return errors.New("status change of an existing order must have a from status")
+	}
+
+	var userID int
+	err := tx.QueryRow(ctx, "UPDATE user_order SET status = $3 WHERE id = $1 AND status = $2 RETURNING user_id", id, string(*change.From), string(change.To)).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM user_order WHERE id = $1)", id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return shared.DBError(pgx.ErrNoRows, "order")
+		}
+		return ErrStatusChanged
+	}
+	if err != nil {
+		return err
+	}
+
+	return storeStatusChange(ctx, tx, id, userID, change)
 }
 
-func (s *Store) UpdateOrderStatus(ctx context.Context, id int, status OrderStatus) error {
-	_, err := s.db.Exec(ctx, "UPDATE user_order SET status = $2 WHERE id = $1", id, string(status))
-	return err
+// storeStatusChange records the change in the status history and writes an OrderStatusChanged event within tx.
+func storeStatusChange(ctx context.Context, tx pgx.Tx, id, userID int, change StatusChange) error {
+	var from *string
+	if change.From != nil {
+		f := string(*change.From)
+		from = &f
+	}
+
+	_, err := tx.Exec(ctx, "INSERT INTO order_status_history(user_order_id, from_status, to_status, actor, reason) VALUES($1, $2, $3, $4, $5)", id, from, string(change.To), change.Actor, change.Reason)
+	if err != nil {
+		return err
+	}
+
+	payload := events.OrderStatusChangedPayload{
+		OrderID: id,
+		UserID:  userID,
+		To:      string(change.To),
+		Actor:   change.Actor,
+		Reason:  change.Reason,
+	}
+	if from != nil {
+		payload.From = *from
+	}
+	return outbox.Add(ctx, tx, events.AggregateOrder, id, events.OrderStatusChanged, payload)
 }
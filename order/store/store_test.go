@@ -2,36 +2,38 @@ package store
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/PseudoMera/virtual-store/order/migrations"
 	"github.com/PseudoMera/virtual-store/shared"
-)
-
-const (
-	projectRootPath = "../../"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
+	"github.com/jackc/pgx/v5"
 )
 
 func TestOrderStore(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	store := NewStore(db.DB())
 
-	var userID int
-	err = db.DB().QueryRow(ctx, "INSERT INTO vstore_user(email, password) VALUES($1, $2) RETURNING id", "email", "password").Scan(&userID)
-	if err != nil {
-		t.Fatal(err)
-	}
+	// Users and products live in the databases of their services, orders only keep their ids.
+	userID := 1
+	productID := 1
 
 	order := Order{
-		UserID:     userID,
-		TotalPrice: 22,
-		Status:     Pending,
+		UserID: userID,
+		Status: Pending,
+		Items: []OrderItem{
+			{ProductID: productID, Quantity: 2, Price: money.MustParse("11", "")},
+		},
 	}
-	id, err := store.StoreOrder(ctx, order)
+	id, err := store.StoreOrder(ctx, order, "test")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -45,6 +47,16 @@ func TestOrderStore(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	if retrievedOrder.TotalPrice != money.MustParse("22", "") {
+		t.Fatalf("wanted %s, got %s", "22.00", retrievedOrder.TotalPrice)
+	}
+	if len(retrievedOrder.Items) != 1 {
+		t.Fatalf("wanted %d, got %d", 1, len(retrievedOrder.Items))
+	}
+	if retrievedOrder.Items[0].Price != money.MustParse("11", "") {
+		t.Fatalf("wanted %s, got %s", "11.00", retrievedOrder.Items[0].Price)
+	}
+
 	if retrievedOrder.UserID != userID {
 		t.Fatalf("wanted %d, got %d", userID, retrievedOrder.UserID)
 	}
@@ -52,23 +64,465 @@ func TestOrderStore(t *testing.T) {
 		t.Fatalf("wanted %d, got %d", id, retrievedOrder.ID)
 	}
 
-	orders, err := store.RetrieveOrdersByUserID(ctx, userID)
+	orders, err := store.RetrieveOrdersByUserID(ctx, userID, OrderFilter{}, page.Request{})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if len(orders) != 1 {
-		t.Fatalf("wanted %d, got %d", 1, len(orders))
+	if len(orders.Items) != 1 {
+		t.Fatalf("wanted %d, got %d", 1, len(orders.Items))
+	}
+	if len(orders.Items[0].Items) != 1 {
+		t.Fatalf("wanted %d, got %d", 1, len(orders.Items[0].Items))
 	}
 
 	if err = store.UpdateOrder(ctx, id, Order{
-		Status:     Cancelled,
-		TotalPrice: 22,
+		TotalPrice: money.MustParse("20", ""),
+	}, &StatusChange{
+		From:  &Pending,
+		To:    Completed,
+		Actor: "test",
 	}); err != nil {
 		t.Fatal(err)
 	}
 
-	if err = store.UpdateOrderStatus(ctx, id, Completed); err != nil {
+	if err = store.UpdateOrderStatus(ctx, id, StatusChange{
+		From:   &Pending,
+		To:     Cancelled,
+		Actor:  "test",
+		Reason: "stale",
+	}); !errors.Is(err, ErrStatusChanged) {
+		t.Fatalf("wanted %v, got %v", ErrStatusChanged, err)
+	}
+
+	if err = store.UpdateOrderStatus(ctx, id, StatusChange{
+		From:   &Completed,
+		To:     Shipped,
+		Actor:  "test",
+		Reason: "handed to carrier",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = store.UpdateOrderStatus(ctx, id+100, StatusChange{
+		From:  &Pending,
+		To:    Completed,
+		Actor: "test",
+	}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+
+	history, err := store.RetrieveOrderStatusHistory(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("wanted %d, got %d", 3, len(history))
+	}
+	if history[0].From != nil || history[0].To != Pending {
+		t.Fatalf("wanted initial %s status, got %v to %s", Pending, history[0].From, history[0].To)
+	}
+	if *history[2].From != Completed || history[2].To != Shipped || history[2].Reason != "handed to carrier" {
+		t.Fatalf("wanted %s to %s, got %s to %s", Completed, Shipped, *history[2].From, history[2].To)
+	}
+}
+
+func TestRetrieveOrdersPages(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	for _, userID := range []int{1, 1, 2, 1} {
+		if _, err := store.StoreOrder(ctx, Order{
+			UserID: userID,
+			Status: Pending,
+			Items: []OrderItem{
+				{ProductID: 1, Quantity: 1, Price: money.MustParse("10", "")},
+			},
+		}, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.UpdateOrderStatus(ctx, 2, StatusChange{From: &Pending, To: Cancelled, Actor: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	orders, err := store.RetrieveOrdersByUserID(ctx, 1, OrderFilter{}, page.Request{Limit: 2, Desc: true, WithTotal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders.Items) != 2 || orders.Items[0].ID != 4 || orders.Items[1].ID != 2 {
+		t.Fatalf("wanted orders %v, got %d orders", []int{4, 2}, len(orders.Items))
+	}
+	if orders.Total == nil || *orders.Total != 3 {
+		t.Fatalf("wanted a total of %d, got %v", 3, orders.Total)
+	}
+
+	orders, err = store.RetrieveOrdersByUserID(ctx, 1, OrderFilter{}, page.Request{Limit: 2, Desc: true, Cursor: orders.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders.Items) != 1 || orders.Items[0].ID != 1 || orders.NextCursor != "" {
+		t.Fatalf("wanted only order %d on the last page, got %d orders", 1, len(orders.Items))
+	}
+
+	orders, err = store.RetrieveOrdersByUserID(ctx, 1, OrderFilter{Status: Cancelled}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders.Items) != 1 || orders.Items[0].ID != 2 {
+		t.Fatalf("wanted only order %d, got %d orders", 2, len(orders.Items))
+	}
+
+	// Totals are only compared within a currency.
+	if _, err := store.RetrieveOrdersByUserID(ctx, 1, OrderFilter{}, page.Request{Sort: "total_price"}); !errors.Is(err, page.ErrSortCurrency) {
+		t.Fatalf("wanted %v, got %v", page.ErrSortCurrency, err)
+	}
+	orders, err = store.RetrieveOrdersByUserID(ctx, 1, OrderFilter{Currency: money.DefaultCurrency}, page.Request{Sort: "total_price"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders.Items) != 3 {
+		t.Fatalf("wanted %d orders, got %d", 3, len(orders.Items))
+	}
+	orders, err = store.RetrieveOrdersByUserID(ctx, 1, OrderFilter{Currency: "JPY"}, page.Request{Sort: "total_price"})
+	if err != nil {
 		t.Fatal(err)
 	}
+	if len(orders.Items) != 0 {
+		t.Fatalf("wanted no orders, got %d", len(orders.Items))
+	}
+}
+
+func TestPaymentStore(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+
+	userID := 1
+
+	orderID, err := store.StoreOrder(ctx, Order{
+		UserID: userID,
+		Status: Pending,
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.StorePayment(ctx, Payment{
+		OrderID:   orderID,
+		Operation: PaymentAuthorize,
+		Outcome:   PaymentDeclined,
+		Amount:    money.MustParse("22", ""),
+		CardLast4: "0002",
+		Error:     "payment declined",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.StorePayment(ctx, Payment{
+		OrderID:         orderID,
+		Operation:       PaymentAuthorize,
+		Outcome:         PaymentApproved,
+		Amount:          money.MustParse("22", ""),
+		AuthorizationID: "auth_1",
+		CardLast4:       "4242",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	payments, err := store.RetrievePaymentsByOrderID(ctx, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(payments))
+	}
+	if payments[0].Outcome != PaymentDeclined || payments[1].AuthorizationID != "auth_1" {
+		t.Fatalf("wanted %s and %s, got %s and %s", PaymentDeclined, "auth_1", payments[0].Outcome, payments[1].AuthorizationID)
+	}
+}
+
+func TestRefundStore(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+
+	userID := 1
+	productID := 1
+
+	orderID, err := store.StoreOrder(ctx, Order{
+		UserID: userID,
+		Status: Completed,
+		Items: []OrderItem{
+			{ProductID: productID, Quantity: 2, Price: money.MustParse("11", "")},
+		},
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paymentID, err := store.StorePayment(ctx, Payment{
+		OrderID:         orderID,
+		Operation:       PaymentCapture,
+		Outcome:         PaymentApproved,
+		Amount:          money.MustParse("22", ""),
+		AuthorizationID: "auth_1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capture, err := store.RetrieveCapturePayment(ctx, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capture.ID != paymentID {
+		t.Fatalf("wanted %d, got %d", paymentID, capture.ID)
+	}
+
+	refund, err := store.StoreRefund(ctx, Refund{
+		OrderID:   orderID,
+		PaymentID: paymentID,
+		Actor:     "test",
+		Items: []RefundItem{
+			{ProductID: productID, Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Amount != money.MustParse("11", "") {
+		t.Fatalf("wanted %d, got %s", 11, refund.Amount)
+	}
+
+	if _, err := store.StoreRefund(ctx, Refund{
+		OrderID:   orderID,
+		PaymentID: paymentID,
+		Actor:     "test",
+		Items: []RefundItem{
+			{ProductID: productID, Quantity: 2},
+		},
+	}); !errors.Is(err, ErrRefundExceeded) {
+		t.Fatalf("wanted %v, got %v", ErrRefundExceeded, err)
+	}
+
+	// A failed refund gives its quantities and amount back.
+	if err := store.FailRefund(ctx, refund.ID); err != nil {
+		t.Fatal(err)
+	}
+	refund, err = store.StoreRefund(ctx, Refund{
+		OrderID:   orderID,
+		PaymentID: paymentID,
+		Actor:     "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Amount != money.MustParse("22", "") {
+		t.Fatalf("wanted %d, got %s", 22, refund.Amount)
+	}
+	if err := store.CompleteRefund(ctx, refund.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := store.RetrieveOrder(ctx, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.RefundedAmount != money.MustParse("22", "") {
+		t.Fatalf("wanted %d, got %s", 22, order.RefundedAmount)
+	}
+
+	refunds, err := store.RetrieveRefunds(ctx, orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(refunds))
+	}
+	if refunds[0].Status != RefundFailed || refunds[1].Status != RefundSucceeded {
+		t.Fatalf("wanted %s and %s, got %s and %s", RefundFailed, RefundSucceeded, refunds[0].Status, refunds[1].Status)
+	}
+	if len(refunds[1].Items) != 1 || refunds[1].Items[0].Quantity != 2 {
+		t.Fatalf("wanted %d, got %v", 2, refunds[1].Items)
+	}
+}
+
+func TestCartStore(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+
+	userID := 1
+	productID := 1
+
+	if _, err := store.RetrieveCart(ctx, userID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+
+	if err := store.StoreCartItems(ctx, userID, money.DefaultCurrency, CartItem{ProductID: productID, SKUID: productID, Quantity: 1, Price: money.MustParse("11", "")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreCartItems(ctx, userID, money.DefaultCurrency, CartItem{ProductID: productID, SKUID: productID, Quantity: 3, Price: money.MustParse("11", "")}); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := store.RetrieveCart(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cart.Items) != 1 || cart.Items[0].Quantity != 3 {
+		t.Fatalf("wanted %d, got %v", 3, cart.Items)
+	}
+	if cart.TotalPrice != money.MustParse("33", "") {
+		t.Fatalf("wanted %d, got %s", 33, cart.TotalPrice)
+	}
+
+	// Changing the currency of the cart removes the items priced in the previous one.
+	if err := store.StoreCartItems(ctx, userID, "EUR", CartItem{ProductID: 2, SKUID: 2, Quantity: 1, Price: money.MustParse("9.50", "EUR")}); err != nil {
+		t.Fatal(err)
+	}
+	cart, err = store.RetrieveCart(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.Currency != "EUR" || len(cart.Items) != 1 || cart.TotalPrice != money.MustParse("9.50", "EUR") {
+		t.Fatalf("wanted a total of %s, got %s %s", "9.50 EUR", cart.TotalPrice, cart.Currency)
+	}
+	if err := store.StoreCartItems(ctx, userID, money.DefaultCurrency, CartItem{ProductID: productID, SKUID: productID, Quantity: 3, Price: money.MustParse("11", "")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ordering items removes them unless they changed in the meantime.
+	if err := store.StoreCartItems(ctx, userID, money.DefaultCurrency, CartItem{ProductID: 2, SKUID: 2, Quantity: 1, Price: money.MustParse("5", "")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteOrderedCartItems(ctx, userID, money.DefaultCurrency,
+		CartItem{ProductID: productID, SKUID: productID, Quantity: 2, Price: money.MustParse("11", "")},
+		CartItem{ProductID: 2, SKUID: 2, Quantity: 1, Price: money.MustParse("5", "")},
+	); err != nil {
+		t.Fatal(err)
+	}
+	cart, err = store.RetrieveCart(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cart.Items) != 1 || cart.Items[0].SKUID != productID {
+		t.Fatalf("wanted sku %d, got %v", productID, cart.Items)
+	}
+
+	// The cart was just updated so it is not idle.
+	expired, err := store.DeleteIdleCarts(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired != 0 {
+		t.Fatalf("wanted %d, got %d", 0, expired)
+	}
+
+	if err := store.DeleteCartItem(ctx, userID, productID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteCartItem(ctx, userID, productID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+
+	if _, err := db.DB().Exec(ctx, "UPDATE cart SET updated_at = CURRENT_TIMESTAMP - INTERVAL '2 hours' WHERE user_id = $1", userID); err != nil {
+		t.Fatal(err)
+	}
+	expired, err = store.DeleteIdleCarts(ctx, time.Hour, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired != 1 {
+		t.Fatalf("wanted %d, got %d", 1, expired)
+	}
+	if _, err := store.RetrieveCart(ctx, userID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+}
+
+func TestOrderCurrency(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+
+	rate, err := money.ParseRate("USD", "EUR", "0.92", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreRate(ctx, rate); err != nil {
+		t.Fatal(err)
+	}
+	rates, err := store.LoadRates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rate, err = rates.Rate("USD", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.String() != "0.92" || rate.AsOf.IsZero() {
+		t.Fatalf("wanted %s with a timestamp, got %s as of %s", "0.92", rate, rate.AsOf)
+	}
+
+	price, err := rate.Convert(money.MustParse("10", "USD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.StoreOrder(ctx, Order{
+		UserID:   1,
+		Currency: "EUR",
+		Status:   Pending,
+		Items: []OrderItem{
+			{ProductID: 1, SKUID: 1, Quantity: 2, Price: price, Rate: &rate},
+			{ProductID: 2, SKUID: 2, Quantity: 1, Price: money.MustParse("5", "EUR")},
+		},
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := store.RetrieveOrder(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Currency != "EUR" || order.TotalPrice != money.MustParse("23.40", "EUR") {
+		t.Fatalf("wanted %s, got %s %s", "23.40 EUR", order.TotalPrice, order.Currency)
+	}
+	item := order.Items[0]
+	if item.Rate == nil || item.Rate.From != "USD" || item.Rate.To != "EUR" || item.Rate.String() != "0.92" {
+		t.Fatalf("wanted the rate of the conversion, got %+v", item.Rate)
+	}
+	if order.Items[1].Rate != nil {
+		t.Fatalf("wanted no rate, got %+v", order.Items[1].Rate)
+	}
+
+	if _, err := store.StoreOrder(ctx, Order{
+		UserID:   1,
+		Currency: "EUR",
+		Status:   Pending,
+		Items:    []OrderItem{{ProductID: 1, SKUID: 1, Quantity: 1, Price: money.MustParse("10", "USD")}},
+	}, "test"); !errors.Is(err, money.ErrInvalidCurrency) {
+		t.Fatalf("wanted %v, got %v", money.ErrInvalidCurrency, err)
+	}
 }
@@ -3,9 +3,14 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/PseudoMera/virtual-store/product/service"
+	"github.com/PseudoMera/virtual-store/product/store"
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
 )
 
 type ProductAPI struct {
@@ -20,9 +25,13 @@ func NewProductAPI(service *service.ProductService) *ProductAPI {
 }
 
 type CreateProductRequest struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
-	Stock int     `json:"stock"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Currency    string       `json:"currency"`
+	Price       money.Money  `json:"price"`
+	Prices      money.Prices `json:"prices"`
+	Stock       int          `json:"stock"`
+	Options     []string     `json:"options"`
 }
 
 type CreateProductResponse struct {
@@ -36,9 +45,20 @@ func (p *ProductAPI) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := p.service.CreateProduct(r.Context(), req.Name, req.Price, req.Stock)
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	price, err := inCurrency("price", req.Price, req.Currency)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
+		return
+	}
+
+	id, err := p.service.CreateProduct(r.Context(), req.Name, req.Description, price, req.Prices, req.Stock, req.Options)
+	if err != nil {
+		shared.WriteError(w, err)
 		return
 	}
 
@@ -60,7 +80,7 @@ func (p *ProductAPI) GetProduct(w http.ResponseWriter, r *http.Request) {
 
 	product, err := p.service.GetProduct(r.Context(), req.ID)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
 		return
 	}
 
@@ -68,7 +88,15 @@ func (p *ProductAPI) GetProduct(w http.ResponseWriter, r *http.Request) {
 }
 
 type GetProductsRequest struct {
-	Name string `json:"name"`
+	CategoryID    int         `json:"category_id"`
+	Name          string      `json:"name"`
+	Currency      string      `json:"currency"`
+	MinPrice      money.Money `json:"min_price"`
+	MaxPrice      money.Money `json:"max_price"`
+	InStock       bool        `json:"in_stock"`
+	CreatedAfter  time.Time   `json:"created_after"`
+	CreatedBefore time.Time   `json:"created_before"`
+	page.Request
 }
 
 func (p *ProductAPI) GetProducts(w http.ResponseWriter, r *http.Request) {
@@ -78,20 +106,96 @@ func (p *ProductAPI) GetProducts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	products, err := p.service.GetProducts(r.Context(), req.Name)
+	minPrice, err := inCurrency("min_price", req.MinPrice, req.Currency)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
+		return
+	}
+	maxPrice, err := inCurrency("max_price", req.MaxPrice, req.Currency)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	products, err := p.service.GetProducts(r.Context(), store.ProductFilter{
+		CategoryID:    req.CategoryID,
+		Name:          req.Name,
+		Currency:      req.Currency,
+		MinPrice:      minPrice,
+		MaxPrice:      maxPrice,
+		InStock:       req.InStock,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+	}, req.Request)
+	if err != nil {
+		shared.WriteError(w, err)
 		return
 	}
 
 	shared.WriteResponse(http.StatusOK, products, w)
 }
 
+type SearchProductsRequest struct {
+	Query      string      `json:"query"`
+	CategoryID int         `json:"category_id"`
+	Currency   string      `json:"currency"`
+	MinPrice   money.Money `json:"min_price"`
+	MaxPrice   money.Money `json:"max_price"`
+	InStock    bool        `json:"in_stock"`
+	Facets     bool        `json:"facets"`
+	page.Request
+}
+
+type SearchProductsResponse struct {
+	*page.Page[*store.SearchResult]
+	Facets *store.SearchFacets `json:"facets,omitempty"`
+}
+
+func (p *ProductAPI) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	var req SearchProductsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	minPrice, err := inCurrency("min_price", req.MinPrice, req.Currency)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+	maxPrice, err := inCurrency("max_price", req.MaxPrice, req.Currency)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	results, facets, err := p.service.SearchProducts(r.Context(), store.SearchFilter{
+		Query:      req.Query,
+		CategoryID: req.CategoryID,
+		MinPrice:   minPrice,
+		MaxPrice:   maxPrice,
+		InStock:    req.InStock,
+		Facets:     req.Facets,
+	}, req.Request)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, SearchProductsResponse{
+		Page:   results,
+		Facets: facets,
+	}, w)
+}
+
 type UpdateProductRequest struct {
-	ID    int     `json:"id"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
-	Stock int     `json:"stock"`
+	ID          int          `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Currency    string       `json:"currency"`
+	Price       money.Money  `json:"price"`
+	Prices      money.Prices `json:"prices"`
+	Stock       int          `json:"stock"`
 }
 
 func (p *ProductAPI) UpdateProduct(w http.ResponseWriter, r *http.Request) {
@@ -101,9 +205,20 @@ func (p *ProductAPI) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := p.service.UpdateProduct(r.Context(), req.ID, req.Name, req.Price, req.Stock)
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	price, err := inCurrency("price", req.Price, req.Currency)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
+		return
+	}
+
+	err = p.service.UpdateProduct(r.Context(), req.ID, req.Name, req.Description, price, req.Prices, req.Stock)
+	if err != nil {
+		shared.WriteError(w, err)
 		return
 	}
 
@@ -122,11 +237,31 @@ func (p *ProductAPI) UpdateProductStock(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductStock); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
 	err := p.service.UpdateProductStock(r.Context(), req.ID, req.Stock)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// inCurrency returns a price of a request, decoded before its currency was known, as an amount
+// of the currency of the request, DefaultCurrency when it has none.
+func inCurrency(field string, price money.Money, currency string) (money.Money, error) {
+	if currency == "" {
+		return price, nil
+	}
+
+	price, err := price.InCurrency(currency)
+	if err != nil {
+		return money.Money{}, shared.InvalidFieldError(field, err.Error())
+	}
+
+	return price, nil
+}
@@ -7,25 +7,46 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/product/migrations"
 
 	"github.com/PseudoMera/virtual-store/product/service"
 	"github.com/PseudoMera/virtual-store/product/store"
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/money"
 	"github.com/go-chi/chi/v5"
 )
 
 const (
-	projectRootPath = "../../"
-
 	testName  = "testName"
-	testPrice = 12.05
 	teststock = 120
 )
 
+var testPrice = money.MustParse("12.05", "")
+
+var testAuthenticator = auth.NewAuthenticator([]byte("testSecret"))
+
+// setAuthorization sets a valid access token with the given permissions on the request.
+func setAuthorization(t *testing.T, req *http.Request, permissions ...string) {
+	t.Helper()
+
+	token, _, err := testAuthenticator.IssueToken(auth.Identity{
+		UserID:      1,
+		Permissions: permissions,
+	}, auth.AccessToken, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
 func TestCreateProduct(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -34,6 +55,7 @@ func TestCreateProduct(t *testing.T) {
 	serv := service.NewProductService(s, slog.Default())
 	api := NewProductAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Post("/api/v1/product", api.CreateProduct)
 
 	ts := httptest.NewServer(router)
@@ -55,6 +77,7 @@ func TestCreateProduct(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, auth.PermissionProductWrite)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -69,9 +92,41 @@ func TestCreateProduct(t *testing.T) {
 	}
 }
 
+func TestCreateProductWithLongDescription(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewProductAPI(service.NewProductService(store.NewStore(db.DB()), slog.Default()))
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Post("/api/v1/product", api.CreateProduct)
+
+	createProductBytes, err := json.Marshal(CreateProductRequest{
+		Name:        testName,
+		Description: strings.Repeat("a", 2001),
+		Price:       testPrice,
+		Stock:       teststock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/product", bytes.NewBuffer(createProductBytes))
+	setAuthorization(t, req, auth.PermissionProductWrite)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("wanted %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
 func TestGetProduct(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -124,7 +179,7 @@ func TestGetProduct(t *testing.T) {
 
 func TestGetProducts(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -177,7 +232,7 @@ func TestGetProducts(t *testing.T) {
 
 func TestUpdateProduct(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -195,6 +250,7 @@ func TestUpdateProduct(t *testing.T) {
 	serv := service.NewProductService(s, slog.Default())
 	api := NewProductAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Put("/api/v1/product", api.UpdateProduct)
 
 	ts := httptest.NewServer(router)
@@ -217,6 +273,7 @@ func TestUpdateProduct(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, auth.PermissionProductWrite)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -233,7 +290,7 @@ func TestUpdateProduct(t *testing.T) {
 
 func TestUpdateProductStock(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -251,6 +308,7 @@ func TestUpdateProductStock(t *testing.T) {
 	serv := service.NewProductService(s, slog.Default())
 	api := NewProductAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Put("/api/v1/product", api.UpdateProductStock)
 
 	ts := httptest.NewServer(router)
@@ -271,6 +329,7 @@ func TestUpdateProductStock(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, auth.PermissionProductStock)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -284,3 +343,50 @@ func TestUpdateProductStock(t *testing.T) {
 		t.Fatalf("wanted %d, got %d", http.StatusNoContent, status)
 	}
 }
+
+func TestCreateProductWithoutPermission(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := store.NewStore(db.DB())
+	serv := service.NewProductService(s, slog.Default())
+	api := NewProductAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Post("/api/v1/product", api.CreateProduct)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	createProductBytes, err := json.Marshal(CreateProductRequest{
+		Name:  testName,
+		Price: testPrice,
+		Stock: teststock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", ts.URL+"/api/v1/product", bytes.NewBuffer(createProductBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, auth.PermissionProductStock)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusForbidden {
+		t.Fatalf("wanted %d, got %d", http.StatusForbidden, status)
+	}
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+)
+
+type CreateCategoryRequest struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
+}
+
+type CreateCategoryResponse struct {
+	ID int `json:"id"`
+}
+
+func (p *ProductAPI) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req CreateCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	id, err := p.service.CreateCategory(r.Context(), req.Name, req.ParentID)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusCreated, CreateCategoryResponse{
+		ID: id,
+	}, w)
+}
+
+type GetCategoryRequest struct {
+	ID int `json:"id"`
+}
+
+func (p *ProductAPI) GetCategory(w http.ResponseWriter, r *http.Request) {
+	var req GetCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	category, err := p.service.GetCategory(r.Context(), req.ID)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, category, w)
+}
+
+func (p *ProductAPI) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := p.service.GetCategories(r.Context())
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, categories, w)
+}
+
+type UpdateCategoryRequest struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
+}
+
+func (p *ProductAPI) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	var req UpdateCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := p.service.UpdateCategory(r.Context(), req.ID, req.Name, req.ParentID); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type DeleteCategoryRequest struct {
+	ID int `json:"id"`
+}
+
+func (p *ProductAPI) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	var req DeleteCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := p.service.DeleteCategory(r.Context(), req.ID); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type SetProductCategoriesRequest struct {
+	ProductID   int   `json:"product_id"`
+	CategoryIDs []int `json:"category_ids"`
+}
+
+func (p *ProductAPI) SetProductCategories(w http.ResponseWriter, r *http.Request) {
+	var req SetProductCategoriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := p.service.SetProductCategories(r.Context(), req.ProductID, req.CategoryIDs); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
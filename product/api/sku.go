@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+type CreateSKURequest struct {
+	ProductID int               `json:"product_id"`
+	Code      string            `json:"code"`
+	Barcode   string            `json:"barcode"`
+	Options   map[string]string `json:"options"`
+	Currency  string            `json:"currency"`
+	Price     money.Money       `json:"price"`
+	Prices    money.Prices      `json:"prices"`
+	Stock     int               `json:"stock"`
+}
+
+type CreateSKUResponse struct {
+	ID int `json:"id"`
+}
+
+func (p *ProductAPI) CreateSKU(w http.ResponseWriter, r *http.Request) {
+	var req CreateSKURequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	price, err := inCurrency("price", req.Price, req.Currency)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	id, err := p.service.CreateSKU(r.Context(), store.SKU{
+		ProductID: req.ProductID,
+		Code:      req.Code,
+		Barcode:   req.Barcode,
+		Options:   req.Options,
+		Price:     price,
+		Prices:    req.Prices,
+		Stock:     req.Stock,
+	})
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusCreated, CreateSKUResponse{
+		ID: id,
+	}, w)
+}
+
+type GetSKURequest struct {
+	ID int `json:"id"`
+}
+
+func (p *ProductAPI) GetSKU(w http.ResponseWriter, r *http.Request) {
+	var req GetSKURequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sku, err := p.service.GetSKU(r.Context(), req.ID)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, sku, w)
+}
+
+type GetSKUsRequest struct {
+	ProductID int `json:"product_id"`
+}
+
+func (p *ProductAPI) GetSKUs(w http.ResponseWriter, r *http.Request) {
+	var req GetSKUsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	skus, err := p.service.GetSKUs(r.Context(), req.ProductID)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, skus, w)
+}
+
+type UpdateSKURequest struct {
+	ID       int               `json:"id"`
+	Code     string            `json:"code"`
+	Barcode  string            `json:"barcode"`
+	Options  map[string]string `json:"options"`
+	Currency string            `json:"currency"`
+	Price    money.Money       `json:"price"`
+	Prices   money.Prices      `json:"prices"`
+	Stock    int               `json:"stock"`
+}
+
+func (p *ProductAPI) UpdateSKU(w http.ResponseWriter, r *http.Request) {
+	var req UpdateSKURequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductWrite); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	price, err := inCurrency("price", req.Price, req.Currency)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	err = p.service.UpdateSKU(r.Context(), store.SKU{
+		ID:      req.ID,
+		Code:    req.Code,
+		Barcode: req.Barcode,
+		Options: req.Options,
+		Price:   price,
+		Prices:  req.Prices,
+		Stock:   req.Stock,
+	})
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type UpdateSKUStockRequest struct {
+	ID    int `json:"id"`
+	Stock int `json:"stock"`
+}
+
+func (p *ProductAPI) UpdateSKUStock(w http.ResponseWriter, r *http.Request) {
+	var req UpdateSKUStockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := auth.CheckPermission(r.Context(), auth.PermissionProductStock); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := p.service.UpdateSKUStock(r.Context(), req.ID, req.Stock); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,25 +2,41 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 )
 
 const (
-	connectionString = "CONNECTION_STRING"
-	httpServerPort   = "HTTP_SERVER_PORT"
-	grpcServerPort   = "GRPC_SERVER_PORT"
+	connectionString       = "CONNECTION_STRING"
+	eventsConnectionString = "EVENTS_CONNECTION_STRING"
+	httpServerPort         = "HTTP_SERVER_PORT"
+	grpcServerPort         = "GRPC_SERVER_PORT"
+	jwtSecret              = "JWT_SECRET"
+	tracesExporter         = "TRACES_EXPORTER"
+	consumerMaxAttempts    = "CONSUMER_MAX_ATTEMPTS"
+	consumerBackoff        = "CONSUMER_BACKOFF"
 )
 
 var (
 	errEmptyConnectionString = errors.New("env variable 'CONNECTION_STRING' cannot be empty")
 	errEmptyHTTPServerPort   = errors.New("env variable 'HTTP_SERVER_PORT' cannot be empty")
 	errEmptyGRPCServerPort   = errors.New("env variable 'GRPC_SERVER_PORT' cannot be empty")
+	errEmptyJWTSecret        = errors.New("env variable 'JWT_SECRET' cannot be empty")
 )
 
 type config struct {
 	connectionString string
-	httpServerPort   string
-	grpcServerPort   string
+	// eventsConnectionString is the database the domain events are published on, it defaults to connectionString.
+	eventsConnectionString string
+	httpServerPort         string
+	grpcServerPort         string
+	jwtSecret              string
+	tracesExporter         string
+	// consumerMaxAttempts and consumerBackoff configure the retries of the event handlers, see consumer.RetryPolicy.
+	consumerMaxAttempts int
+	consumerBackoff     time.Duration
 }
 
 func getConfig() config {
@@ -29,6 +45,11 @@ func getConfig() config {
 		panic(errEmptyConnectionString)
 	}
 
+	eventsCstr := os.Getenv(eventsConnectionString)
+	if eventsCstr == "" {
+		eventsCstr = cstr
+	}
+
 	httpPort := os.Getenv(httpServerPort)
 	if httpPort == "" {
 		panic(errEmptyHTTPServerPort)
@@ -39,9 +60,49 @@ func getConfig() config {
 		panic(errEmptyGRPCServerPort)
 	}
 
+	secret := os.Getenv(jwtSecret)
+	if secret == "" {
+		panic(errEmptyJWTSecret)
+	}
+
 	return config{
-		connectionString: cstr,
-		httpServerPort:   httpPort,
-		grpcServerPort:   grpcPort,
+		connectionString:       cstr,
+		eventsConnectionString: eventsCstr,
+		httpServerPort:         httpPort,
+		grpcServerPort:         grpcPort,
+		jwtSecret:              secret,
+		tracesExporter:         os.Getenv(tracesExporter),
+		consumerMaxAttempts:    getInt(consumerMaxAttempts),
+		consumerBackoff:        getDuration(consumerBackoff),
+	}
+}
+
+// getDuration parses an optional duration env variable, returning 0 when it is not set.
+func getDuration(key string) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		panic(fmt.Errorf("env variable '%s' is not a valid duration: %w", key, err))
+	}
+
+	return d
+}
+
+// getInt parses an optional integer env variable, returning 0 when it is not set.
+func getInt(key string) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Errorf("env variable '%s' is not a valid integer: %w", key, err))
 	}
+
+	return i
 }
@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	context "context"
+
+	"github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var errEmptyProductID = shared.InvalidFieldError("productID", "product id field cannot be empty")
+
+func (ps *ProductServer) CreateCategory(ctx context.Context, req *CreateCategoryRequest) (*CreateCategoryResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.Name == "" {
+		return nil, shared.GRPCError(errEmptyName)
+	}
+
+	id, err := ps.db.StoreCategory(ctx, store.Category{
+		ParentID: asID(req.ParentID),
+		Name:     req.Name,
+	})
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &CreateCategoryResponse{
+		Id: int64(id),
+	}, nil
+}
+
+func (ps *ProductServer) GetCategory(ctx context.Context, req *GetCategoryRequest) (*GetCategoryResponse, error) {
+	if req.Id == 0 {
+		return nil, shared.GRPCError(errEmptyID)
+	}
+
+	category, err := ps.db.RetrieveCategory(ctx, int(req.Id))
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &GetCategoryResponse{
+		Category: newCategory(category),
+	}, nil
+}
+
+func (ps *ProductServer) GetCategories(ctx context.Context, req *GetCategoriesRequest) (*GetCategoriesResponse, error) {
+	categories, err := ps.db.RetrieveCategories(ctx)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	parsedCategories := make([]*Category, len(categories))
+	for i, category := range categories {
+		parsedCategories[i] = newCategory(category)
+	}
+
+	return &GetCategoriesResponse{
+		Categories: parsedCategories,
+	}, nil
+}
+
+func (ps *ProductServer) UpdateCategory(ctx context.Context, req *UpdateCategoryRequest) (*SuccessResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.Id == 0 {
+		return nil, shared.GRPCError(errEmptyID)
+	}
+	if req.Name == "" {
+		return nil, shared.GRPCError(errEmptyName)
+	}
+
+	err := ps.db.UpdateCategory(ctx, store.Category{
+		ID:       int(req.Id),
+		ParentID: asID(req.ParentID),
+		Name:     req.Name,
+	})
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+func (ps *ProductServer) DeleteCategory(ctx context.Context, req *DeleteCategoryRequest) (*SuccessResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.Id == 0 {
+		return nil, shared.GRPCError(errEmptyID)
+	}
+
+	if err := ps.db.DeleteCategory(ctx, int(req.Id)); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+func (ps *ProductServer) SetProductCategories(ctx context.Context, req *SetProductCategoriesRequest) (*SuccessResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.ProductID == 0 {
+		return nil, shared.GRPCError(errEmptyProductID)
+	}
+
+	categoryIDs := make([]int, len(req.CategoryIDs))
+	for i, id := range req.CategoryIDs {
+		categoryIDs[i] = int(id)
+	}
+	if err := ps.db.SetProductCategories(ctx, int(req.ProductID), categoryIDs); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+// newCategory converts a category with its path.
+func newCategory(category *store.Category) *Category {
+	parsed := &Category{
+		Id:   int64(category.ID),
+		Name: category.Name,
+		Path: newBreadcrumb(category.Path),
+	}
+	if category.ParentID != nil {
+		parsed.ParentID = wrapperspb.Int64(int64(*category.ParentID))
+	}
+
+	return parsed
+}
+
+// newBreadcrumb converts a breadcrumb.
+func newBreadcrumb(breadcrumb store.Breadcrumb) *Breadcrumb {
+	refs := make([]*CategoryRef, len(breadcrumb))
+	for i, ref := range breadcrumb {
+		refs[i] = &CategoryRef{
+			Id:   int64(ref.ID),
+			Name: ref.Name,
+		}
+	}
+
+	return &Breadcrumb{
+		Categories: refs,
+	}
+}
+
+// asID converts an optional id, nil stays nil.
+func asID(id *wrapperspb.Int64Value) *int {
+	if id == nil {
+		return nil
+	}
+
+	value := int(id.Value)
+	return &value
+}
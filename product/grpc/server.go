@@ -2,16 +2,32 @@ package grpc
 
 import (
 	context "context"
-	"errors"
+	"strings"
+	"time"
 
 	"github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/money/moneypb"
+	"github.com/PseudoMera/virtual-store/shared/page"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 var (
-	errEmptyName  = errors.New("name field cannot be empty")
-	errEmptyPrice = errors.New("price field cannot be empty")
-	errEmptyStock = errors.New("stock field cannot be empty")
-	errEmptyID    = errors.New("id field cannot be empty")
+	errEmptyName  = shared.InvalidFieldError("name", "name field cannot be empty")
+	errEmptyPrice = shared.InvalidFieldError("price", "price field cannot be empty")
+	errEmptyStock = shared.InvalidFieldError("stock", "stock field cannot be empty")
+	errEmptyID    = shared.InvalidFieldError("id", "id field cannot be empty")
+
+	errEmptyOrderID    = shared.InvalidFieldError("orderID", "order id field cannot be empty")
+	errInvalidQuantity = shared.InvalidFieldError("quantity", "quantity must be greater than zero")
+
+	errInvalidPriceRange = shared.InvalidFieldError("minPrice", "minPrice cannot be greater than maxPrice")
+	errMixedPriceRange   = shared.InvalidFieldError("maxPrice", "minPrice and maxPrice must be in the same currency")
+	errInvalidPrices     = shared.InvalidFieldError("prices", "prices must be positive and in currencies other than the currency of the price")
+	errEmptyQuery        = shared.InvalidFieldError("query", "query field cannot be empty")
 )
 
 type ProductServer struct {
@@ -27,23 +43,43 @@ func NewProductServer(db *store.Store) *ProductServer {
 }
 
 func (ps *ProductServer) CreateProduct(ctx context.Context, req *CreateProductRequest) (*CreateProductResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
 	if req.Name == "" {
-		return nil, errEmptyName
+		return nil, shared.GRPCError(errEmptyName)
 	}
-	if req.Price == 0.0 {
-		return nil, errEmptyPrice
+	if err := store.ValidateDescription(req.Description); err != nil {
+		return nil, shared.GRPCError(err)
 	}
-	if req.Stock == 0 {
-		return nil, errEmptyStock
+	price, err := asMoney("price", req.Price)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if !price.IsPositive() {
+		return nil, shared.GRPCError(errEmptyPrice)
+	}
+	prices, err := asPrices(price, req.Prices)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if len(req.Options) == 0 && req.Stock == 0 {
+		return nil, shared.GRPCError(errEmptyStock)
+	}
+	if len(req.Options) > 0 && req.Stock != 0 {
+		return nil, shared.GRPCError(errStockWithOptions)
 	}
 
 	id, err := ps.db.StoreProduct(ctx, store.Product{
-		Name:  req.Name,
-		Price: float64(req.Price),
-		Stock: int(req.Stock),
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       price,
+		Prices:      prices,
+		Stock:       int(req.Stock),
+		Options:     req.Options,
 	})
 	if err != nil {
-		return nil, err
+		return nil, shared.GRPCError(err)
 	}
 
 	return &CreateProductResponse{
@@ -53,69 +89,164 @@ func (ps *ProductServer) CreateProduct(ctx context.Context, req *CreateProductRe
 
 func (ps *ProductServer) GetProduct(ctx context.Context, req *GetProductRequest) (*GetProductResponse, error) {
 	if req.Id == 0 {
-		return nil, errEmptyID
+		return nil, shared.GRPCError(errEmptyID)
 	}
 
 	product, err := ps.db.RetrieveProduct(ctx, int(req.Id))
 	if err != nil {
-		return nil, err
+		return nil, shared.GRPCError(err)
 	}
 
 	return &GetProductResponse{
-		Product: &Product{
-			Id:    int64(product.ID),
-			Name:  product.Name,
-			Stock: int32(product.Stock),
-			Price: float32(product.Price),
-		},
+		Product: newProduct(product),
 	}, nil
 }
 
 func (ps *ProductServer) GetProducts(ctx context.Context, req *GetProductsRequest) (*GetProductsResponse, error) {
-	if req.Name == "" {
-		return nil, errEmptyName
+	minPrice, err := asMoney("minPrice", req.MinPrice)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	maxPrice, err := asMoney("maxPrice", req.MaxPrice)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	filter := store.ProductFilter{
+		CategoryID:    int(req.CategoryID),
+		Name:          req.Name,
+		Currency:      req.Currency,
+		MinPrice:      minPrice,
+		MaxPrice:      maxPrice,
+		InStock:       req.InStock,
+		CreatedAfter:  asTime(req.CreatedAfter),
+		CreatedBefore: asTime(req.CreatedBefore),
+	}
+	if err := checkPriceRange(filter.MinPrice, filter.MaxPrice); err != nil {
+		return nil, shared.GRPCError(err)
 	}
 
-	products, err := ps.db.RetrieveProducts(ctx, req.Name)
+	products, err := ps.db.RetrieveProducts(ctx, filter, newPageRequest(req.Page))
 	if err != nil {
-		return nil, err
+		return nil, shared.GRPCError(err)
 	}
 
-	parsedProducts := make([]*Product, len(products))
-	for i := range products {
-		parsedProducts = append(parsedProducts, &Product{
-			Id:    int64(products[i].ID),
-			Name:  products[i].Name,
-			Price: float32(products[i].Price),
-			Stock: int32(products[i].Stock),
-		})
+	parsedProducts := make([]*Product, len(products.Items))
+	for i, product := range products.Items {
+		parsedProducts[i] = newProduct(product)
 	}
 
-	return &GetProductsResponse{
-		Products: parsedProducts,
-	}, nil
+	resp := &GetProductsResponse{
+		Products:   parsedProducts,
+		NextCursor: products.NextCursor,
+	}
+	if products.Total != nil {
+		resp.Total = wrapperspb.Int64(int64(*products.Total))
+	}
+
+	return resp, nil
+}
+
+func (ps *ProductServer) SearchProducts(ctx context.Context, req *SearchProductsRequest) (*SearchProductsResponse, error) {
+	minPrice, err := asMoney("minPrice", req.MinPrice)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	maxPrice, err := asMoney("maxPrice", req.MaxPrice)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	filter := store.SearchFilter{
+		Query:      strings.TrimSpace(req.Query),
+		CategoryID: int(req.CategoryID),
+		MinPrice:   minPrice,
+		MaxPrice:   maxPrice,
+		InStock:    req.InStock,
+		Facets:     req.Facets,
+	}
+	if filter.Query == "" {
+		return nil, shared.GRPCError(errEmptyQuery)
+	}
+	if err := checkPriceRange(filter.MinPrice, filter.MaxPrice); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	results, facets, err := ps.db.SearchProducts(ctx, filter, newPageRequest(req.Page))
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	parsedResults := make([]*SearchResult, len(results.Items))
+	for i, result := range results.Items {
+		parsedResults[i] = &SearchResult{
+			Product: newProduct(&result.Product),
+			Rank:    result.Rank,
+			Snippet: result.Snippet,
+		}
+	}
+
+	resp := &SearchProductsResponse{
+		Results:    parsedResults,
+		NextCursor: results.NextCursor,
+	}
+	if results.Total != nil {
+		resp.Total = wrapperspb.Int64(int64(*results.Total))
+	}
+	if facets != nil {
+		for _, bucket := range facets.Prices {
+			resp.PriceBuckets = append(resp.PriceBuckets, &PriceBucket{
+				Min:   bucket.Min.Proto(),
+				Max:   bucket.Max.Proto(),
+				Count: int64(bucket.Count),
+			})
+		}
+		for _, category := range facets.Categories {
+			resp.CategoryCounts = append(resp.CategoryCounts, &CategoryCount{
+				Id:    int64(category.ID),
+				Name:  category.Name,
+				Count: int64(category.Count),
+			})
+		}
+	}
+
+	return resp, nil
 }
 
 func (ps *ProductServer) UpdateProductRequest(ctx context.Context, req *Product) (*SuccessResponse, error) {
-	id, name, price, stock := req.Id, req.Name, req.Price, req.Stock
+	if err := auth.CheckPermission(ctx, auth.PermissionProductWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	id, name, stock := req.Id, req.Name, req.Stock
 	if name == "" {
-		return nil, errEmptyName
+		return nil, shared.GRPCError(errEmptyName)
+	}
+	if err := store.ValidateDescription(req.Description); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	price, err := asMoney("price", req.Price)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if !price.IsPositive() {
+		return nil, shared.GRPCError(errEmptyPrice)
 	}
-	if price == 0.0 {
-		return nil, errEmptyPrice
+	prices, err := asPrices(price, req.Prices)
+	if err != nil {
+		return nil, shared.GRPCError(err)
 	}
 	if stock == 0 {
-		return nil, errEmptyStock
+		return nil, shared.GRPCError(errEmptyStock)
 	}
 
-	err := ps.db.UpdateProduct(ctx, store.Product{
-		ID:    int(id),
-		Name:  name,
-		Price: float64(price),
-		Stock: int(stock),
+	err = ps.db.UpdateProduct(ctx, store.Product{
+		ID:          int(id),
+		Name:        name,
+		Description: req.Description,
+		Price:       price,
+		Prices:      prices,
+		Stock:       int(stock),
 	})
 	if err != nil {
-		return nil, err
+		return nil, shared.GRPCError(err)
 	}
 
 	return &SuccessResponse{
@@ -124,17 +255,151 @@ func (ps *ProductServer) UpdateProductRequest(ctx context.Context, req *Product)
 }
 
 func (ps *ProductServer) UpdateProductStock(ctx context.Context, req *UpdateProductStockRequest) (*SuccessResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductStock); err != nil {
+		return nil, shared.GRPCError(err)
+	}
 	id, stock := req.Id, req.Stock
 	if stock == 0 {
-		return nil, errEmptyStock
+		return nil, shared.GRPCError(errEmptyStock)
 	}
 
 	err := ps.db.UpdateProductStock(ctx, int(id), int(stock))
 	if err != nil {
-		return nil, err
+		return nil, shared.GRPCError(err)
 	}
 
 	return &SuccessResponse{
 		Msg: "Success!",
 	}, nil
 }
+
+// ReserveStock takes stock of a SKU for an order. Reserving the same SKU
+// for the same order twice only takes the stock once.
+func (ps *ProductServer) ReserveStock(ctx context.Context, req *ReserveStockRequest) (*SuccessResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductStock); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.OrderID == 0 {
+		return nil, shared.GRPCError(errEmptyOrderID)
+	}
+	if req.SkuID == 0 {
+		return nil, shared.GRPCError(errEmptySKUID)
+	}
+	if req.Quantity <= 0 {
+		return nil, shared.GRPCError(errInvalidQuantity)
+	}
+
+	if err := ps.db.ReserveSKUStock(ctx, int(req.OrderID), int(req.SkuID), int(req.Quantity)); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+// ReleaseStock gives back the stock reserved for an order, all of it if no quantity is given.
+// Releasing stock that was never reserved or was already released succeeds without changing anything.
+func (ps *ProductServer) ReleaseStock(ctx context.Context, req *ReleaseStockRequest) (*SuccessResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductStock); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.OrderID == 0 {
+		return nil, shared.GRPCError(errEmptyOrderID)
+	}
+	if req.SkuID == 0 {
+		return nil, shared.GRPCError(errEmptySKUID)
+	}
+
+	if req.Quantity < 0 {
+		return nil, shared.GRPCError(errInvalidQuantity)
+	}
+
+	if err := ps.db.ReleaseSKUStock(ctx, int(req.OrderID), int(req.SkuID), int(req.Quantity)); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+// newProduct converts a product with the breadcrumbs of its categories.
+func newProduct(product *store.Product) *Product {
+	categories := make([]*Breadcrumb, len(product.Categories))
+	for i, breadcrumb := range product.Categories {
+		categories[i] = newBreadcrumb(breadcrumb)
+	}
+
+	return &Product{
+		Id:          int64(product.ID),
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price.Proto(),
+		Prices:      product.Prices.Proto(),
+		Stock:       int32(product.Stock),
+		Categories:  categories,
+		Options:     product.Options,
+	}
+}
+
+// newPageRequest converts a PageRequest, nil selects the first page with the defaults.
+func newPageRequest(req *PageRequest) page.Request {
+	return page.Request{
+		Limit:     int(req.GetLimit()),
+		Cursor:    req.GetCursor(),
+		Sort:      req.GetSort(),
+		Desc:      req.GetDesc(),
+		WithTotal: req.GetWithTotal(),
+	}
+}
+
+// asMoney converts an optional amount of the given field, nil is zero.
+func asMoney(field string, m *moneypb.Money) (money.Money, error) {
+	amount, err := money.FromProto(m)
+	if err != nil {
+		return money.Money{}, shared.InvalidFieldError(field, err.Error())
+	}
+
+	return amount, nil
+}
+
+// asPrices converts the prices of a product or SKU in currencies other than the currency of its
+// price, they must be positive.
+func asPrices(price money.Money, messages []*moneypb.Money) (money.Prices, error) {
+	prices, err := money.PricesFromProto(messages)
+	if err != nil {
+		return nil, shared.InvalidFieldError("prices", err.Error())
+	}
+	for currency, amount := range prices {
+		if currency == price.CurrencyCode() || !amount.IsPositive() {
+			return nil, errInvalidPrices
+		}
+	}
+
+	return prices, nil
+}
+
+// checkPriceRange checks the bounds of a price range are in the same currency and in order.
+func checkPriceRange(min, max money.Money) error {
+	if !min.IsPositive() || !max.IsPositive() {
+		return nil
+	}
+	if !min.SameCurrency(max) {
+		return errMixedPriceRange
+	}
+	if min.Cmp(max) > 0 {
+		return errInvalidPriceRange
+	}
+
+	return nil
+}
+
+// asTime converts an optional timestamp, nil is the zero time.
+func asTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+
+	return ts.AsTime()
+}
@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	context "context"
+
+	"github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+)
+
+var (
+	errEmptySKUID       = shared.InvalidFieldError("skuID", "sku id field cannot be empty")
+	errNegativeStock    = shared.InvalidFieldError("stock", "stock cannot be negative")
+	errStockWithOptions = shared.InvalidFieldError("stock", "the stock of a product with options is set on its skus")
+)
+
+func (ps *ProductServer) CreateSKU(ctx context.Context, req *CreateSKURequest) (*CreateSKUResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.ProductID == 0 {
+		return nil, shared.GRPCError(errEmptyProductID)
+	}
+	price, err := asMoney("price", req.Price)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if !price.IsPositive() {
+		return nil, shared.GRPCError(errEmptyPrice)
+	}
+	prices, err := asPrices(price, req.Prices)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.Stock < 0 {
+		return nil, shared.GRPCError(errNegativeStock)
+	}
+
+	id, err := ps.db.StoreSKU(ctx, store.SKU{
+		ProductID: int(req.ProductID),
+		Code:      req.Code,
+		Barcode:   req.Barcode,
+		Options:   req.Options,
+		Price:     price,
+		Prices:    prices,
+		Stock:     int(req.Stock),
+	})
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &CreateSKUResponse{
+		Id: int64(id),
+	}, nil
+}
+
+// GetSKU returns the SKU with the given id or, if only a product id is given, the single SKU of
+// that product. A product with options has no single SKU and returns an InvalidArgument error.
+func (ps *ProductServer) GetSKU(ctx context.Context, req *GetSKURequest) (*GetSKUResponse, error) {
+	var sku *store.SKU
+	var err error
+	switch {
+	case req.Id != 0:
+		sku, err = ps.db.RetrieveSKU(ctx, int(req.Id))
+	case req.ProductID != 0:
+		sku, err = ps.db.RetrieveDefaultSKU(ctx, int(req.ProductID))
+	default:
+		return nil, shared.GRPCError(errEmptyID)
+	}
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &GetSKUResponse{
+		Sku: newSKU(sku),
+	}, nil
+}
+
+func (ps *ProductServer) GetSKUs(ctx context.Context, req *GetSKUsRequest) (*GetSKUsResponse, error) {
+	if req.ProductID == 0 {
+		return nil, shared.GRPCError(errEmptyProductID)
+	}
+
+	skus, err := ps.db.RetrieveSKUs(ctx, int(req.ProductID))
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	parsedSKUs := make([]*SKU, len(skus))
+	for i, sku := range skus {
+		parsedSKUs[i] = newSKU(sku)
+	}
+
+	return &GetSKUsResponse{
+		Skus: parsedSKUs,
+	}, nil
+}
+
+func (ps *ProductServer) UpdateSKU(ctx context.Context, req *SKU) (*SuccessResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductWrite); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.Id == 0 {
+		return nil, shared.GRPCError(errEmptyID)
+	}
+	price, err := asMoney("price", req.Price)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if !price.IsPositive() {
+		return nil, shared.GRPCError(errEmptyPrice)
+	}
+	prices, err := asPrices(price, req.Prices)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.Stock < 0 {
+		return nil, shared.GRPCError(errNegativeStock)
+	}
+
+	err = ps.db.UpdateSKU(ctx, store.SKU{
+		ID:      int(req.Id),
+		Code:    req.Code,
+		Barcode: req.Barcode,
+		Options: req.Options,
+		Price:   price,
+		Prices:  prices,
+		Stock:   int(req.Stock),
+	})
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+func (ps *ProductServer) UpdateSKUStock(ctx context.Context, req *UpdateSKUStockRequest) (*SuccessResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionProductStock); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+	if req.Id == 0 {
+		return nil, shared.GRPCError(errEmptyID)
+	}
+	if req.Stock < 0 {
+		return nil, shared.GRPCError(errNegativeStock)
+	}
+
+	if err := ps.db.UpdateSKUStock(ctx, int(req.Id), int(req.Stock)); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+// newSKU converts a SKU.
+func newSKU(sku *store.SKU) *SKU {
+	return &SKU{
+		Id:        int64(sku.ID),
+		ProductID: int64(sku.ProductID),
+		Code:      sku.Code,
+		Barcode:   sku.Barcode,
+		Options:   sku.Options,
+		Price:     sku.Price.Proto(),
+		Prices:    sku.Prices.Proto(),
+		Stock:     int32(sku.Stock),
+	}
+}
@@ -3,15 +3,26 @@ package main
 import (
 	"context"
 	"fmt"
-	"net"
-	"net/http"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/PseudoMera/virtual-store/product/api"
 	"github.com/PseudoMera/virtual-store/product/grpc"
+	"github.com/PseudoMera/virtual-store/product/migrations"
 	"github.com/PseudoMera/virtual-store/product/service"
 	"github.com/PseudoMera/virtual-store/product/store"
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/shared/consumer"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	eventmigrations "github.com/PseudoMera/virtual-store/shared/events/migrations"
+	"github.com/PseudoMera/virtual-store/shared/health"
+	"github.com/PseudoMera/virtual-store/shared/metrics"
+	"github.com/PseudoMera/virtual-store/shared/migrate"
+	"github.com/PseudoMera/virtual-store/shared/outbox"
+	"github.com/PseudoMera/virtual-store/shared/tracing"
+	"github.com/go-chi/chi/v5"
 	egrpc "google.golang.org/grpc"
 )
 
@@ -23,38 +34,146 @@ func main() {
 	config := getConfig()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	database, err := shared.NewPostgresDatabase(ctx, config.connectionString)
+	cancel()
 	if err != nil {
 		panic(err)
 	}
-	defer cancel()
 
 	logger := shared.NewLogger()
+	migrator, err := migrate.NewMigrator(database.DB(), migrations.FS, logger)
+	if err != nil {
+		panic(err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == migrate.Command {
+		err := migrator.Run(context.Background(), os.Args[2:], os.Stdout)
+		database.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		panic(err)
+	}
+
+	exporter, err := tracing.NewExporter(context.Background(), config.tracesExporter)
+	if err != nil {
+		panic(err)
+	}
+	tracerProvider := tracing.Setup("product", exporter)
+	if err := metrics.RegisterPool(database.DB()); err != nil {
+		panic(err)
+	}
 	router := api.NewRouter()
 	store := store.NewStore(database.DB())
 	productService := service.NewProductService(store, logger)
 	productAPI := api.NewProductAPI(productService)
+	authenticator := auth.NewAuthenticator([]byte(config.jwtSecret))
+	checker := health.NewChecker(grpc.ProductService_ServiceDesc.ServiceName)
+	checker.AddCheck("postgres", database.DB().Ping)
+	background, stopBackground := context.WithCancel(context.Background())
+	go checker.Watch(background, 10*time.Second)
+	eventBus := database
+	if config.eventsConnectionString != config.connectionString {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		eventBus, err = shared.NewPostgresDatabase(ctx, config.eventsConnectionString)
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+	}
+	eventsMigrator, err := migrate.NewTableMigrator(eventBus.DB(), eventmigrations.FS, eventmigrations.Table, logger)
+	if err != nil {
+		panic(err)
+	}
+	if err := eventsMigrator.Up(context.Background()); err != nil {
+		panic(err)
+	}
+	publisher := outbox.NewLogPublisher(eventBus.DB(), outbox.Channel)
+	go publisher.RunExpiry(background, time.Hour, logger)
+	relay := outbox.NewRelay(database.DB(), "product", publisher, logger)
+	go relay.Run(background, time.Second)
+	eventConsumer := consumer.NewConsumer(database.DB(), "product", consumer.RetryPolicy{
+		MaxAttempts:    config.consumerMaxAttempts,
+		InitialBackoff: config.consumerBackoff,
+	}, logger)
+	eventConsumer.Handle(events.OrderStatusChanged, productService.HandleOrderStatusChanged)
+	eventConsumer.Handle(events.RefundSucceeded, productService.HandleRefundSucceeded)
+	go eventConsumer.Run(background, eventBus.DB(), outbox.Channel, 5*time.Second)
+	go eventConsumer.RunInboxExpiry(background, time.Hour)
 
-	router.Post(fmt.Sprintf("%s/product", apiPath), productAPI.CreateProduct)
+	router.Get(health.LivenessPath, checker.Liveness)
+	router.Get(health.ReadinessPath, checker.Readiness)
+	router.Handle(metrics.Path, metrics.Handler())
 	router.Get(fmt.Sprintf("%s/product", apiPath), productAPI.GetProduct)
 	router.Get(fmt.Sprintf("%s/products", apiPath), productAPI.GetProducts)
-	router.Put(fmt.Sprintf("%s/product", apiPath), productAPI.UpdateProduct)
-	router.Put(fmt.Sprintf("%s/product/stock", apiPath), productAPI.UpdateProductStock)
+	router.Get(fmt.Sprintf("%s/products/search", apiPath), productAPI.SearchProducts)
+	router.Get(fmt.Sprintf("%s/product/sku", apiPath), productAPI.GetSKU)
+	router.Get(fmt.Sprintf("%s/product/skus", apiPath), productAPI.GetSKUs)
+	router.Get(fmt.Sprintf("%s/category", apiPath), productAPI.GetCategory)
+	router.Get(fmt.Sprintf("%s/categories", apiPath), productAPI.GetCategories)
+	router.Group(func(r chi.Router) {
+		r.Use(authenticator.Middleware)
+		r.Post(fmt.Sprintf("%s/product", apiPath), productAPI.CreateProduct)
+		r.Put(fmt.Sprintf("%s/product", apiPath), productAPI.UpdateProduct)
+		r.Put(fmt.Sprintf("%s/product/stock", apiPath), productAPI.UpdateProductStock)
+		r.Put(fmt.Sprintf("%s/product/categories", apiPath), productAPI.SetProductCategories)
+		r.Post(fmt.Sprintf("%s/product/sku", apiPath), productAPI.CreateSKU)
+		r.Put(fmt.Sprintf("%s/product/sku", apiPath), productAPI.UpdateSKU)
+		r.Put(fmt.Sprintf("%s/product/sku/stock", apiPath), productAPI.UpdateSKUStock)
+		r.Post(fmt.Sprintf("%s/category", apiPath), productAPI.CreateCategory)
+		r.Put(fmt.Sprintf("%s/category", apiPath), productAPI.UpdateCategory)
+		r.Delete(fmt.Sprintf("%s/category", apiPath), productAPI.DeleteCategory)
+		r.Get(fmt.Sprintf("%s/admin/dead-letters", apiPath), eventConsumer.GetDeadLetters)
+		r.Post(fmt.Sprintf("%s/admin/dead-letters/replay", apiPath), eventConsumer.ReplayDeadLetter)
+	})
 
-	lis, err := net.Listen("tcp", config.grpcServerPort)
-	if err != nil {
-		panic(err)
+	publicMethods := []string{
+		grpcMethod("GetProduct"),
+		grpcMethod("GetProducts"),
+		grpcMethod("SearchProducts"),
+		grpcMethod("GetSKU"),
+		grpcMethod("GetSKUs"),
+		grpcMethod("GetCategory"),
+		grpcMethod("GetCategories"),
+	}
+	publicMethods = append(publicMethods, health.Methods()...)
+	opts := []egrpc.ServerOption{
+		egrpc.ChainUnaryInterceptor(
+			tracing.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor(),
+			authenticator.UnaryServerInterceptor(publicMethods...),
+		),
+		egrpc.ChainStreamInterceptor(
+			tracing.StreamServerInterceptor(),
+			metrics.StreamServerInterceptor(),
+			authenticator.StreamServerInterceptor(publicMethods...),
+		),
 	}
-
-	var opts []egrpc.ServerOption
 	grpcServer := egrpc.NewServer(opts...)
 	serviceServer := grpc.NewProductServer(store)
 	grpc.RegisterProductServiceServer(grpcServer, serviceServer)
+	checker.Register(grpcServer)
 
-	if err := grpcServer.Serve(lis); err != nil {
-		panic(err)
+	runner := shared.NewServerRunner(fmt.Sprintf(":%s", config.httpServerPort), router, fmt.Sprintf(":%s", config.grpcServerPort), grpcServer, logger)
+	runner.BeforeShutdown(checker.Shutdown)
+	runner.OnShutdown(stopBackground)
+	runner.OnShutdown(database.Close)
+	if eventBus != database {
+		runner.OnShutdown(eventBus.Close)
 	}
+	runner.OnShutdown(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			logger.Error("error at tracer shutdown", slog.String("error", err.Error()))
+		}
+	})
+	os.Exit(runner.Run(context.Background()))
+}
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", config.httpServerPort), router); err != nil {
-		panic(err)
-	}
+// grpcMethod returns the full gRPC method name of a product service method.
+func grpcMethod(name string) string {
+	return fmt.Sprintf("/%s/%s", grpc.ProductService_ServiceDesc.ServiceName, name)
 }
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_reservation;
+DROP TABLE IF EXISTS product;
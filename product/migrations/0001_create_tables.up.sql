@@ -0,0 +1,19 @@
+CREATE TABLE product (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name VARCHAR NOT NULL UNIQUE,
+    price NUMERIC(12, 2) NOT NULL,
+    stock INT NOT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE stock_reservation (
+    order_id INT NOT NULL,
+    product_id INT NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES product (id) ON DELETE CASCADE,
+    PRIMARY KEY (order_id, product_id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    released INT NOT NULL DEFAULT 0 CHECK (released >= 0 AND released <= quantity),
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS update_product_modtime ON product;
+DROP TRIGGER IF EXISTS update_stock_reservation_modtime ON stock_reservation;
+
+DROP FUNCTION IF EXISTS update_modified_column();
@@ -0,0 +1,13 @@
+/*
+    Updates the updated_at column when a record is modified
+*/
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_product_modtime BEFORE UPDATE ON product FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_stock_reservation_modtime BEFORE UPDATE ON stock_reservation FOR EACH ROW EXECUTE FUNCTION update_modified_column();
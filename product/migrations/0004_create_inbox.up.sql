@@ -0,0 +1,21 @@
+CREATE TABLE inbox (
+    event_id UUID PRIMARY KEY,
+    event_type VARCHAR NOT NULL,
+    source VARCHAR NOT NULL,
+    processed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX inbox_processed_at_idx ON inbox (processed_at);
+
+CREATE TABLE dead_letter (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    event_id UUID NOT NULL,
+    event_type VARCHAR NOT NULL,
+    event JSONB NOT NULL,
+    error TEXT NOT NULL,
+    attempts INT NOT NULL,
+    replayed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX dead_letter_pending_idx ON dead_letter (id) WHERE replayed_at IS NULL;
@@ -0,0 +1,11 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE product ADD COLUMN description VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE product ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+CREATE INDEX product_search_vector_idx ON product USING GIN (search_vector);
+CREATE INDEX product_name_trgm_idx ON product USING GIN (name gin_trgm_ops);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS product_category;
+DROP TABLE IF EXISTS category;
@@ -0,0 +1,21 @@
+CREATE TABLE category (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    parent_id INT REFERENCES category (id),
+    name VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE NULLS NOT DISTINCT (parent_id, name),
+    CHECK (parent_id <> id)
+);
+
+CREATE INDEX category_parent_id_idx ON category (parent_id);
+
+CREATE TABLE product_category (
+    product_id INT NOT NULL REFERENCES product (id) ON DELETE CASCADE,
+    category_id INT NOT NULL REFERENCES category (id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, category_id)
+);
+
+CREATE INDEX product_category_category_id_idx ON product_category (category_id);
+
+CREATE TRIGGER update_category_modtime BEFORE UPDATE ON category FOR EACH ROW EXECUTE FUNCTION update_modified_column();
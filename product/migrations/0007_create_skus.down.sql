@@ -0,0 +1,8 @@
+ALTER TABLE stock_reservation DROP CONSTRAINT stock_reservation_pkey;
+DELETE FROM stock_reservation a USING stock_reservation b WHERE a.order_id = b.order_id AND a.product_id = b.product_id AND a.sku_id > b.sku_id;
+ALTER TABLE stock_reservation ADD PRIMARY KEY (order_id, product_id);
+ALTER TABLE stock_reservation DROP COLUMN sku_id;
+
+DROP TABLE IF EXISTS sku;
+
+ALTER TABLE product DROP COLUMN IF EXISTS options;
@@ -0,0 +1,29 @@
+ALTER TABLE product ADD COLUMN options VARCHAR[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE sku (
+    id INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES product (id) ON DELETE CASCADE,
+    code VARCHAR UNIQUE,
+    barcode VARCHAR UNIQUE,
+    options JSONB NOT NULL DEFAULT '{}',
+    price NUMERIC(12, 2) NOT NULL,
+    stock INT NOT NULL CHECK (stock >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (product_id, options)
+);
+
+CREATE TRIGGER update_sku_modtime BEFORE UPDATE ON sku FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+
+/*
+    Existing products get a single SKU holding their price and stock. It takes the id of its
+    product so the order service can point its existing lines at it.
+*/
+INSERT INTO sku (id, product_id, price, stock) SELECT id, id, price, GREATEST(stock, 0) FROM product;
+SELECT setval(pg_get_serial_sequence('sku', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM sku;
+
+ALTER TABLE stock_reservation ADD COLUMN sku_id INT REFERENCES sku (id) ON DELETE CASCADE;
+UPDATE stock_reservation SET sku_id = product_id;
+ALTER TABLE stock_reservation ALTER COLUMN sku_id SET NOT NULL;
+ALTER TABLE stock_reservation DROP CONSTRAINT stock_reservation_pkey;
+ALTER TABLE stock_reservation ADD PRIMARY KEY (order_id, sku_id);
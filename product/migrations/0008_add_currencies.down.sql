@@ -0,0 +1,3 @@
+ALTER TABLE sku DROP COLUMN IF EXISTS prices;
+ALTER TABLE product DROP COLUMN IF EXISTS prices;
+ALTER TABLE product DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,8 @@
+/*
+    Prices are in the currency of their product. Prices holds the optional prices of a SKU in
+    other currencies, such as {"EUR": "9.90"}, the prices of a product without options are those
+    of its single SKU.
+*/
+ALTER TABLE product ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE product ADD COLUMN prices JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE sku ADD COLUMN prices JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,9 @@
+/*
+    Refunds of the order service whose items were given back to the stock, so a redelivered
+    RefundSucceeded event does not restock them twice.
+*/
+CREATE TABLE refund_restock (
+    refund_id INT PRIMARY KEY,
+    order_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+ALTER TABLE product DROP CONSTRAINT IF EXISTS product_description_length;
@@ -0,0 +1,5 @@
+/*
+    Descriptions are carried by every product event, they are limited to 2000 characters so an
+    event stays small whatever is written in them.
+*/
+ALTER TABLE product ADD CONSTRAINT product_description_length CHECK (char_length(description) <= 2000);
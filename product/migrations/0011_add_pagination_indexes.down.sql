@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS product_created_at_id_idx;
+DROP INDEX IF EXISTS product_price_id_idx;
+DROP INDEX IF EXISTS product_name_id_idx;
@@ -0,0 +1,7 @@
+/*
+    Lists of products are paginated by keyset on their sort field and id, see the page package.
+    Each sort field has an index matching its ORDER BY, sorting by id uses the primary key.
+*/
+CREATE INDEX product_name_id_idx ON product (name, id);
+CREATE INDEX product_price_id_idx ON product (price, id);
+CREATE INDEX product_created_at_id_idx ON product (created_at, id);
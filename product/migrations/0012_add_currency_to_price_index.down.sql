@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS product_currency_price_id_idx;
+CREATE INDEX product_price_id_idx ON product (price, id);
@@ -0,0 +1,6 @@
+/*
+    Sorting products by price requires a currency filter, prices of different currencies cannot
+    be compared, so the index of that sort key starts with the currency.
+*/
+DROP INDEX IF EXISTS product_price_id_idx;
+CREATE INDEX product_currency_price_id_idx ON product (currency, price, id);
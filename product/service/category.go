@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared"
+)
+
+var errEmptyProductID = shared.InvalidFieldError("product_id", "product_id field cannot be empty")
+
+// CreateCategory stores a new category with the given name under the given parent, a root
+// category if parentID is nil.
+func (p *ProductService) CreateCategory(ctx context.Context, name string, parentID *int) (int, error) {
+	if name == "" {
+		p.logger.InfoContext(ctx, "error at CreateCategory", slog.String("error", errEmptyName.Error()))
+		return 0, errEmptyName
+	}
+
+	return p.db.StoreCategory(ctx, store.Category{
+		ParentID: parentID,
+		Name:     name,
+	})
+}
+
+// GetCategory returns the category associated with the given id and its breadcrumb.
+func (p *ProductService) GetCategory(ctx context.Context, id int) (*store.Category, error) {
+	if id == 0 {
+		p.logger.InfoContext(ctx, "error at GetCategory", slog.String("error", errEmptyID.Error()))
+		return nil, errEmptyID
+	}
+
+	return p.db.RetrieveCategory(ctx, id)
+}
+
+// GetCategories returns the whole category tree in depth-first order.
+func (p *ProductService) GetCategories(ctx context.Context) ([]*store.Category, error) {
+	return p.db.RetrieveCategories(ctx)
+}
+
+// UpdateCategory renames the category with the given id and moves it under the given parent,
+// to the root if parentID is nil.
+func (p *ProductService) UpdateCategory(ctx context.Context, id int, name string, parentID *int) error {
+	if id == 0 {
+		p.logger.InfoContext(ctx, "error at UpdateCategory", slog.String("error", errEmptyID.Error()))
+		return errEmptyID
+	}
+	if name == "" {
+		p.logger.InfoContext(ctx, "error at UpdateCategory", slog.String("error", errEmptyName.Error()))
+		return errEmptyName
+	}
+
+	return p.db.UpdateCategory(ctx, store.Category{
+		ID:       id,
+		ParentID: parentID,
+		Name:     name,
+	})
+}
+
+// DeleteCategory deletes the category with the given id, its subcategories and products move to its parent.
+func (p *ProductService) DeleteCategory(ctx context.Context, id int) error {
+	if id == 0 {
+		p.logger.InfoContext(ctx, "error at DeleteCategory", slog.String("error", errEmptyID.Error()))
+		return errEmptyID
+	}
+
+	return p.db.DeleteCategory(ctx, id)
+}
+
+// SetProductCategories replaces the categories of the product with the given id.
+func (p *ProductService) SetProductCategories(ctx context.Context, productID int, categoryIDs []int) error {
+	if productID == 0 {
+		p.logger.InfoContext(ctx, "error at SetProductCategories", slog.String("error", errEmptyProductID.Error()))
+		return errEmptyProductID
+	}
+
+	return p.db.SetProductCategories(ctx, productID, categoryIDs)
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared/events"
+)
+
+// orderCancelled is the status of a cancelled order in the order service.
+const orderCancelled = "cancelled"
+
+// HandleOrderStatusChanged gives back the stock still reserved for an order once it is cancelled.
+// The order service releases it while cancelling, this catches the reservations it could not release.
+func (p *ProductService) HandleOrderStatusChanged(ctx context.Context, event events.Event) error {
+	var payload events.OrderStatusChangedPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return err
+	}
+	if payload.To != orderCancelled {
+		return nil
+	}
+
+	return p.db.ReleaseOrderStock(ctx, payload.OrderID)
+}
+
+// HandleRefundSucceeded gives back the stock of the items of a refund that asked to restock them.
+func (p *ProductService) HandleRefundSucceeded(ctx context.Context, event events.Event) error {
+	var payload events.RefundSucceededPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return err
+	}
+	if !payload.Restock {
+		return nil
+	}
+
+	items := make([]store.StockItem, len(payload.Items))
+	for i, item := range payload.Items {
+		items[i] = store.StockItem{SKUID: item.SKUID, Quantity: item.Quantity}
+	}
+
+	return p.db.ReleaseRefundStock(ctx, payload.RefundID, payload.OrderID, items)
+}
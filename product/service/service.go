@@ -2,17 +2,25 @@ package service
 
 import (
 	"context"
-	"errors"
 	"log/slog"
+	"strings"
 
 	"github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
 )
 
 var (
-	errEmptyName  = errors.New("name field cannot be empty")
-	errEmptyPrice = errors.New("price field cannot be empty")
-	errEmptyStock = errors.New("stock field cannot be empty")
-	errEmptyID    = errors.New("id field cannot be empty")
+	errEmptyName  = shared.InvalidFieldError("name", "name field cannot be empty")
+	errEmptyPrice = shared.InvalidFieldError("price", "price field cannot be empty")
+	errEmptyStock = shared.InvalidFieldError("stock", "stock field cannot be empty")
+	errEmptyID    = shared.InvalidFieldError("id", "id field cannot be empty")
+
+	errInvalidPriceRange = shared.InvalidFieldError("min_price", "min_price cannot be greater than max_price")
+	errMixedPriceRange   = shared.InvalidFieldError("max_price", "min_price and max_price must be in the same currency")
+	errInvalidPrices     = shared.InvalidFieldError("prices", "prices must be positive and in currencies other than the currency of the price")
+	errEmptyQuery        = shared.InvalidFieldError("query", "query field cannot be empty")
 )
 
 type ProductService struct {
@@ -31,85 +39,160 @@ func NewProductService(db *store.Store, logger *slog.Logger) *ProductService {
 	}
 }
 
-// CreateProduct stores a new product with the given name, price and stock.
-func (p *ProductService) CreateProduct(ctx context.Context, name string, price float64, stock int) (int, error) {
+// CreateProduct stores a new product with the given name, description, price and stock. The
+// product is in the currency of its price, prices holds its optional prices in other currencies.
+// A product with options is sold through the SKUs created with CreateSKU, its stock is theirs.
+func (p *ProductService) CreateProduct(ctx context.Context, name, description string, price money.Money, prices money.Prices, stock int, options []string) (int, error) {
 	if name == "" {
-		p.logger.Info("error at CreateProduct", slog.String("error", errEmptyName.Error()))
+		p.logger.InfoContext(ctx, "error at CreateProduct", slog.String("error", errEmptyName.Error()))
 		return 0, errEmptyName
 	}
-	if price == 0.0 {
-		p.logger.Info("error at CreateProduct", slog.String("error", errEmptyPrice.Error()))
+	if err := store.ValidateDescription(description); err != nil {
+		p.logger.InfoContext(ctx, "error at CreateProduct", slog.String("error", err.Error()))
+		return 0, err
+	}
+	if !price.IsPositive() {
+		p.logger.InfoContext(ctx, "error at CreateProduct", slog.String("error", errEmptyPrice.Error()))
 		return 0, errEmptyPrice
 	}
-	if stock == 0 {
-		p.logger.Info("error at CreateProduct", slog.String("error", errEmptyStock.Error()))
+	if err := validatePrices(price, prices); err != nil {
+		p.logger.InfoContext(ctx, "error at CreateProduct", slog.String("error", err.Error()))
+		return 0, err
+	}
+	if len(options) == 0 && stock == 0 {
+		p.logger.InfoContext(ctx, "error at CreateProduct", slog.String("error", errEmptyStock.Error()))
 		return 0, errEmptyStock
 	}
+	if len(options) > 0 && stock != 0 {
+		p.logger.InfoContext(ctx, "error at CreateProduct", slog.String("error", errStockWithOptions.Error()))
+		return 0, errStockWithOptions
+	}
+	if err := validateOptions(options); err != nil {
+		p.logger.InfoContext(ctx, "error at CreateProduct", slog.String("error", err.Error()))
+		return 0, err
+	}
 
 	return p.db.StoreProduct(ctx, store.Product{
-		Name:  name,
-		Price: price,
-		Stock: stock,
+		Name:        name,
+		Description: description,
+		Price:       price,
+		Prices:      prices,
+		Stock:       stock,
+		Options:     options,
 	})
 }
 
 // GetProduct returns the product associated with the given id if it exists.
 func (p *ProductService) GetProduct(ctx context.Context, id int) (*store.Product, error) {
 	if id == 0 {
-		p.logger.Info("error at CreateProduct", slog.String("error", errEmptyID.Error()))
+		p.logger.InfoContext(ctx, "error at CreateProduct", slog.String("error", errEmptyID.Error()))
 		return nil, errEmptyID
 	}
 
 	return p.db.RetrieveProduct(ctx, id)
 }
 
-// GetProducts returns a slice of products associated with the given name.
-func (p *ProductService) GetProducts(ctx context.Context, name string) ([]*store.Product, error) {
-	if name == "" {
-		p.logger.Info("error at GetProducts", slog.String("error", errEmptyName.Error()))
-		return nil, errEmptyName
+// GetProducts returns a page of the products matching the filter.
+func (p *ProductService) GetProducts(ctx context.Context, filter store.ProductFilter, req page.Request) (*page.Page[*store.Product], error) {
+	if err := validatePriceRange(filter.MinPrice, filter.MaxPrice); err != nil {
+		p.logger.InfoContext(ctx, "error at GetProducts", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return p.db.RetrieveProducts(ctx, filter, req)
+}
+
+// SearchProducts returns a page of the products matching the search, most relevant first,
+// and the facet counts of the search if they were requested.
+func (p *ProductService) SearchProducts(ctx context.Context, filter store.SearchFilter, req page.Request) (*page.Page[*store.SearchResult], *store.SearchFacets, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Query == "" {
+		p.logger.InfoContext(ctx, "error at SearchProducts", slog.String("error", errEmptyQuery.Error()))
+		return nil, nil, errEmptyQuery
+	}
+	if err := validatePriceRange(filter.MinPrice, filter.MaxPrice); err != nil {
+		p.logger.InfoContext(ctx, "error at SearchProducts", slog.String("error", err.Error()))
+		return nil, nil, err
 	}
 
-	return p.db.RetrieveProducts(ctx, name)
+	return p.db.SearchProducts(ctx, filter, req)
 }
 
-// UpdateProduct updates a product with the given id.
-func (p *ProductService) UpdateProduct(ctx context.Context, id int, name string, price float64, stock int) error {
+// UpdateProduct updates a product with the given id. The currency of a product with options
+// cannot change, its SKUs are priced in it.
+func (p *ProductService) UpdateProduct(ctx context.Context, id int, name, description string, price money.Money, prices money.Prices, stock int) error {
 	if name == "" {
-		p.logger.Info("error at UpdateProduct", slog.String("error", errEmptyName.Error()))
+		p.logger.InfoContext(ctx, "error at UpdateProduct", slog.String("error", errEmptyName.Error()))
 		return errEmptyName
 	}
-	if price == 0.0 {
-		p.logger.Info("error at UpdateProduct", slog.String("error", errEmptyPrice.Error()))
+	if err := store.ValidateDescription(description); err != nil {
+		p.logger.InfoContext(ctx, "error at UpdateProduct", slog.String("error", err.Error()))
+		return err
+	}
+	if !price.IsPositive() {
+		p.logger.InfoContext(ctx, "error at UpdateProduct", slog.String("error", errEmptyPrice.Error()))
 		return errEmptyPrice
 	}
+	if err := validatePrices(price, prices); err != nil {
+		p.logger.InfoContext(ctx, "error at UpdateProduct", slog.String("error", err.Error()))
+		return err
+	}
 	if stock == 0 {
-		p.logger.Info("error at UpdateProduct", slog.String("error", errEmptyStock.Error()))
+		p.logger.InfoContext(ctx, "error at UpdateProduct", slog.String("error", errEmptyStock.Error()))
 		return errEmptyStock
 	}
 	if id == 0 {
-		p.logger.Info("error at UpdateProduct", slog.String("error", errEmptyID.Error()))
+		p.logger.InfoContext(ctx, "error at UpdateProduct", slog.String("error", errEmptyID.Error()))
 		return errEmptyID
 	}
 
 	return p.db.UpdateProduct(ctx, store.Product{
-		ID:    id,
-		Name:  name,
-		Price: price,
-		Stock: stock,
+		ID:          id,
+		Name:        name,
+		Description: description,
+		Price:       price,
+		Prices:      prices,
+		Stock:       stock,
 	})
 }
 
 // UpdateProductStock updates the stock of the product associated with the specified id.
 func (p *ProductService) UpdateProductStock(ctx context.Context, id int, stock int) error {
 	if stock == 0 {
-		p.logger.Info("error at UpdateProductStock", slog.String("error", errEmptyStock.Error()))
+		p.logger.InfoContext(ctx, "error at UpdateProductStock", slog.String("error", errEmptyStock.Error()))
 		return errEmptyStock
 	}
 	if id == 0 {
-		p.logger.Info("error at UpdateProductStock", slog.String("error", errEmptyID.Error()))
+		p.logger.InfoContext(ctx, "error at UpdateProductStock", slog.String("error", errEmptyID.Error()))
 		return errEmptyID
 	}
 
 	return p.db.UpdateProductStock(ctx, id, stock)
 }
+
+// validatePrices checks the prices of a product or SKU are positive and in currencies other than
+// the currency of its price.
+func validatePrices(price money.Money, prices money.Prices) error {
+	for currency, amount := range prices {
+		if currency == price.CurrencyCode() || amount.CurrencyCode() != currency || !amount.IsPositive() {
+			return errInvalidPrices
+		}
+	}
+
+	return nil
+}
+
+// validatePriceRange checks the bounds of a price range are in the same currency and in order.
+func validatePriceRange(min, max money.Money) error {
+	if !min.IsPositive() || !max.IsPositive() {
+		return nil
+	}
+	if !min.SameCurrency(max) {
+		return errMixedPriceRange
+	}
+	if min.Cmp(max) > 0 {
+		return errInvalidPriceRange
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/PseudoMera/virtual-store/product/store"
+	"github.com/PseudoMera/virtual-store/shared"
+)
+
+var (
+	errNegativeStock    = shared.InvalidFieldError("stock", "stock cannot be negative")
+	errStockWithOptions = shared.InvalidFieldError("stock", "the stock of a product with options is set on its skus")
+	errInvalidOptions   = shared.InvalidFieldError("options", "options must be unique and cannot be empty")
+)
+
+// CreateSKU stores a new SKU of a product with options, with a value for each of its options.
+func (p *ProductService) CreateSKU(ctx context.Context, sku store.SKU) (int, error) {
+	if sku.ProductID == 0 {
+		p.logger.InfoContext(ctx, "error at CreateSKU", slog.String("error", errEmptyProductID.Error()))
+		return 0, errEmptyProductID
+	}
+	if err := validateSKU(sku); err != nil {
+		p.logger.InfoContext(ctx, "error at CreateSKU", slog.String("error", err.Error()))
+		return 0, err
+	}
+
+	return p.db.StoreSKU(ctx, sku)
+}
+
+// GetSKU returns the SKU associated with the given id.
+func (p *ProductService) GetSKU(ctx context.Context, id int) (*store.SKU, error) {
+	if id == 0 {
+		p.logger.InfoContext(ctx, "error at GetSKU", slog.String("error", errEmptyID.Error()))
+		return nil, errEmptyID
+	}
+
+	return p.db.RetrieveSKU(ctx, id)
+}
+
+// GetSKUs returns the SKUs of the product with the given id.
+func (p *ProductService) GetSKUs(ctx context.Context, productID int) ([]*store.SKU, error) {
+	if productID == 0 {
+		p.logger.InfoContext(ctx, "error at GetSKUs", slog.String("error", errEmptyProductID.Error()))
+		return nil, errEmptyProductID
+	}
+
+	return p.db.RetrieveSKUs(ctx, productID)
+}
+
+// UpdateSKU updates the code, barcode, options, price and stock of the SKU with the given id.
+func (p *ProductService) UpdateSKU(ctx context.Context, sku store.SKU) error {
+	if sku.ID == 0 {
+		p.logger.InfoContext(ctx, "error at UpdateSKU", slog.String("error", errEmptyID.Error()))
+		return errEmptyID
+	}
+	if err := validateSKU(sku); err != nil {
+		p.logger.InfoContext(ctx, "error at UpdateSKU", slog.String("error", err.Error()))
+		return err
+	}
+
+	return p.db.UpdateSKU(ctx, sku)
+}
+
+// UpdateSKUStock sets the stock of the SKU with the given id.
+func (p *ProductService) UpdateSKUStock(ctx context.Context, id, stock int) error {
+	if id == 0 {
+		p.logger.InfoContext(ctx, "error at UpdateSKUStock", slog.String("error", errEmptyID.Error()))
+		return errEmptyID
+	}
+	if stock < 0 {
+		p.logger.InfoContext(ctx, "error at UpdateSKUStock", slog.String("error", errNegativeStock.Error()))
+		return errNegativeStock
+	}
+
+	return p.db.UpdateSKUStock(ctx, id, stock)
+}
+
+func validateSKU(sku store.SKU) error {
+	if !sku.Price.IsPositive() {
+		return errEmptyPrice
+	}
+	if sku.Stock < 0 {
+		return errNegativeStock
+	}
+	if err := validatePrices(sku.Price, sku.Prices); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateOptions checks the option names of a product are unique and not empty.
+func validateOptions(options []string) error {
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		if option == "" || seen[option] {
+			return errInvalidOptions
+		}
+		seen[option] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrCategoryCycle is returned when a category would be moved under itself or one of its descendants.
+var ErrCategoryCycle = shared.InvalidFieldError("parent_id", "a category cannot be moved under itself or one of its descendants")
+
+// categoryLockKey is hashed into the key of the advisory lock held by the transactions changing
+// the category tree, so concurrent moves cannot create a cycle.
+const categoryLockKey = "product_category_tree"
+
+// Category is a node of the category tree, ParentID is nil for the root categories. Path is the
+// breadcrumb from its root down to the category itself.
+type Category struct {
+	ID        int
+	ParentID  *int
+	Name      string
+	Path      Breadcrumb
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// CategoryRef is a step of a breadcrumb.
+type CategoryRef struct {
+	ID   int
+	Name string
+}
+
+// Breadcrumb is the path from a root category down to a category.
+type Breadcrumb []CategoryRef
+
+// categorySubtree returns a query selecting the ids of the category given by the placeholder
+// and of all its descendants.
+func categorySubtree(id string) string {
+	return "WITH RECURSIVE subtree AS (SELECT id FROM category WHERE id = " + id +
+		" UNION ALL SELECT c.id FROM category c JOIN subtree s ON c.parent_id = s.id) SELECT id FROM subtree"
+}
+
+// lockCategories takes the category tree lock until tx ends.
+func lockCategories(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", categoryLockKey)
+	return err
+}
+
+// StoreCategory creates a new category under category.ParentID, a root category if it is nil,
+// and returns its id. Sibling categories have unique names.
+func (s *Store) StoreCategory(ctx context.Context, category Category) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err := lockCategories(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = tx.QueryRow(ctx, "INSERT INTO category(parent_id, name) VALUES($1, $2) RETURNING id", category.ParentID, category.Name).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return 0, shared.DBError(pgx.ErrNoRows, "parent category")
+	}
+	if err != nil {
+		return 0, shared.DBError(err, "category")
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+// RetrieveCategory retrieves the category with the given id and its path.
+func (s *Store) RetrieveCategory(ctx context.Context, id int) (*Category, error) {
+	category := new(Category)
+	err := s.db.QueryRow(ctx, "SELECT id, parent_id, name, created_at, updated_at FROM category WHERE id = $1", id).Scan(&category.ID, &category.ParentID, &category.Name, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return nil, shared.DBError(err, "category")
+	}
+
+	rows, err := s.db.Query(ctx, "WITH RECURSIVE ancestors AS (SELECT id, parent_id, name, 0 AS depth FROM category WHERE id = $1"+
+		" UNION ALL SELECT c.id, c.parent_id, c.name, a.depth + 1 FROM category c JOIN ancestors a ON c.id = a.parent_id)"+
+		" SELECT id, name FROM ancestors ORDER BY depth DESC", id)
+	if err != nil {
+		return nil, err
+	}
+	category.Path, err = pgx.CollectRows(rows, pgx.RowToStructByPos[CategoryRef])
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// RetrieveCategories retrieves every category with its path, in depth-first order with
+// siblings sorted by name.
+func (s *Store) RetrieveCategories(ctx context.Context) ([]*Category, error) {
+	rows, err := s.db.Query(ctx, "WITH RECURSIVE tree AS ("+
+		"SELECT id, parent_id, name, created_at, updated_at, ARRAY[id] AS ids, ARRAY[name] AS names FROM category WHERE parent_id IS NULL"+
+		" UNION ALL SELECT c.id, c.parent_id, c.name, c.created_at, c.updated_at, t.ids || c.id, t.names || c.name FROM category c JOIN tree t ON c.parent_id = t.id)"+
+		" SELECT id, parent_id, name, created_at, updated_at, ids, names FROM tree ORDER BY names, ids")
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []*Category
+	var category Category
+	var ids []int
+	var names []string
+	_, err = pgx.ForEachRow(rows, []any{&category.ID, &category.ParentID, &category.Name, &category.CreatedAt, &category.UpdatedAt, &ids, &names}, func() error {
+		c := category
+		c.Path = make(Breadcrumb, len(ids))
+		for i := range ids {
+			c.Path[i] = CategoryRef{
+				ID:   ids[i],
+				Name: names[i],
+			}
+		}
+		categories = append(categories, &c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// UpdateCategory renames the category with the given id and moves it, with its descendants,
+// under category.ParentID, to the root if it is nil. Returns ErrCategoryCycle if the new parent
+// is the category itself or one of its descendants.
+func (s *Store) UpdateCategory(ctx context.Context, category Category) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err := lockCategories(ctx, tx); err != nil {
+		return err
+	}
+
+	if category.ParentID != nil {
+		var cycle bool
+		if err := tx.QueryRow(ctx, "SELECT $2 IN ("+categorySubtree("$1")+")", category.ID, *category.ParentID).Scan(&cycle); err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCategoryCycle
+		}
+	}
+
+	var id int
+	err = tx.QueryRow(ctx, "UPDATE category SET parent_id = $2, name = $3 WHERE id = $1 RETURNING id", category.ID, category.ParentID, category.Name).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return shared.DBError(pgx.ErrNoRows, "parent category")
+	}
+	if err != nil {
+		return shared.DBError(err, "category")
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteCategory deletes the category with the given id. Its subcategories and products move to
+// its parent so the tree stays connected, a deleted root leaves its subcategories as roots and
+// its products without the category.
+func (s *Store) DeleteCategory(ctx context.Context, id int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err := lockCategories(ctx, tx); err != nil {
+		return err
+	}
+
+	var parentID *int
+	if err := tx.QueryRow(ctx, "SELECT parent_id FROM category WHERE id = $1", id).Scan(&parentID); err != nil {
+		return shared.DBError(err, "category")
+	}
+
+	if parentID != nil {
+		if _, err := tx.Exec(ctx, "INSERT INTO product_category(product_id, category_id) SELECT product_id, $2 FROM product_category WHERE category_id = $1 ON CONFLICT DO NOTHING", id, *parentID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, "UPDATE category SET parent_id = $2 WHERE parent_id = $1", id, parentID); err != nil {
+		return shared.DBError(err, "category")
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM category WHERE id = $1", id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetProductCategories replaces the categories of the product with the given id.
+func (s *Store) SetProductCategories(ctx context.Context, productID int, categoryIDs []int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err := tx.QueryRow(ctx, "SELECT id FROM product WHERE id = $1 FOR UPDATE", productID).Scan(&productID); err != nil {
+		return shared.DBError(err, "product")
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM product_category WHERE product_id = $1", productID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO product_category(product_id, category_id) SELECT $1, unnest($2::int[]) ON CONFLICT DO NOTHING", productID, categoryIDs)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return shared.DBError(pgx.ErrNoRows, "category")
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// productBreadcrumbs returns the breadcrumbs of the categories of the given products, by product id.
+func (s *Store) productBreadcrumbs(ctx context.Context, productIDs []int) (map[int][]Breadcrumb, error) {
+	breadcrumbs := make(map[int][]Breadcrumb)
+	if len(productIDs) == 0 {
+		return breadcrumbs, nil
+	}
+
+	rows, err := s.db.Query(ctx, "WITH RECURSIVE ancestors AS ("+
+		"SELECT pc.product_id, pc.category_id, c.id, c.parent_id, c.name, 0 AS depth FROM product_category pc JOIN category c ON c.id = pc.category_id WHERE pc.product_id = ANY($1)"+
+		" UNION ALL SELECT a.product_id, a.category_id, c.id, c.parent_id, c.name, a.depth + 1 FROM category c JOIN ancestors a ON c.id = a.parent_id)"+
+		" SELECT product_id, category_id, id, name FROM ancestors ORDER BY product_id, category_id, depth DESC", productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var productID, categoryID int
+	var ref CategoryRef
+	lastProductID, lastCategoryID := 0, 0
+	_, err = pgx.ForEachRow(rows, []any{&productID, &categoryID, &ref.ID, &ref.Name}, func() error {
+		if productID != lastProductID || categoryID != lastCategoryID {
+			breadcrumbs[productID] = append(breadcrumbs[productID], nil)
+			lastProductID, lastCategoryID = productID, categoryID
+		}
+		crumbs := breadcrumbs[productID]
+		crumbs[len(crumbs)-1] = append(crumbs[len(crumbs)-1], ref)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return breadcrumbs, nil
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/PseudoMera/virtual-store/product/migrations"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
+)
+
+func TestCategoryTree(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	newCategory := func(name string, parentID *int) int {
+		t.Helper()
+		id, err := store.StoreCategory(ctx, Category{Name: name, ParentID: parentID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	electronics := newCategory("Electronics", nil)
+	computers := newCategory("Computers", &electronics)
+	laptops := newCategory("Laptops", &computers)
+	audio := newCategory("Audio", &electronics)
+
+	if _, err := store.StoreCategory(ctx, Category{Name: "Laptops", ParentID: &computers}); shared.HTTPStatus(err) != http.StatusConflict {
+		t.Fatalf("wanted %d, got %d", http.StatusConflict, shared.HTTPStatus(err))
+	}
+	missing := audio + 1
+	if _, err := store.StoreCategory(ctx, Category{Name: "Phones", ParentID: &missing}); shared.HTTPStatus(err) != http.StatusNotFound {
+		t.Fatalf("wanted %d, got %d", http.StatusNotFound, shared.HTTPStatus(err))
+	}
+
+	category, err := store.RetrieveCategory(ctx, laptops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wanted := "[{1 Electronics} {2 Computers} {3 Laptops}]"; fmt.Sprint(category.Path) != wanted {
+		t.Fatalf("wanted %s, got %v", wanted, category.Path)
+	}
+
+	laptop, err := store.StoreProduct(ctx, Product{Name: "laptop", Price: money.MustParse("900", ""), Stock: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	speaker, err := store.StoreProduct(ctx, Product{Name: "speaker", Price: money.MustParse("90", ""), Stock: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetProductCategories(ctx, laptop, []int{laptops}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetProductCategories(ctx, speaker, []int{audio}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetProductCategories(ctx, speaker, []int{missing}); shared.HTTPStatus(err) != http.StatusNotFound {
+		t.Fatalf("wanted %d, got %d", http.StatusNotFound, shared.HTTPStatus(err))
+	}
+
+	// Listing a category includes the products of its descendants.
+	products, err := store.RetrieveProducts(ctx, ProductFilter{CategoryID: electronics}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products.Items) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(products.Items))
+	}
+	if wanted := "[[{1 Electronics} {2 Computers} {3 Laptops}]]"; fmt.Sprint(products.Items[0].Categories) != wanted {
+		t.Fatalf("wanted %s, got %v", wanted, products.Items[0].Categories)
+	}
+	products, err = store.RetrieveProducts(ctx, ProductFilter{CategoryID: computers}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products.Items) != 1 || products.Items[0].ID != laptop {
+		t.Fatalf("wanted product %d, got %d products", laptop, len(products.Items))
+	}
+
+	// A category cannot move under its own subtree.
+	if err := store.UpdateCategory(ctx, Category{ID: computers, Name: "Computers", ParentID: &laptops}); !errors.Is(err, ErrCategoryCycle) {
+		t.Fatalf("wanted %v, got %v", ErrCategoryCycle, err)
+	}
+	if err := store.UpdateCategory(ctx, Category{ID: laptops, Name: "Laptops", ParentID: &audio}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting a category moves its subcategories and products to its parent.
+	if err := store.DeleteCategory(ctx, audio); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteCategory(ctx, audio); shared.HTTPStatus(err) != http.StatusNotFound {
+		t.Fatalf("wanted %d, got %d", http.StatusNotFound, shared.HTTPStatus(err))
+	}
+	product, err := store.RetrieveProduct(ctx, laptop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wanted := "[[{1 Electronics} {3 Laptops}]]"; fmt.Sprint(product.Categories) != wanted {
+		t.Fatalf("wanted %s, got %v", wanted, product.Categories)
+	}
+	product, err = store.RetrieveProduct(ctx, speaker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wanted := "[[{1 Electronics}]]"; fmt.Sprint(product.Categories) != wanted {
+		t.Fatalf("wanted %s, got %v", wanted, product.Categories)
+	}
+
+	categories, err := store.RetrieveCategories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+	if wanted := "[Electronics Computers Laptops]"; fmt.Sprint(names) != wanted {
+		t.Fatalf("wanted %s, got %v", wanted, names)
+	}
+}
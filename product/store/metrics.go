@@ -0,0 +1,24 @@
+package store
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Operations of the stock updates counter.
+const (
+	stockSet     = "set"
+	stockReserve = "reserve"
+	stockRelease = "release"
+)
+
+var (
+	stockUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "product_stock_updates_total",
+		Help: "Number of product stock updates, by operation.",
+	}, []string{"operation"})
+	stockUnits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "product_stock_units_total",
+		Help: "Number of stock units reserved for orders or released back, by operation.",
+	}, []string{"operation"})
+)
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
+	"github.com/jackc/pgx/v5"
+)
+
+// priceBucketBounds are the limits between the price buckets of the search facets.
+var priceBucketBounds = []money.Money{
+	money.MustParse("10", money.DefaultCurrency),
+	money.MustParse("50", money.DefaultCurrency),
+	money.MustParse("100", money.DefaultCurrency),
+	money.MustParse("500", money.DefaultCurrency),
+}
+
+// searchSortFields only holds the relevance, search results are always sorted by it.
+var searchSortFields = map[string]page.SortField{
+	"rank": {Column: "rank", Type: "real"},
+}
+
+// maxCategoryFacets is the number of categories counted by the search facets.
+const maxCategoryFacets = 20
+
+// SearchFilter selects the products of a search. Query is required, the other zero values do not
+// filter. CategoryID selects the products of a category and of all its descendants and Facets asks
+// for the facet counts of every product matching the search. Filtering by price selects the
+// products in the currency of MinPrice and MaxPrice, which must be the same. The price facets count
+// the products in that currency, which a zero MinPrice can set, DefaultCurrency otherwise.
+type SearchFilter struct {
+	Query      string
+	CategoryID int
+	MinPrice   money.Money
+	MaxPrice   money.Money
+	InStock    bool
+	Facets     bool
+}
+
+// currency returns the currency of the price range of the filter or, without one, the currency of
+// the zero MinPrice, which is DefaultCurrency unless it was set.
+func (f SearchFilter) currency() string {
+	if f.MaxPrice.IsPositive() && !f.MinPrice.IsPositive() {
+		return f.MaxPrice.CurrencyCode()
+	}
+
+	return f.MinPrice.CurrencyCode()
+}
+
+// SearchResult is a product matching a search. Rank is its relevance, higher is better, and
+// Snippet is the part of its description, or its name, matching the query with the matched
+// words wrapped in <mark> tags.
+type SearchResult struct {
+	Product
+	Rank    float32
+	Snippet string
+}
+
+// PriceBucket counts the products priced from Min up to Max, Max is zero for the last bucket.
+type PriceBucket struct {
+	Min   money.Money
+	Max   money.Money
+	Count int
+}
+
+// CategoryCount counts the products of a category.
+type CategoryCount struct {
+	ID    int
+	Name  string
+	Count int
+}
+
+// SearchFacets counts the products matching a search across all pages, by price bucket and by
+// category for the categories with the most products. Prices only counts the products in the
+// currency of the filter, see SearchFilter, prices in different currencies cannot be compared.
+type SearchFacets struct {
+	Prices     []PriceBucket
+	Categories []CategoryCount
+}
+
+// SearchProducts retrieves a page of the products matching the search, most relevant first.
+// Products match on the full-text index of their name and description or, to tolerate typos,
+// on the trigram similarity of their name. Facets are only returned when requested.
+func (s *Store) SearchProducts(ctx context.Context, filter SearchFilter, req page.Request) (*page.Page[*SearchResult], *SearchFacets, error) {
+	var q page.Query
+	query := q.Arg(filter.Query)
+	matches := "(SELECT " + productColumns + "," +
+		" (ts_rank_cd(search_vector, websearch_to_tsquery('english', " + query + ")) + word_similarity(" + query + ", name))::real AS rank" +
+		" FROM product WHERE search_vector @@ websearch_to_tsquery('english', " + query + ") OR " + query + " <% name) matches"
+
+	if filter.CategoryID != 0 {
+		q.Where(inCategory(q.Arg(filter.CategoryID)))
+	}
+	priceRange(&q, filter.MinPrice, filter.MaxPrice)
+	if filter.InStock {
+		q.Where("stock > 0")
+	}
+
+	var total *int
+	var facets *SearchFacets
+	if req.WithTotal || filter.Facets {
+		var count int
+		var err error
+		facets, count, err = s.searchFacets(ctx, matches, q, filter.currency())
+		if err != nil {
+			return nil, nil, err
+		}
+		if req.WithTotal {
+			total = &count
+		}
+		if !filter.Facets {
+			facets = nil
+		}
+	}
+
+	req.Sort, req.Desc = "rank", true
+	orderBy, err := req.Apply(&q, searchSortFields, "rank")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Snippets are only highlighted for the rows of the page, ts_headline is expensive.
+	rows, err := s.db.Query(ctx, "SELECT "+productColumns+", rank,"+
+		" ts_headline('english', COALESCE(NULLIF(description, ''), name), websearch_to_tsquery('english', "+query+"), 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')"+
+		" FROM (SELECT * FROM "+matches+q.Conditions()+orderBy+") results ORDER BY rank DESC, id DESC", q.Args()...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var results []*SearchResult
+	var result SearchResult
+	var ids []int
+	_, err = pgx.ForEachRow(rows, append(productFields(&result.Product), &result.Rank, &result.Snippet), func() error {
+		r := result
+		r.Currency = r.Price.Currency
+		results = append(results, &r)
+		ids = append(ids, r.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	breadcrumbs, err := s.productBreadcrumbs(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, result := range results {
+		result.Categories = breadcrumbs[result.ID]
+	}
+
+	p := page.New(results, req, searchKey)
+	p.Total = total
+	return p, facets, nil
+}
+
+// searchFacets counts the products of the given matches selected by q by category and, for the
+// products in the given currency, by price bucket, and returns them with the number of products.
+// The bounds of the price buckets are amounts of the given currency.
+func (s *Store) searchFacets(ctx context.Context, matches string, q page.Query, currency string) (*SearchFacets, int, error) {
+	categories, err := s.db.Query(ctx, "SELECT c.id, c.name, COUNT(*) FROM (SELECT id FROM "+matches+q.Conditions()+") results"+
+		" JOIN product_category pc ON pc.product_id = results.id JOIN category c ON c.id = pc.category_id"+
+		" GROUP BY c.id, c.name ORDER BY COUNT(*) DESC, c.id LIMIT "+strconv.Itoa(maxCategoryFacets), q.Args()...)
+	if err != nil {
+		return nil, 0, err
+	}
+	categoryCounts, err := pgx.CollectRows(categories, pgx.RowToStructByPos[CategoryCount])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	bounds := make([]string, len(priceBucketBounds))
+	bucketBounds := make([]money.Money, len(priceBucketBounds))
+	for i, bound := range priceBucketBounds {
+		bucketBounds[i], err = bound.InCurrency(currency)
+		if err != nil {
+			return nil, 0, err
+		}
+		bounds[i] = bucketBounds[i].String()
+	}
+	// Products in other currencies have no bucket, they are only counted in the total.
+	bucket := "CASE WHEN currency = " + q.Arg(currency) + " THEN width_bucket(price, " + q.Arg(bounds) + "::numeric[]) END"
+	rows, err := s.db.Query(ctx, "SELECT "+bucket+" AS bucket, COUNT(*) FROM "+matches+q.Conditions()+" GROUP BY bucket", q.Args()...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	facets := &SearchFacets{
+		Prices:     make([]PriceBucket, len(priceBucketBounds)+1),
+		Categories: categoryCounts,
+	}
+	for i := range facets.Prices {
+		facets.Prices[i].Min = money.New(0, currency)
+		facets.Prices[i].Max = money.New(0, currency)
+		if i > 0 {
+			facets.Prices[i].Min = bucketBounds[i-1]
+		}
+		if i < len(bucketBounds) {
+			facets.Prices[i].Max = bucketBounds[i]
+		}
+	}
+
+	var index *int
+	var count, total int
+	_, err = pgx.ForEachRow(rows, []any{&index, &count}, func() error {
+		if index != nil {
+			facets.Prices[*index].Count = count
+		}
+		total += count
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return facets, total, nil
+}
+
+// searchKey returns the cursor key of a search result.
+func searchKey(result *SearchResult, sort string) (string, int) {
+	return strconv.FormatFloat(float64(result.Rank), 'g', -1, 32), result.ID
+}
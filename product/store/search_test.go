@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PseudoMera/virtual-store/product/migrations"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
+)
+
+func TestSearchProducts(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	products := []Product{
+		{Name: "Wireless keyboard", Description: "A compact keyboard with a long lasting battery", Price: money.MustParse("45", ""), Stock: 3},
+		{Name: "Mechanical keyboard", Description: "Tactile switches", Price: money.MustParse("120", ""), Stock: 1},
+		{Name: "Mouse pad", Description: "Fits next to any keyboard", Price: money.MustParse("8", ""), Stock: 0},
+		{Name: "Monitor", Description: "A 27 inch display", Price: money.MustParse("300", ""), Stock: 2},
+	}
+	for _, product := range products {
+		if _, err := store.StoreProduct(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, facets, err := store.SearchProducts(ctx, SearchFilter{Query: "keyboard", Facets: true}, page.Request{WithTotal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Items) != 3 || results.Total == nil || *results.Total != 3 {
+		t.Fatalf("wanted %d results, got %d", 3, len(results.Items))
+	}
+	// Matches on the name rank above matches on the description.
+	if results.Items[2].ID != 3 {
+		t.Fatalf("wanted product %d last, got %d", 3, results.Items[2].ID)
+	}
+	if !strings.Contains(results.Items[0].Snippet, "<mark>keyboard</mark>") {
+		t.Fatalf("wanted a highlighted snippet, got %s", results.Items[0].Snippet)
+	}
+	wantedCounts := []int{1, 1, 0, 1, 0}
+	for i, bucket := range facets.Prices {
+		if bucket.Count != wantedCounts[i] {
+			t.Fatalf("wanted %d products from %v, got %d", wantedCounts[i], bucket.Min, bucket.Count)
+		}
+	}
+
+	// Typos still match the name.
+	results, _, err = store.SearchProducts(ctx, SearchFilter{Query: "monitr"}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Items) != 1 || results.Items[0].ID != 4 {
+		t.Fatalf("wanted product %d, got %d results", 4, len(results.Items))
+	}
+
+	results, _, err = store.SearchProducts(ctx, SearchFilter{Query: "keyboard", InStock: true, MaxPrice: money.MustParse("100", "")}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Items) != 1 || results.Items[0].ID != 1 {
+		t.Fatalf("wanted product %d, got %d results", 1, len(results.Items))
+	}
+
+	// Walking the pages visits every result once.
+	seen := map[int]bool{}
+	req := page.Request{Limit: 1}
+	for {
+		results, _, err := store.SearchProducts(ctx, SearchFilter{Query: "keyboard"}, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range results.Items {
+			if seen[result.ID] {
+				t.Fatalf("wanted product %d once, got it twice", result.ID)
+			}
+			seen[result.ID] = true
+		}
+		if results.NextCursor == "" {
+			break
+		}
+		req.Cursor = results.NextCursor
+	}
+	if len(seen) != 3 {
+		t.Fatalf("wanted %d, got %d", 3, len(seen))
+	}
+}
+
+func TestSearchFacetsByCurrency(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	products := []Product{
+		{Name: "Desk lamp", Price: money.MustParse("30", "USD"), Stock: 1},
+		{Name: "Floor lamp", Price: money.MustParse("4500", "JPY"), Stock: 1},
+	}
+	for _, product := range products {
+		if _, err := store.StoreProduct(ctx, product); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		filter       SearchFilter
+		wantedCounts []int
+	}{
+		// Without a price range the buckets are in USD and the JPY lamp is left out of them.
+		{SearchFilter{Query: "lamp", Facets: true}, []int{0, 1, 0, 0, 0}},
+		{SearchFilter{Query: "lamp", Facets: true, MinPrice: money.MustParse("1", "JPY")}, []int{0, 0, 0, 0, 1}},
+		// A zero minimum price only sets the currency of the buckets.
+		{SearchFilter{Query: "lamp", Facets: true, MinPrice: money.New(0, "JPY")}, []int{0, 0, 0, 0, 1}},
+	} {
+		results, facets, err := store.SearchProducts(ctx, test.filter, page.Request{WithTotal: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, bucket := range facets.Prices {
+			if bucket.Count != test.wantedCounts[i] {
+				t.Fatalf("wanted %d products from %v, got %d", test.wantedCounts[i], bucket.Min, bucket.Count)
+			}
+			if bucket.Min.CurrencyCode() != test.filter.currency() {
+				t.Fatalf("wanted %s, got %s", test.filter.currency(), bucket.Min.CurrencyCode())
+			}
+		}
+		if *results.Total != len(results.Items) {
+			t.Fatalf("wanted %d, got %d", len(results.Items), *results.Total)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrSKURequired is returned when a product with options is referred to without one of its SKUs.
+	ErrSKURequired = shared.InvalidFieldError("sku_id", "the product has options, select one of its skus")
+	// ErrInvalidOptions is returned when the options of a SKU are not the options of its product.
+	ErrInvalidOptions = shared.InvalidFieldError("options", "options must set a value for every option of the product and nothing else")
+	// ErrCurrencyMismatch is returned when the price of a SKU is not in the currency of its product.
+	ErrCurrencyMismatch = shared.InvalidFieldError("price", "the price of a sku must be in the currency of its product")
+)
+
+// skuColumns are the columns scanned by scanSKU.
+const skuColumns = "id, product_id, COALESCE(code, ''), COALESCE(barcode, ''), options, (SELECT currency FROM product WHERE product.id = sku.product_id), price, prices, stock, created_at, updated_at"
+
+// SKU is a variant of a product that is sold and stocked on its own. Options holds its value for
+// each of the options of its product, it is empty for the single SKU of a product without options.
+// Code and Barcode are optional and unique. Price is in Currency, the currency of its product, and
+// Prices holds its optional prices in other currencies, which are sold at them instead of at the
+// converted Price.
+type SKU struct {
+	ID        int
+	ProductID int
+	Code      string
+	Barcode   string
+	Options   map[string]string
+	Currency  string
+	Price     money.Money
+	Prices    money.Prices
+	Stock     int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func scanSKU(row pgx.Row) (*SKU, error) {
+	sku := new(SKU)
+	err := row.Scan(&sku.ID, &sku.ProductID, &sku.Code, &sku.Barcode, &sku.Options, &sku.Price.Currency, &sku.Price, &sku.Prices, &sku.Stock, &sku.CreatedAt, &sku.UpdatedAt)
+	sku.Currency = sku.Price.Currency
+
+	return sku, err
+}
+
+// checkOptions returns ErrInvalidOptions unless the values set a value for each option of a product.
+func checkOptions(options []string, values map[string]string) error {
+	if len(values) != len(options) {
+		return ErrInvalidOptions
+	}
+	for _, option := range options {
+		if values[option] == "" {
+			return ErrInvalidOptions
+		}
+	}
+
+	return nil
+}
+
+// skuOptions returns the options of a SKU as they are stored, never nil.
+func skuOptions(values map[string]string) map[string]string {
+	if values == nil {
+		return map[string]string{}
+	}
+
+	return values
+}
+
+// StoreSKU creates a new SKU of a product with options and returns its id. Its stock is added to
+// the stock of the product alongside a ProductStockChanged event. Returns ErrCurrencyMismatch if
+// its price is not in the currency of the product.
+func (s *Store) StoreSKU(ctx context.Context, sku SKU) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var options []string
+	var currency string
+	if err := tx.QueryRow(ctx, "SELECT options, currency FROM product WHERE id = $1 FOR UPDATE", sku.ProductID).Scan(&options, &currency); err != nil {
+		return 0, shared.DBError(err, "product")
+	}
+	if len(options) == 0 {
+		return 0, ErrInvalidOptions
+	}
+	if err := checkOptions(options, sku.Options); err != nil {
+		return 0, err
+	}
+	if sku.Price.CurrencyCode() != currency {
+		return 0, ErrCurrencyMismatch
+	}
+
+	var id int
+	err = tx.QueryRow(ctx, "INSERT INTO sku(product_id, code, barcode, options, price, prices, stock) VALUES($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7) RETURNING id", sku.ProductID, sku.Code, sku.Barcode, sku.Options, sku.Price, productPrices(sku.Prices), sku.Stock).Scan(&id)
+	if err != nil {
+		return 0, shared.DBError(err, "sku")
+	}
+
+	if sku.Stock != 0 {
+		stock, err := addProductStock(ctx, tx, sku.ProductID, sku.Stock)
+		if err != nil {
+			return 0, err
+		}
+		if err := addStockChanged(ctx, tx, sku.ProductID, id, stock, sku.Stock, events.StockSet, 0); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit(ctx)
+}
+
+// RetrieveSKU retrieves the SKU with the given id.
+func (s *Store) RetrieveSKU(ctx context.Context, id int) (*SKU, error) {
+	sku, err := scanSKU(s.db.QueryRow(ctx, "SELECT "+skuColumns+" FROM sku WHERE id = $1", id))
+	if err != nil {
+		return nil, shared.DBError(err, "sku")
+	}
+
+	return sku, nil
+}
+
+// RetrieveDefaultSKU retrieves the single SKU of the product with the given id.
+// Returns ErrSKURequired if the product has options.
+func (s *Store) RetrieveDefaultSKU(ctx context.Context, productID int) (*SKU, error) {
+	var options []string
+	if err := s.db.QueryRow(ctx, "SELECT options FROM product WHERE id = $1", productID).Scan(&options); err != nil {
+		return nil, shared.DBError(err, "product")
+	}
+	if len(options) > 0 {
+		return nil, ErrSKURequired
+	}
+
+	sku, err := scanSKU(s.db.QueryRow(ctx, "SELECT "+skuColumns+" FROM sku WHERE product_id = $1 AND options = '{}'", productID))
+	if err != nil {
+		return nil, shared.DBError(err, "sku")
+	}
+
+	return sku, nil
+}
+
+// RetrieveSKUs retrieves the SKUs of the product with the given id, oldest first.
+func (s *Store) RetrieveSKUs(ctx context.Context, productID int) ([]*SKU, error) {
+	var exists bool
+	if err := s.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM product WHERE id = $1)", productID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, shared.DBError(pgx.ErrNoRows, "product")
+	}
+
+	rows, err := s.db.Query(ctx, "SELECT "+skuColumns+" FROM sku WHERE product_id = $1 ORDER BY id", productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	skus := []*SKU{}
+	for rows.Next() {
+		sku, err := scanSKU(rows)
+		if err != nil {
+			return nil, err
+		}
+		skus = append(skus, sku)
+	}
+
+	return skus, rows.Err()
+}
+
+// UpdateSKU updates the code, barcode, options, prices and stock of the SKU with the given id.
+// The change of its stock is added to the stock of its product alongside a ProductStockChanged
+// event. The prices of the single SKU of a product without options are the prices of the product,
+// both are updated. Returns ErrCurrencyMismatch if its price is not in the currency of the product.
+func (s *Store) UpdateSKU(ctx context.Context, sku SKU) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	sku.ProductID, err = lockSKUProduct(ctx, tx, sku.ID)
+	if err != nil {
+		return err
+	}
+	var previousStock int
+	var options []string
+	var currency string
+	err = tx.QueryRow(ctx, "SELECT s.stock, p.options, p.currency FROM sku s JOIN product p ON p.id = s.product_id WHERE s.id = $1 FOR UPDATE OF s", sku.ID).Scan(&previousStock, &options, &currency)
+	if err != nil {
+		return shared.DBError(err, "sku")
+	}
+	sku.Options = skuOptions(sku.Options)
+	if err := checkOptions(options, sku.Options); err != nil {
+		return err
+	}
+	if sku.Price.CurrencyCode() != currency {
+		return ErrCurrencyMismatch
+	}
+	sku.Prices = productPrices(sku.Prices)
+
+	_, err = tx.Exec(ctx, "UPDATE sku SET code = NULLIF($2, ''), barcode = NULLIF($3, ''), options = $4, price = $5, prices = $6, stock = $7 WHERE id = $1", sku.ID, sku.Code, sku.Barcode, sku.Options, sku.Price, sku.Prices, sku.Stock)
+	if err != nil {
+		return shared.DBError(err, "sku")
+	}
+	if len(options) == 0 {
+		if _, err := tx.Exec(ctx, "UPDATE product SET price = $2, prices = $3 WHERE id = $1", sku.ProductID, sku.Price, sku.Prices); err != nil {
+			return err
+		}
+	}
+	if err := s.skuStockChanged(ctx, tx, sku.ProductID, sku.ID, sku.Stock-previousStock); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UpdateSKUStock sets the stock of the SKU with the given id, the change is added to the stock
+// of its product alongside a ProductStockChanged event.
+func (s *Store) UpdateSKUStock(ctx context.Context, id, stock int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	productID, err := lockSKUProduct(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	var previousStock int
+	err = tx.QueryRow(ctx, "UPDATE sku s SET stock = $2 FROM (SELECT id, stock FROM sku WHERE id = $1 FOR UPDATE) previous WHERE s.id = previous.id RETURNING previous.stock", id, stock).Scan(&previousStock)
+	if err != nil {
+		return shared.DBError(err, "sku")
+	}
+	if err := s.skuStockChanged(ctx, tx, productID, id, stock-previousStock); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	stockUpdates.WithLabelValues(stockSet).Inc()
+
+	return nil
+}
+
+// skuStockChanged adds the change of the stock of a SKU to its product within tx and writes the
+// ProductStockChanged event, if the stock changed.
+func (s *Store) skuStockChanged(ctx context.Context, tx pgx.Tx, productID, skuID, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+
+	stock, err := addProductStock(ctx, tx, productID, delta)
+	if err != nil {
+		return err
+	}
+
+	return addStockChanged(ctx, tx, productID, skuID, stock, delta, events.StockSet, 0)
+}
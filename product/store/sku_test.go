@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/PseudoMera/virtual-store/product/migrations"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
+)
+
+func TestSKUStore(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	id, err := store.StoreProduct(ctx, Product{
+		Name:    "shirt",
+		Price:   money.MustParse("20", ""),
+		Stock:   10,
+		Options: []string{"size", "color"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStock := func(want int) {
+		t.Helper()
+		product, err := store.RetrieveProduct(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if product.Stock != want {
+			t.Fatalf("wanted %d, got %d", want, product.Stock)
+		}
+	}
+	// The stock of a product with options comes from its SKUs.
+	assertStock(0)
+
+	small, err := store.StoreSKU(ctx, SKU{ProductID: id, Code: "SHIRT-S-RED", Options: map[string]string{"size": "S", "color": "red"}, Price: money.MustParse("20", ""), Stock: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := store.StoreSKU(ctx, SKU{ProductID: id, Barcode: "0012345678905", Options: map[string]string{"size": "L", "color": "red"}, Price: money.MustParse("22", ""), Stock: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertStock(5)
+
+	invalid := []map[string]string{
+		{"size": "M"},
+		{"size": "M", "color": "red", "fit": "slim"},
+		{"size": "M", "fabric": "cotton"},
+	}
+	for _, options := range invalid {
+		if _, err := store.StoreSKU(ctx, SKU{ProductID: id, Options: options, Price: money.MustParse("20", "")}); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("wanted %v, got %v", ErrInvalidOptions, err)
+		}
+	}
+	if _, err := store.StoreSKU(ctx, SKU{ProductID: id, Code: "SHIRT-S-RED", Options: map[string]string{"size": "M", "color": "red"}, Price: money.MustParse("20", "")}); shared.HTTPStatus(err) != http.StatusConflict {
+		t.Fatalf("wanted %d, got %d", http.StatusConflict, shared.HTTPStatus(err))
+	}
+	if _, err := store.StoreSKU(ctx, SKU{ProductID: id, Options: map[string]string{"size": "S", "color": "red"}, Price: money.MustParse("20", "")}); shared.HTTPStatus(err) != http.StatusConflict {
+		t.Fatalf("wanted %d, got %d", http.StatusConflict, shared.HTTPStatus(err))
+	}
+
+	if _, err := store.RetrieveDefaultSKU(ctx, id); !errors.Is(err, ErrSKURequired) {
+		t.Fatalf("wanted %v, got %v", ErrSKURequired, err)
+	}
+	if err := store.UpdateProductStock(ctx, id, 4); !errors.Is(err, ErrSKURequired) {
+		t.Fatalf("wanted %v, got %v", ErrSKURequired, err)
+	}
+
+	sku, err := store.RetrieveSKU(ctx, small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sku.Price = money.MustParse("21", "")
+	sku.Stock = 6
+	if err := store.UpdateSKU(ctx, *sku); err != nil {
+		t.Fatal(err)
+	}
+	assertStock(8)
+
+	if err := store.ReserveSKUStock(ctx, 1, large, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReserveSKUStock(ctx, 1, large, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReserveSKUStock(ctx, 2, large, 1); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("wanted %v, got %v", ErrInsufficientStock, err)
+	}
+	assertStock(6)
+
+	if err := store.ReleaseOrderStock(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	assertStock(8)
+
+	skus, err := store.RetrieveSKUs(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skus) != 2 || skus[0].Price != money.MustParse("21", "") || skus[1].Stock != 2 || skus[1].Options["size"] != "L" {
+		t.Fatalf("wanted the updated skus, got %+v %+v", skus[0], skus[1])
+	}
+
+	// A product without options has a single SKU following its price and stock.
+	simple, err := store.StoreProduct(ctx, Product{Name: "mug", Price: money.MustParse("8", ""), Stock: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	product, err := store.RetrieveProduct(ctx, simple)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product.Price = money.MustParse("9", "")
+	if err := store.UpdateProduct(ctx, *product); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateProductStock(ctx, simple, 7); err != nil {
+		t.Fatal(err)
+	}
+	sku, err = store.RetrieveDefaultSKU(ctx, simple)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sku.Price != money.MustParse("9", "") || sku.Stock != 7 {
+		t.Fatalf("wanted a price of %s and a stock of %d, got %s and %d", "9.00", 7, sku.Price, sku.Stock)
+	}
+}
+
+func TestProductCurrency(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	simple, err := store.StoreProduct(ctx, Product{
+		Name:   "mug",
+		Price:  money.MustParse("10", "EUR"),
+		Prices: money.Prices{"USD": money.MustParse("11.50", "USD")},
+		Stock:  4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sku, err := store.RetrieveDefaultSKU(ctx, simple)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sku.Currency != "EUR" || sku.Price != money.MustParse("10", "EUR") || sku.Prices["USD"] != money.MustParse("11.50", "USD") {
+		t.Fatalf("wanted the prices of the product, got %s %s %v", sku.Currency, sku.Price, sku.Prices)
+	}
+
+	// The prices of the single SKU of a product are the prices of the product.
+	sku.Prices = money.Prices{"JPY": money.MustParse("1700", "JPY")}
+	if err := store.UpdateSKU(ctx, *sku); err != nil {
+		t.Fatal(err)
+	}
+	product, err := store.RetrieveProduct(ctx, simple)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Currency != "EUR" || len(product.Prices) != 1 || product.Prices["JPY"] != money.MustParse("1700", "JPY") {
+		t.Fatalf("wanted the prices of the sku, got %s %v", product.Currency, product.Prices)
+	}
+
+	withOptions, err := store.StoreProduct(ctx, Product{Name: "shirt", Price: money.MustParse("20", "EUR"), Options: []string{"size"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.StoreSKU(ctx, SKU{ProductID: withOptions, Options: map[string]string{"size": "S"}, Price: money.MustParse("20", "USD")}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("wanted %v, got %v", ErrCurrencyMismatch, err)
+	}
+	product, err = store.RetrieveProduct(ctx, withOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product.Price = money.MustParse("20", "USD")
+	if err := store.UpdateProduct(ctx, *product); !errors.Is(err, ErrCurrencyChanged) {
+		t.Fatalf("wanted %v, got %v", ErrCurrencyChanged, err)
+	}
+
+	// Price filters select the products in their currency.
+	products, err := store.RetrieveProducts(ctx, ProductFilter{MinPrice: money.MustParse("5", "EUR")}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products.Items) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(products.Items))
+	}
+	products, err = store.RetrieveProducts(ctx, ProductFilter{MinPrice: money.MustParse("5", "USD")}, page.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products.Items) != 0 {
+		t.Fatalf("wanted %d, got %d", 0, len(products.Items))
+	}
+}
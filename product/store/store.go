@@ -2,11 +2,40 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
+	"unicode/utf8"
 
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/outbox"
+	"github.com/PseudoMera/virtual-store/shared/page"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	// ErrInsufficientStock is returned when a product does not have enough stock for a reservation.
+	ErrInsufficientStock = shared.ConflictError("insufficient stock")
+	// ErrCurrencyChanged is returned when changing the currency of a product with options, its
+	// SKUs are priced in it.
+	ErrCurrencyChanged = shared.InvalidFieldError("currency", "the currency of a product with options cannot change")
+	// ErrPricesWithOptions is returned when setting the prices of a product with options, they are
+	// set on each of its SKUs.
+	ErrPricesWithOptions = shared.InvalidFieldError("prices", "the prices of a product with options are set on its skus")
+	// ErrLongDescription is returned for a product description longer than MaxDescriptionLength.
+	ErrLongDescription = shared.InvalidFieldError("description", fmt.Sprintf("description cannot be longer than %d characters", MaxDescriptionLength))
+)
+
+// MaxDescriptionLength is the maximum number of characters of a product description, the product
+// table enforces it too.
+const MaxDescriptionLength = 2000
+
+const foreignKeyViolation = "23503"
+
 type Store struct {
 	db *pgxpool.Pool
 }
@@ -19,60 +48,569 @@ func NewStore(db *pgxpool.Pool) *Store {
 	}
 }
 
+// ValidateDescription returns ErrLongDescription if the product description is longer than MaxDescriptionLength.
+func ValidateDescription(description string) error {
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return ErrLongDescription
+	}
+
+	return nil
+}
+
+// productColumns are the columns scanned into productFields.
+const productColumns = "id, name, description, currency, price, prices, stock, options, created_at, updated_at"
+
+// Product is a product of the catalog, sold as one or more SKUs. Options are the names of the
+// options its SKUs vary by, such as size or color, a product without options has a single SKU.
+// Price is in Currency, the base currency of the product and of its SKUs, and Prices holds its
+// optional prices in other currencies. Stock is the sum of the stock of its SKUs. Categories
+// holds the breadcrumbs of the categories it belongs to, it is only set on retrieval.
 type Product struct {
-	ID        int
-	Name      string
-	Price     float64
-	Stock     int
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID          int
+	Name        string
+	Description string
+	Currency    string
+	Price       money.Money
+	Prices      money.Prices
+	Stock       int
+	Options     []string
+	Categories  []Breadcrumb
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// productFields returns the scan targets of productColumns. The currency is scanned into the
+// price first, the price is read in it.
+func productFields(product *Product) []any {
+	return []any{&product.ID, &product.Name, &product.Description, &product.Price.Currency, &product.Price, &product.Prices, &product.Stock, &product.Options, &product.CreatedAt, &product.UpdatedAt}
 }
 
+// productPrices returns the prices of a product or SKU as they are stored, never nil.
+func productPrices(prices money.Prices) money.Prices {
+	if prices == nil {
+		return money.Prices{}
+	}
+
+	return prices
+}
+
+// StoreProduct creates a new product alongside a ProductCreated event and returns its id.
+// The product is in the currency of its price. A product without options gets a single SKU
+// holding its prices and stock, the SKUs of a product with options are stored with StoreSKU and
+// its stock starts at zero. Returns ErrPricesWithOptions if a product with options has prices.
 func (s *Store) StoreProduct(ctx context.Context, product Product) (int, error) {
+	if len(product.Options) > 0 && len(product.Prices) > 0 {
+		return 0, ErrPricesWithOptions
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if len(product.Options) > 0 {
+		product.Stock = 0
+	}
+	product.Currency = product.Price.CurrencyCode()
+	product.Prices = productPrices(product.Prices)
+
 	var id int
-	err := s.db.QueryRow(ctx, "INSERT INTO product(name, price, stock) VALUES($1, $2, $3) RETURNING id", product.Name, product.Price, product.Stock).Scan(&id)
-	return id, err
+	err = tx.QueryRow(ctx, "INSERT INTO product(name, description, currency, price, prices, stock, options) VALUES($1, $2, $3, $4, $5, $6, COALESCE($7::varchar[], '{}')) RETURNING id", product.Name, product.Description, product.Currency, product.Price, product.Prices, product.Stock, product.Options).Scan(&id)
+	if err != nil {
+		return 0, shared.DBError(err, "product")
+	}
+	if len(product.Options) == 0 {
+		_, err := tx.Exec(ctx, "INSERT INTO sku(product_id, price, prices, stock) VALUES($1, $2, $3, $4)", id, product.Price, product.Prices, product.Stock)
+		if err != nil {
+			return 0, shared.DBError(err, "product")
+		}
+	}
+
+	if err := outbox.Add(ctx, tx, events.AggregateProduct, id, events.ProductCreated, events.ProductPayload{
+		ProductID:   id,
+		Name:        product.Name,
+		Description: product.Description,
+		Currency:    product.Currency,
+		Price:       product.Price,
+		Prices:      product.Prices,
+		Stock:       product.Stock,
+	}); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit(ctx)
 }
 
 func (s *Store) RetrieveProduct(ctx context.Context, id int) (*Product, error) {
 	product := new(Product)
-	err := s.db.QueryRow(ctx, "SELECT id, name, price, stock, created_at, updated_at FROM product WHERE id = $1", id).Scan(&product.ID, &product.Name, &product.Price, &product.Stock, &product.CreatedAt, &product.UpdatedAt)
-	return product, err
+	err := s.db.QueryRow(ctx, "SELECT "+productColumns+" FROM product WHERE id = $1", id).Scan(productFields(product)...)
+	if err != nil {
+		return product, shared.DBError(err, "product")
+	}
+	product.Currency = product.Price.Currency
+
+	breadcrumbs, err := s.productBreadcrumbs(ctx, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	product.Categories = breadcrumbs[id]
+
+	return product, nil
 }
 
-func (s *Store) RetrieveProducts(ctx context.Context, name string) ([]*Product, error) {
-	rows, err := s.db.Query(ctx, "SELECT * FROM product WHERE name = $1", name)
+// ProductFilter selects the products of a list. Zero values do not filter, CategoryID selects
+// the products of a category and of all its descendants. Filtering by price selects the products
+// in the currency of MinPrice and MaxPrice, which must be the same and be Currency if it is set.
+// Sorting by price requires Currency or a price range, prices of different currencies cannot be compared.
+type ProductFilter struct {
+	CategoryID    int
+	Name          string
+	Currency      string
+	MinPrice      money.Money
+	MaxPrice      money.Money
+	InStock       bool
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// currency returns the currency the filter selects, empty if it selects every currency.
+func (f ProductFilter) currency() string {
+	switch {
+	case f.Currency != "":
+		return f.Currency
+	case f.MinPrice.IsPositive():
+		return f.MinPrice.CurrencyCode()
+	case f.MaxPrice.IsPositive():
+		return f.MaxPrice.CurrencyCode()
+	default:
+		return ""
+	}
+}
+
+// productSortFields are the fields products can be sorted by, id is the default.
+var productSortFields = map[string]page.SortField{
+	"id":         {Column: "id", Type: "int"},
+	"name":       {Column: "name", Type: "varchar"},
+	"price":      {Column: "price", Type: "numeric"},
+	"created_at": {Column: "created_at", Type: "timestamp"},
+}
+
+// RetrieveProducts retrieves a page of the products matching the filter.
+func (s *Store) RetrieveProducts(ctx context.Context, filter ProductFilter, req page.Request) (*page.Page[*Product], error) {
+	if req.Sort == "price" && filter.currency() == "" {
+		return nil, page.ErrSortCurrency
+	}
+
+	var q page.Query
+	if filter.CategoryID != 0 {
+		if _, err := s.RetrieveCategory(ctx, filter.CategoryID); err != nil {
+			return nil, err
+		}
+		q.Where(inCategory(q.Arg(filter.CategoryID)))
+	}
+	if filter.Name != "" {
+		q.Where("name = " + q.Arg(filter.Name))
+	}
+	if filter.Currency != "" {
+		q.Where("currency = " + q.Arg(filter.Currency))
+	}
+	priceRange(&q, filter.MinPrice, filter.MaxPrice)
+	if filter.InStock {
+		q.Where("stock > 0")
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q.Where("created_at >= " + q.Arg(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q.Where("created_at < " + q.Arg(filter.CreatedBefore))
+	}
+
+	var total *int
+	if req.WithTotal {
+		var count int
+		if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM product"+q.Conditions(), q.Args()...).Scan(&count); err != nil {
+			return nil, err
+		}
+		total = &count
+	}
+
+	orderBy, err := req.Apply(&q, productSortFields, "id")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(ctx, "SELECT "+productColumns+" FROM product"+q.Conditions()+orderBy, q.Args()...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	var products []*Product
-	for rows.Next() {
-		product := new(Product)
-		err = rows.Scan(
-			&product.ID,
-			&product.Name,
-			&product.Price,
-			&product.Stock,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
+	var product Product
+	var ids []int
+	_, err = pgx.ForEachRow(rows, productFields(&product), func() error {
+		p := product
+		p.Currency = p.Price.Currency
+		products = append(products, &p)
+		ids = append(ids, p.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	breadcrumbs, err := s.productBreadcrumbs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		product.Categories = breadcrumbs[product.ID]
+	}
+
+	result := page.New(products, req, productKey)
+	result.Total = total
+	return result, nil
+}
+
+// priceRange adds the conditions selecting the products priced from min to max, in their currency.
+func priceRange(q *page.Query, min, max money.Money) {
+	if min.IsPositive() {
+		q.Where("currency = " + q.Arg(min.CurrencyCode()))
+		q.Where("price >= " + q.Arg(min))
+	}
+	if max.IsPositive() {
+		if !min.IsPositive() {
+			q.Where("currency = " + q.Arg(max.CurrencyCode()))
 		}
-		products = append(products, product)
+		q.Where("price <= " + q.Arg(max))
 	}
+}
+
+// inCategory returns the condition selecting the products of the category given by the
+// placeholder and of all its descendants.
+func inCategory(id string) string {
+	return "id IN (SELECT product_id FROM product_category WHERE category_id IN (" + categorySubtree(id) + "))"
+}
 
-	return products, nil
+// productKey returns the cursor key of a product for the given sort field.
+func productKey(product *Product, sort string) (string, int) {
+	switch sort {
+	case "name":
+		return product.Name, product.ID
+	case "price":
+		return product.Price.String(), product.ID
+	case "created_at":
+		return product.CreatedAt.Format(time.RFC3339Nano), product.ID
+	default:
+		return "", product.ID
+	}
 }
 
+// UpdateProduct updates the product with the given id alongside a ProductUpdated event. The
+// prices and stock of a product without options are those of its single SKU, they are updated
+// together with a ProductStockChanged event if the stock changed. The stock of a product with
+// options is the sum of the stock of its SKUs and is left as is, returns ErrCurrencyChanged if
+// its currency changes and ErrPricesWithOptions if it has prices.
 func (s *Store) UpdateProduct(ctx context.Context, product Product) error {
-	_, err := s.db.Exec(ctx, "UPDATE product SET name = $2, price = $3, stock = $4 WHERE id = $1", product.ID, product.Name, product.Price, product.Stock)
-	return err
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	product.Currency = product.Price.CurrencyCode()
+	product.Prices = productPrices(product.Prices)
+
+	var previousStock int
+	var previousCurrency string
+	var options []string
+	err = tx.QueryRow(ctx, "UPDATE product p SET name = $2, description = $3, currency = $4, price = $5, prices = $6, stock = CASE WHEN cardinality(previous.options) = 0 THEN $7 ELSE previous.stock END FROM (SELECT id, currency, stock, options FROM product WHERE id = $1 FOR UPDATE) previous WHERE p.id = previous.id RETURNING previous.currency, previous.stock, previous.options", product.ID, product.Name, product.Description, product.Currency, product.Price, product.Prices, product.Stock).Scan(&previousCurrency, &previousStock, &options)
+	if err != nil {
+		return shared.DBError(err, "product")
+	}
+	if len(options) > 0 && previousCurrency != product.Currency {
+		return ErrCurrencyChanged
+	}
+	if len(options) > 0 && len(product.Prices) > 0 {
+		return ErrPricesWithOptions
+	}
+
+	var skuID int
+	if len(options) == 0 {
+		err := tx.QueryRow(ctx, "UPDATE sku SET price = $2, prices = $3, stock = $4 WHERE product_id = $1 AND options = '{}' RETURNING id", product.ID, product.Price, product.Prices, product.Stock).Scan(&skuID)
+		if err != nil {
+			return shared.DBError(err, "product")
+		}
+	} else {
+		product.Stock = previousStock
+	}
+
+	if err := outbox.Add(ctx, tx, events.AggregateProduct, product.ID, events.ProductUpdated, events.ProductPayload{
+		ProductID:   product.ID,
+		Name:        product.Name,
+		Description: product.Description,
+		Currency:    product.Currency,
+		Price:       product.Price,
+		Prices:      product.Prices,
+		Stock:       product.Stock,
+	}); err != nil {
+		return err
+	}
+	if product.Stock != previousStock {
+		if err := addStockChanged(ctx, tx, product.ID, skuID, product.Stock, product.Stock-previousStock, events.StockSet, 0); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
+// UpdateProductStock sets the stock of the single SKU of the product with the given id.
+// Returns ErrSKURequired if the product has options, their stock is set with UpdateSKUStock.
 func (s *Store) UpdateProductStock(ctx context.Context, id, stock int) error {
-	_, err := s.db.Exec(ctx, "UPDATE product SET stock = $2 WHERE id = $1", id, stock)
-	return err
+	sku, err := s.RetrieveDefaultSKU(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.UpdateSKUStock(ctx, sku.ID, stock)
+}
+
+// ReserveSKUStock takes quantity units of the SKU stock for the given order.
+// Reservations are idempotent per order and SKU, reserving again is a no-op.
+// Returns ErrInsufficientStock if the stock would go negative and a not found error wrapping
+// pgx.ErrNoRows if the SKU does not exist.
+func (s *Store) ReserveSKUStock(ctx context.Context, orderID, skuID, quantity int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	productID, err := lockSKUProduct(ctx, tx, skuID)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, "INSERT INTO stock_reservation(order_id, sku_id, product_id, quantity) VALUES($1, $2, $3, $4) ON CONFLICT DO NOTHING", orderID, skuID, productID, quantity)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return tx.Commit(ctx)
+	}
+
+	tag, err = tx.Exec(ctx, "UPDATE sku SET stock = stock - $2 WHERE id = $1 AND stock >= $2", skuID, quantity)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInsufficientStock
+	}
+	stock, err := addProductStock(ctx, tx, productID, -quantity)
+	if err != nil {
+		return err
+	}
+	if err := addStockChanged(ctx, tx, productID, skuID, stock, -quantity, events.StockReserve, orderID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	stockUpdates.WithLabelValues(stockReserve).Inc()
+	stockUnits.WithLabelValues(stockReserve).Add(float64(quantity))
+
+	return nil
+}
+
+// ReleaseSKUStock gives back up to quantity units of the SKU stock reserved for the given order,
+// a zero quantity gives back everything that was not released yet.
+// A reservation never releases more than it took, so releasing everything is idempotent and
+// releasing a reservation that does not exist is a no-op, it is always safe to call as a compensation.
+func (s *Store) ReleaseSKUStock(ctx context.Context, orderID, skuID, quantity int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	released, err := releaseSKUStock(ctx, tx, orderID, skuID, quantity)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	countRelease(released)
+
+	return nil
+}
+
+// StockItem is a quantity of a SKU.
+type StockItem struct {
+	SKUID    int
+	Quantity int
+}
+
+// ReleaseRefundStock gives back the stock of the items of a refund of the given order, like
+// ReleaseSKUStock does for each of them, all at once. The refund is recorded with its stock so
+// releasing it again is a no-op.
+func (s *Store) ReleaseRefundStock(ctx context.Context, refundID, orderID int, items []StockItem) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, "INSERT INTO refund_restock(refund_id, order_id) VALUES($1, $2) ON CONFLICT DO NOTHING", refundID, orderID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	items = slices.Clone(items)
+	slices.SortFunc(items, func(a, b StockItem) int {
+		return a.SKUID - b.SKUID
+	})
+	// The products of the items are locked in id order before any of their SKUs, like lockSKUProduct does for a single SKU.
+	skuIDs := make([]int, len(items))
+	for i, item := range items {
+		skuIDs[i] = item.SKUID
+	}
+	if _, err := tx.Exec(ctx, "SELECT id FROM product WHERE id IN (SELECT product_id FROM sku WHERE id = ANY($1)) ORDER BY id FOR UPDATE", skuIDs); err != nil {
+		return err
+	}
+	released := make([]int, 0, len(items))
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			continue
+		}
+		n, err := releaseSKUStock(ctx, tx, orderID, item.SKUID, item.Quantity)
+		if err != nil {
+			return fmt.Errorf("sku %d: %w", item.SKUID, err)
+		}
+		released = append(released, n)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	for _, n := range released {
+		countRelease(n)
+	}
+
+	return nil
+}
+
+// releaseSKUStock releases stock of a reservation within tx like ReleaseSKUStock and returns how
+// many units it gave back.
+func releaseSKUStock(ctx context.Context, tx pgx.Tx, orderID, skuID, quantity int) (int, error) {
+	productID, err := lockSKUProduct(ctx, tx, skuID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var reserved, released int
+	err = tx.QueryRow(ctx, "SELECT quantity, released FROM stock_reservation WHERE order_id = $1 AND sku_id = $2 FOR UPDATE", orderID, skuID).Scan(&reserved, &released)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	release := reserved - released
+	if quantity > 0 && quantity < release {
+		release = quantity
+	}
+	if release == 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE stock_reservation SET released = released + $3 WHERE order_id = $1 AND sku_id = $2", orderID, skuID, release); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, "UPDATE sku SET stock = stock + $2 WHERE id = $1", skuID, release); err != nil {
+		return 0, err
+	}
+	stock, err := addProductStock(ctx, tx, productID, release)
+	if err != nil {
+		return 0, err
+	}
+	if err := addStockChanged(ctx, tx, productID, skuID, stock, release, events.StockRelease, orderID); err != nil {
+		return 0, err
+	}
+
+	return release, nil
+}
+
+// countRelease records a release of the given units in the stock metrics, releases of nothing are not counted.
+func countRelease(units int) {
+	if units == 0 {
+		return
+	}
+	stockUpdates.WithLabelValues(stockRelease).Inc()
+	stockUnits.WithLabelValues(stockRelease).Add(float64(units))
+}
+
+// ReleaseOrderStock gives back everything that is still reserved for the given order, like
+// ReleaseSKUStock does for each of its SKUs.
+func (s *Store) ReleaseOrderStock(ctx context.Context, orderID int) error {
+	rows, err := s.db.Query(ctx, "SELECT sku_id FROM stock_reservation WHERE order_id = $1 AND released < quantity ORDER BY sku_id", orderID)
+	if err != nil {
+		return err
+	}
+	skuIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	for _, skuID := range skuIDs {
+		if err := s.ReleaseSKUStock(ctx, orderID, skuID, 0); err != nil {
+			return fmt.Errorf("sku %d: %w", skuID, err)
+		}
+	}
+
+	return nil
+}
+
+// lockSKUProduct locks the product of the SKU with the given id within tx and returns its id.
+// Transactions changing a product and its SKUs lock the product first, then the SKUs, so they wait
+// for each other instead of deadlocking. Returns a not found error wrapping pgx.ErrNoRows if the
+// SKU does not exist.
+func lockSKUProduct(ctx context.Context, tx pgx.Tx, skuID int) (int, error) {
+	var productID int
+	err := tx.QueryRow(ctx, "SELECT id FROM product WHERE id = (SELECT product_id FROM sku WHERE id = $1) FOR UPDATE", skuID).Scan(&productID)
+	if err != nil {
+		return 0, shared.DBError(err, "sku")
+	}
+
+	return productID, nil
+}
+
+// addProductStock adds delta to the stock of a product within tx and returns its new stock. The
+// stock of a product is the sum of the stock of its SKUs, adding the delta of a SKU keeps it so
+// when several SKUs of the product change concurrently.
+func addProductStock(ctx context.Context, tx pgx.Tx, productID, delta int) (int, error) {
+	var stock int
+	err := tx.QueryRow(ctx, "UPDATE product SET stock = stock + $2 WHERE id = $1 RETURNING stock", productID, delta).Scan(&stock)
+
+	return stock, err
+}
+
+// addStockChanged writes a ProductStockChanged event within tx.
+func addStockChanged(ctx context.Context, tx pgx.Tx, productID, skuID, stock, delta int, reason string, orderID int) error {
+	return outbox.Add(ctx, tx, events.AggregateProduct, productID, events.ProductStockChanged, events.ProductStockChangedPayload{
+		ProductID: productID,
+		SKUID:     skuID,
+		Stock:     stock,
+		Delta:     delta,
+		Reason:    reason,
+		OrderID:   orderID,
+	})
 }
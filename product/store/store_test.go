@@ -2,18 +2,23 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
-	"github.com/PseudoMera/virtual-store/shared"
-)
+	"github.com/PseudoMera/virtual-store/product/migrations"
 
-const (
-	projectRootPath = "../../"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/money"
+	"github.com/PseudoMera/virtual-store/shared/page"
+	"github.com/jackc/pgx/v5"
 )
 
 func TestProductStore(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -22,7 +27,7 @@ func TestProductStore(t *testing.T) {
 
 	product := Product{
 		Name:  "product",
-		Price: 22.5,
+		Price: money.MustParse("22.5", ""),
 		Stock: 2,
 	}
 	id, err := store.StoreProduct(ctx, product)
@@ -43,20 +48,20 @@ func TestProductStore(t *testing.T) {
 		t.Fatalf("wanted %d, got %d", id, retrievedProduct.ID)
 	}
 
-	products, err := store.RetrieveProducts(ctx, "product")
+	products, err := store.RetrieveProducts(ctx, ProductFilter{Name: "product"}, page.Request{})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if products[0].ID != id {
-		t.Fatalf("wanted %d, got %d", id, products[0].ID)
+	if products.Items[0].ID != id {
+		t.Fatalf("wanted %d, got %d", id, products.Items[0].ID)
 	}
 
 	if err = store.UpdateProduct(ctx, Product{
 		ID:    id,
 		Name:  "product2",
 		Stock: 3,
-		Price: 120,
+		Price: money.MustParse("120", ""),
 	}); err != nil {
 		t.Fatal(err)
 	}
@@ -65,3 +70,220 @@ func TestProductStore(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestRetrieveProductsPages(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	prices := []string{"30", "10", "20", "10", "50"}
+	for i, price := range prices {
+		stock := 1
+		if i == 4 {
+			stock = 0
+		}
+		if _, err := store.StoreProduct(ctx, Product{
+			Name:  fmt.Sprintf("product%d", i),
+			Price: money.MustParse(price, ""),
+			Stock: stock,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Prices in other currencies are not compared with them.
+	if _, err := store.StoreProduct(ctx, Product{
+		Name:  "product5",
+		Price: money.MustParse("15", "JPY"),
+		Stock: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.RetrieveProducts(ctx, ProductFilter{}, page.Request{Sort: "price"}); !errors.Is(err, page.ErrSortCurrency) {
+		t.Fatalf("wanted %v, got %v", page.ErrSortCurrency, err)
+	}
+
+	// Walking the pages sorted by price visits every product once, ties broken by id.
+	wanted := []int{2, 4, 3, 1, 5}
+	var got []int
+	req := page.Request{Limit: 2, Sort: "price", WithTotal: true}
+	for {
+		products, err := store.RetrieveProducts(ctx, ProductFilter{Currency: money.DefaultCurrency}, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if products.Total == nil || *products.Total != len(prices) {
+			t.Fatalf("wanted a total of %d, got %v", len(prices), products.Total)
+		}
+		for _, product := range products.Items {
+			got = append(got, product.ID)
+		}
+		if products.NextCursor == "" {
+			break
+		}
+		req.Cursor = products.NextCursor
+	}
+	if fmt.Sprint(got) != fmt.Sprint(wanted) {
+		t.Fatalf("wanted %v, got %v", wanted, got)
+	}
+
+	products, err := store.RetrieveProducts(ctx, ProductFilter{MinPrice: money.MustParse("15", ""), InStock: true}, page.Request{Sort: "price", Desc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products.Items) != 2 || products.Items[0].ID != 1 {
+		t.Fatalf("wanted products %v, got %d products", []int{1, 3}, len(products.Items))
+	}
+
+	if _, err := store.RetrieveProducts(ctx, ProductFilter{}, page.Request{Sort: "name", Cursor: req.Cursor}); !errors.Is(err, page.ErrInvalidCursor) {
+		t.Fatalf("wanted %v, got %v", page.ErrInvalidCursor, err)
+	}
+	if _, err := store.RetrieveProducts(ctx, ProductFilter{}, page.Request{Sort: "stock"}); !errors.Is(err, page.ErrInvalidSort) {
+		t.Fatalf("wanted %v, got %v", page.ErrInvalidSort, err)
+	}
+}
+
+func TestStockReservation(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	id, err := store.StoreProduct(ctx, Product{
+		Name:  "product",
+		Price: money.MustParse("10", ""),
+		Stock: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sku, err := store.RetrieveDefaultSKU(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStock := func(want int) {
+		t.Helper()
+		product, err := store.RetrieveProduct(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if product.Stock != want {
+			t.Fatalf("wanted %d, got %d", want, product.Stock)
+		}
+	}
+
+	if err := store.ReserveSKUStock(ctx, 1, sku.ID, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReserveSKUStock(ctx, 1, sku.ID, 3); err != nil {
+		t.Fatal(err)
+	}
+	assertStock(2)
+
+	if err := store.ReserveSKUStock(ctx, 2, sku.ID, 3); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("wanted %v, got %v", ErrInsufficientStock, err)
+	}
+	assertStock(2)
+
+	if err := store.ReserveSKUStock(ctx, 3, sku.ID+100, 1); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+
+	if err := store.ReleaseSKUStock(ctx, 1, sku.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	assertStock(3)
+
+	if err := store.ReleaseSKUStock(ctx, 1, sku.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReleaseSKUStock(ctx, 1, sku.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReleaseSKUStock(ctx, 1, sku.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ReleaseSKUStock(ctx, 2, sku.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	assertStock(5)
+
+	// The stock of a refund is released once.
+	if err := store.ReserveSKUStock(ctx, 4, sku.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.ReleaseRefundStock(ctx, 1, 4, []StockItem{{SKUID: sku.ID, Quantity: 1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertStock(4)
+}
+
+func TestConcurrentProductAndStockUpdates(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	product := Product{
+		Name:  "product",
+		Price: money.MustParse("10", ""),
+		Stock: 1000,
+	}
+	product.ID, err = store.StoreProduct(ctx, product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sku, err := store.RetrieveDefaultSKU(ctx, product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Updating a product and reserving or releasing the stock of its SKU lock the rows in the
+	// same order, none of them can fail with a deadlock.
+	var wg sync.WaitGroup
+	errs := make(chan error, 60)
+	for i := 1; i <= 20; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			errs <- store.UpdateProduct(ctx, product)
+		}()
+		go func(orderID int) {
+			defer wg.Done()
+			errs <- store.ReserveSKUStock(ctx, orderID, sku.ID, 1)
+		}(i)
+		go func(orderID int) {
+			defer wg.Done()
+			errs <- store.ReleaseSKUStock(ctx, orderID, sku.ID, 0)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestValidateDescription(t *testing.T) {
+	// The limit counts characters, not bytes.
+	if err := ValidateDescription(strings.Repeat("é", MaxDescriptionLength)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateDescription(strings.Repeat("a", MaxDescriptionLength+1)); !errors.Is(err, ErrLongDescription) {
+		t.Fatalf("wanted %v, got %v", ErrLongDescription, err)
+	}
+}
@@ -2,20 +2,34 @@ package shared
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
+// ErrorResponse is the body of every error response.
+// Code and Fields are only set for domain errors, see Error.
 type ErrorResponse struct {
-	Error   bool   `json:"error"`
-	Message string `json:"message"`
+	Error   bool              `json:"error"`
+	Message string            `json:"message"`
+	Code    ErrorKind         `json:"code,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
 }
 
+// WriteErrorResponse writes e as an ErrorResponse with the given status code.
+// Prefer WriteError for the errors returned by services.
 func WriteErrorResponse(w http.ResponseWriter, e error, statusCode int) {
-	w.WriteHeader(statusCode)
-	if err := json.NewEncoder(w).Encode(ErrorResponse{
+	resp := ErrorResponse{
 		Error:   true,
 		Message: e.Error(),
-	}); err != nil {
+	}
+	var domainErr *Error
+	if errors.As(e, &domainErr) {
+		resp.Code = domainErr.Kind
+		resp.Fields = domainErr.Fields
+	}
+
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		http.Error(w, e.Error(), statusCode)
 	}
 }
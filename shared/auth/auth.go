@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const issuer = "virtual-store/user"
+
+var (
+	// ErrUnauthenticated is returned when the request carries no valid access token.
+	ErrUnauthenticated = shared.UnauthorizedError("missing or invalid access token")
+	// ErrForbidden is returned when the caller is authenticated but not allowed to perform the operation.
+	ErrForbidden = shared.ForbiddenError("not allowed to perform this operation")
+
+	errUnexpectedTokenType = errors.New("unexpected token type")
+)
+
+// Permissions granted to roles in the role_permission table.
+// They are embedded in access tokens so every service can check them without a database lookup.
+const (
+	PermissionProductWrite = "product:write"
+	PermissionProductStock = "product:stock"
+	PermissionOrderRead    = "order:read"
+	PermissionOrderStatus  = "order:status"
+	PermissionOrderWrite   = "order:write"
+	PermissionOrderRefund  = "order:refund"
+	PermissionUserRole     = "user:role"
+	PermissionUserRead     = "user:read"
+	PermissionEventsReplay = "events:replay"
+	PermissionRatesWrite   = "rates:write"
+)
+
+type TokenType string
+
+var (
+	AccessToken  TokenType = "access"
+	RefreshToken TokenType = "refresh"
+)
+
+// Claims are the JWT claims of both access and refresh tokens.
+// The subject holds the user ID and the ID holds the token ID.
+type Claims struct {
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	Permissions []string  `json:"permissions,omitempty"`
+	Type        TokenType `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the user ID stored in the subject of the claims.
+func (c *Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	UserID      int
+	Email       string
+	Role        string
+	Permissions []string
+}
+
+// HasPermission reports whether the identity was granted the given permission.
+func (i Identity) HasPermission(permission string) bool {
+	return slices.Contains(i.Permissions, permission)
+}
+
+// Authenticator issues and validates the tokens shared by every service.
+// All services must be configured with the same secret.
+type Authenticator struct {
+	secret []byte
+}
+
+// NewAuthenticator returns an Authenticator that signs and verifies tokens with the given secret.
+func NewAuthenticator(secret []byte) *Authenticator {
+	return &Authenticator{
+		secret: secret,
+	}
+}
+
+// IssueToken returns a signed token of the given type for the identity alongside its claims.
+func (a *Authenticator) IssueToken(identity Identity, tokenType TokenType, now time.Time, ttl time.Duration) (string, *Claims, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims := &Claims{
+		Email:       identity.Email,
+		Role:        identity.Role,
+		Permissions: identity.Permissions,
+		Type:        tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Issuer:    issuer,
+			Subject:   strconv.Itoa(identity.UserID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return signed, claims, nil
+}
+
+// ParseToken validates the signature and expiration of the token and makes sure it has the expected type.
+func (a *Authenticator) ParseToken(tokenString string, tokenType TokenType) (*Claims, error) {
+	claims := new(Claims)
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(issuer))
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != tokenType {
+		return nil, errUnexpectedTokenType
+	}
+
+	return claims, nil
+}
+
+// Authenticate validates a bearer access token and returns the identity it belongs to.
+func (a *Authenticator) Authenticate(bearer string) (Identity, error) {
+	token, ok := strings.CutPrefix(bearer, "Bearer ")
+	if !ok || token == "" {
+		return Identity{}, ErrUnauthenticated
+	}
+
+	claims, err := a.ParseToken(token, AccessToken)
+	if err != nil {
+		return Identity{}, ErrUnauthenticated
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return Identity{}, ErrUnauthenticated
+	}
+
+	return Identity{
+		UserID:      userID,
+		Email:       claims.Email,
+		Role:        claims.Role,
+		Permissions: claims.Permissions,
+	}, nil
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying the given identity.
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity stored in ctx by the middleware or interceptors, if any.
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// Authorize checks that the caller stored in ctx is the given user or holds any of the given permissions.
+// Returns ErrUnauthenticated if there is no caller and ErrForbidden if it is someone else.
+func Authorize(ctx context.Context, userID int, permissions ...string) error {
+	identity, ok := FromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if identity.UserID != 0 && identity.UserID == userID {
+		return nil
+	}
+	for _, permission := range permissions {
+		if identity.HasPermission(permission) {
+			return nil
+		}
+	}
+
+	return ErrForbidden
+}
+
+// CheckPermission checks that the caller stored in ctx holds the given permission.
+// It can be used from both chi handlers and gRPC servers.
+func CheckPermission(ctx context.Context, permission string) error {
+	identity, ok := FromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if !identity.HasPermission(permission) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testUserID = 7
+	testEmail  = "test@test.test"
+)
+
+var testIdentity = Identity{
+	UserID:      testUserID,
+	Email:       testEmail,
+	Role:        "staff",
+	Permissions: []string{PermissionProductStock},
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := NewAuthenticator([]byte("secret"))
+
+	token, _, err := a.IssueToken(testIdentity, AccessToken, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := a.Authenticate("Bearer " + token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != testUserID {
+		t.Fatalf("wanted %d, got %d", testUserID, identity.UserID)
+	}
+
+	if _, err := a.Authenticate(token); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("wanted %v, got %v", ErrUnauthenticated, err)
+	}
+
+	refresh, _, err := a.IssueToken(testIdentity, RefreshToken, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate("Bearer " + refresh); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("wanted %v, got %v", ErrUnauthenticated, err)
+	}
+
+	expired, _, err := a.IssueToken(testIdentity, AccessToken, time.Now().Add(-time.Hour), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate("Bearer " + expired); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("wanted %v, got %v", ErrUnauthenticated, err)
+	}
+
+	if _, err := NewAuthenticator([]byte("other")).Authenticate("Bearer " + token); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("wanted %v, got %v", ErrUnauthenticated, err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a := NewAuthenticator([]byte("secret"))
+	token, _, err := a.IssueToken(testIdentity, AccessToken, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := Authorize(r.Context(), testUserID); err != nil {
+			t.Fatal(err)
+		}
+		if err := Authorize(r.Context(), testUserID+1); !errors.Is(err, ErrForbidden) {
+			t.Fatalf("wanted %v, got %v", ErrForbidden, err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wanted %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("wanted %d, got %d", http.StatusNoContent, rec.Code)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	a := NewAuthenticator([]byte("secret"))
+	token, _, err := a.IssueToken(testIdentity, AccessToken, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interceptor := a.UnaryServerInterceptor("/test.Service/Public")
+	handler := func(ctx context.Context, req any) (any, error) {
+		identity, _ := FromContext(ctx)
+		return identity.UserID, nil
+	}
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Private"}, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("wanted %s, got %s", codes.Unauthenticated, status.Code(err))
+	}
+
+	if _, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Public"}, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	userID, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Private"}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != testUserID {
+		t.Fatalf("wanted %d, got %v", testUserID, userID)
+	}
+}
+
+func TestCheckPermission(t *testing.T) {
+	ctx := NewContext(context.Background(), testIdentity)
+
+	if err := CheckPermission(ctx, PermissionProductStock); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPermission(ctx, PermissionProductWrite); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("wanted %v, got %v", ErrForbidden, err)
+	}
+	if err := CheckPermission(context.Background(), PermissionProductStock); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("wanted %v, got %v", ErrUnauthenticated, err)
+	}
+
+	if err := Authorize(ctx, testUserID+1, PermissionOrderRead); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("wanted %v, got %v", ErrForbidden, err)
+	}
+	if err := Authorize(ctx, testUserID+1, PermissionOrderRead, PermissionProductStock); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	serviceRole     = "service"
+	serviceTokenTTL = 5 * time.Minute
+)
+
+// ServiceCredentials authenticate calls made by one service to another.
+// It implements credentials.PerRPCCredentials and signs short lived access tokens
+// holding only the given permissions, renewing them before they expire.
+type ServiceCredentials struct {
+	authenticator *Authenticator
+	identity      Identity
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// ServiceCredentials returns per RPC credentials for the named service.
+// Service identities have no user ID, so they never pass an ownership check in Authorize.
+func (a *Authenticator) ServiceCredentials(name string, permissions ...string) *ServiceCredentials {
+	return &ServiceCredentials{
+		authenticator: a,
+		identity: Identity{
+			Email:       name,
+			Role:        serviceRole,
+			Permissions: permissions,
+		},
+	}
+}
+
+// GetRequestMetadata returns the authorization metadata attached to every call.
+func (c *ServiceCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.token == "" || now.Add(serviceTokenTTL/5).After(c.expiresAt) {
+		token, claims, err := c.authenticator.IssueToken(c.identity, AccessToken, now, serviceTokenTTL)
+		if err != nil {
+			return nil, err
+		}
+		c.token = token
+		c.expiresAt = claims.ExpiresAt.Time
+	}
+
+	return map[string]string{
+		authorizationMetadataKey: "Bearer " + c.token,
+	}, nil
+}
+
+// RequireTransportSecurity allows the credentials on the plain text connections used inside the cluster.
+func (c *ServiceCredentials) RequireTransportSecurity() bool {
+	return false
+}
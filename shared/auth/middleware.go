@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/PseudoMera/virtual-store/shared"
+)
+
+// Middleware is a chi middleware that rejects requests without a valid bearer access token
+// and stores the caller identity in the request context.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := a.Authenticate(r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			shared.WriteError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), identity)))
+	})
+}
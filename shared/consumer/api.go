@@ -0,0 +1,49 @@
+package consumer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+)
+
+// GetDeadLetters lists the dead letters that were not replayed yet. The caller must hold the events:replay permission.
+func (c *Consumer) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if err := auth.CheckPermission(r.Context(), auth.PermissionEventsReplay); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	deadLetters, err := c.DeadLetters(r.Context())
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, deadLetters, w)
+}
+
+type ReplayDeadLetterRequest struct {
+	ID int64 `json:"id"`
+}
+
+// ReplayDeadLetter replays a dead letter. The caller must hold the events:replay permission.
+func (c *Consumer) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	var req ReplayDeadLetterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := auth.CheckPermission(r.Context(), auth.PermissionEventsReplay); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := c.Replay(r.Context(), req.ID); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,293 @@
+// Package consumer implements the consuming side of the domain events: a Consumer reads the event
+// log of the event bus from its own cursor and hands the events to the handlers registered for
+// their type. Each event is processed at most once thanks to an inbox table, failing handlers are
+// retried with backoff and the events they keep failing on are moved to a dead letter table, from
+// which they can be replayed.
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+	// inboxRetention is how long processed events are remembered, it outlives the retention
+	// of the published events in the outbox so a redelivery is always recognized.
+	inboxRetention = 14 * 24 * time.Hour
+	logBatchSize   = 100
+	// pollInterval is how often the event log is read when no notification arrives, in case one was missed.
+	pollInterval = 10 * time.Second
+)
+
+// Handler reacts to an event. It may be called again with the same event if it fails or if the
+// consumer stops before recording the event as processed, so it must be idempotent.
+type Handler func(ctx context.Context, event events.Event) error
+
+// RetryPolicy configures how a failing handler is retried. Attempt n waits InitialBackoff * 2^(n-1),
+// up to MaxBackoff, before the next one. Zero values take the defaults.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, p.MaxBackoff)
+}
+
+// Consumer processes the events of a service, recording them in the inbox and dead_letter tables of its database.
+type Consumer struct {
+	db *pgxpool.Pool
+	// name identifies the cursor of the consumer in the event log, the instances of a service share it.
+	name     string
+	retry    RetryPolicy
+	handlers map[string]Handler
+	logger   *slog.Logger
+}
+
+// NewConsumer returns a Consumer without handlers reading the event log with the cursor of the
+// given name, usually the name of the service, and storing its inbox and dead letters in db.
+func NewConsumer(db *pgxpool.Pool, name string, retry RetryPolicy, logger *slog.Logger) *Consumer {
+	if retry.MaxAttempts == 0 {
+		retry.MaxAttempts = defaultMaxAttempts
+	}
+	if retry.InitialBackoff == 0 {
+		retry.InitialBackoff = defaultInitialBackoff
+	}
+	if retry.MaxBackoff == 0 {
+		retry.MaxBackoff = defaultMaxBackoff
+	}
+
+	return &Consumer{
+		db:       db,
+		name:     name,
+		retry:    retry,
+		handlers: make(map[string]Handler),
+		logger:   logger,
+	}
+}
+
+// Handle registers the handler of the given event type, replacing any previous one.
+// Handlers must be registered before the consumer starts.
+func (c *Consumer) Handle(eventType string, handler Handler) {
+	c.handlers[eventType] = handler
+}
+
+// Consume hands the event to the handler of its type, events without a handler are ignored.
+// A failing handler is retried following the retry policy, once the attempts are exhausted the
+// event is moved to the dead letters. Consume only fails if the event could not be dead lettered
+// or ctx was done while waiting to retry.
+func (c *Consumer) Consume(ctx context.Context, event events.Event) error {
+	handler, ok := c.handlers[event.Type]
+	if !ok {
+		return nil
+	}
+
+	attempt := 1
+	for {
+		err := c.process(ctx, event, handler)
+		if err == nil {
+			return nil
+		}
+		if attempt == c.retry.MaxAttempts {
+			c.logger.ErrorContext(ctx, "error at Consume", slog.String("event", event.ID), slog.Int("attempts", attempt), slog.String("error", err.Error()))
+			return c.storeDeadLetter(ctx, event, attempt, err)
+		}
+		c.logger.InfoContext(ctx, "error at Consume", slog.String("event", event.ID), slog.Int("attempt", attempt), slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.retry.backoff(attempt)):
+		}
+		attempt++
+	}
+}
+
+// process runs the handler unless the event is already in the inbox. The inbox row is inserted
+// before calling the handler and committed after it succeeds, so concurrent consumers of the
+// same database wait for each other instead of processing the event twice.
+func (c *Consumer) process(ctx context.Context, event events.Event, handler Handler) error {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, "INSERT INTO inbox(event_id, event_type, source) VALUES($1, $2, $3) ON CONFLICT DO NOTHING", event.ID, event.Type, event.Source)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	if err := handler(ctx, event); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ConsumeLog consumes a batch of the events of the log of the bus database that follow the cursor
+// of the consumer, in the order they were published, and moves the cursor past them. It returns how
+// many events were read, it stops at the first event Consume fails on so it is read again next time.
+// The cursor stays locked while the batch is consumed, another instance of the consumer reading
+// the log makes it a no-op.
+func (c *Consumer) ConsumeLog(ctx context.Context, bus *pgxpool.Pool) (int, error) {
+	tx, err := bus.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if _, err := tx.Exec(ctx, "INSERT INTO consumer_cursor(consumer) VALUES($1) ON CONFLICT DO NOTHING", c.name); err != nil {
+		return 0, err
+	}
+	var position int64
+	err = tx.QueryRow(ctx, "SELECT position FROM consumer_cursor WHERE consumer = $1 FOR UPDATE SKIP LOCKED", c.name).Scan(&position)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT id, event FROM event_log WHERE id > $1 ORDER BY id LIMIT $2", position, logBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	type loggedEvent struct {
+		id   int64
+		data []byte
+	}
+	var logged []loggedEvent
+	var l loggedEvent
+	_, err = pgx.ForEachRow(rows, []any{&l.id, &l.data}, func() error {
+		logged = append(logged, l)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	read := 0
+	var consumeErr error
+	for _, l := range logged {
+		var event events.Event
+		if err := json.Unmarshal(l.data, &event); err != nil {
+			c.logger.ErrorContext(ctx, "error at ConsumeLog", slog.Int64("position", l.id), slog.String("error", err.Error()))
+		} else if err := c.Consume(ctx, event); err != nil {
+			consumeErr = fmt.Errorf("event %s: %w", event.ID, err)
+			break
+		}
+		position = l.id
+		read++
+	}
+
+	if read > 0 {
+		if _, err := tx.Exec(ctx, "UPDATE consumer_cursor SET position = $2, updated_at = CURRENT_TIMESTAMP WHERE consumer = $1", c.name, position); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return read, consumeErr
+}
+
+// Listen consumes the event log of the bus database until ctx is done or the connection fails.
+// The log is read when it starts, whenever a notification on channel announces new events and
+// every pollInterval, so the events published while the consumer was stopped or whose
+// notification was missed are consumed too.
+func (c *Consumer) Listen(ctx context.Context, bus *pgxpool.Pool, channel string) error {
+	conn, err := bus.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection is closed instead of going back to the pool, it is still listening.
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background()) //nolint:errcheck
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+
+	for {
+		for {
+			read, err := c.ConsumeLog(ctx, bus)
+			if err != nil {
+				c.logger.ErrorContext(ctx, "error at Listen", slog.String("error", err.Error()))
+			}
+			if err != nil || read < logBatchSize {
+				break
+			}
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, pollInterval)
+		_, err := pgConn.WaitForNotification(waitCtx)
+		cancel()
+		if err != nil && (ctx.Err() != nil || !errors.Is(err, context.DeadlineExceeded)) {
+			return err
+		}
+	}
+}
+
+// Run consumes the event log until ctx is done, listening again after interval when the connection fails.
+func (c *Consumer) Run(ctx context.Context, bus *pgxpool.Pool, channel string, interval time.Duration) {
+	for {
+		err := c.Listen(ctx, bus, channel)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			c.logger.ErrorContext(ctx, "error at Run", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// DeleteProcessed removes the inbox entries older than inboxRetention and returns how many were removed.
+func (c *Consumer) DeleteProcessed(ctx context.Context) (int64, error) {
+	tag, err := c.db.Exec(ctx, "DELETE FROM inbox WHERE processed_at < CURRENT_TIMESTAMP - make_interval(secs => $1)", inboxRetention.Seconds())
+	return tag.RowsAffected(), err
+}
+
+// RunInboxExpiry removes the old inbox entries every interval until ctx is done.
+func (c *Consumer) RunInboxExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.DeleteProcessed(ctx); err != nil {
+				c.logger.ErrorContext(ctx, "error at RunInboxExpiry", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/order/migrations"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	eventmigrations "github.com/PseudoMera/virtual-store/shared/events/migrations"
+	"github.com/PseudoMera/virtual-store/shared/outbox"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+
+	tests := map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	}
+	for attempt, wanted := range tests {
+		if got := policy.backoff(attempt); got != wanted {
+			t.Fatalf("wanted %s, got %s", wanted, got)
+		}
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	ctx := context.Background()
+	// The inbox and dead_letter tables are created by the migrations of every consuming service.
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumer := NewConsumer(db.DB(), "order", RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}, slog.Default())
+
+	calls := 0
+	failures := 2
+	consumer.Handle(events.UserDeleted, func(ctx context.Context, event events.Event) error {
+		calls++
+		if failures > 0 {
+			failures--
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+
+	event := events.Event{
+		ID:     "00000000-0000-0000-0000-000000000001",
+		Source: "user",
+		Type:   events.UserDeleted,
+	}
+	if err := consumer.Consume(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("wanted %d, got %d", 3, calls)
+	}
+
+	// A redelivered event was already processed.
+	if err := consumer.Consume(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("wanted %d, got %d", 3, calls)
+	}
+
+	// Events without a handler are ignored.
+	if err := consumer.Consume(ctx, events.Event{ID: "00000000-0000-0000-0000-000000000002", Type: events.UserCreated}); err != nil {
+		t.Fatal(err)
+	}
+
+	poison := events.Event{
+		ID:     "00000000-0000-0000-0000-000000000003",
+		Source: "user",
+		Type:   events.UserDeleted,
+	}
+	failures = 3
+	if err := consumer.Consume(ctx, poison); err != nil {
+		t.Fatal(err)
+	}
+
+	deadLetters, err := consumer.DeadLetters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deadLetters) != 1 {
+		t.Fatalf("wanted %d, got %d", 1, len(deadLetters))
+	}
+	if deadLetters[0].Event.ID != poison.ID || deadLetters[0].Attempts != 3 {
+		t.Fatalf("wanted event %s after %d attempts, got %+v", poison.ID, 3, deadLetters[0])
+	}
+
+	if err := consumer.Replay(ctx, deadLetters[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := consumer.Replay(ctx, deadLetters[0].ID); !errors.Is(err, ErrAlreadyReplayed) {
+		t.Fatalf("wanted %v, got %v", ErrAlreadyReplayed, err)
+	}
+	if err := consumer.Replay(ctx, deadLetters[0].ID+1); shared.HTTPStatus(err) != http.StatusNotFound {
+		t.Fatalf("wanted %d, got %d", http.StatusNotFound, shared.HTTPStatus(err))
+	}
+
+	deadLetters, err = consumer.DeadLetters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deadLetters) != 0 {
+		t.Fatalf("wanted %d, got %d", 0, len(deadLetters))
+	}
+}
+
+func TestConsumeLog(t *testing.T) {
+	ctx := context.Background()
+	dbs, _, err := shared.SetupPostgresClients(ctx, migrations.FS, eventmigrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, bus := dbs[0].DB(), dbs[1].DB()
+	publisher := outbox.NewLogPublisher(bus, outbox.Channel)
+
+	var handled []string
+	handler := func(ctx context.Context, event events.Event) error {
+		handled = append(handled, event.ID)
+		return nil
+	}
+	consumer := NewConsumer(db, "order", RetryPolicy{}, slog.Default())
+	consumer.Handle(events.UserDeleted, handler)
+
+	first := events.Event{ID: "00000000-0000-0000-0000-000000000001", Source: "user", Type: events.UserDeleted}
+	second := events.Event{ID: "00000000-0000-0000-0000-000000000002", Source: "user", Type: events.UserDeleted}
+	// The relay publishes an event again if it stops before marking it as published.
+	for _, event := range []events.Event{first, first, second} {
+		if err := publisher.Publish(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read, err := consumer.ConsumeLog(ctx, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != 2 || len(handled) != 2 || handled[0] != first.ID || handled[1] != second.ID {
+		t.Fatalf("wanted events %s and %s, got %d read and %v handled", first.ID, second.ID, read, handled)
+	}
+
+	// The cursor moved past the events that were read.
+	read, err = consumer.ConsumeLog(ctx, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != 0 {
+		t.Fatalf("wanted %d, got %d", 0, read)
+	}
+
+	// Events published before a consumer starts are read too.
+	handled = nil
+	late := NewConsumer(db, "product", RetryPolicy{}, slog.Default())
+	late.Handle(events.UserDeleted, handler)
+	read, err = late.ConsumeLog(ctx, bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read != 2 {
+		t.Fatalf("wanted %d, got %d", 2, read)
+	}
+	// Both consumers share the inbox of the test database, so the events were already processed.
+	if len(handled) != 0 {
+		t.Fatalf("wanted %d, got %d", 0, len(handled))
+	}
+}
@@ -0,0 +1,95 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrAlreadyReplayed is returned when replaying a dead letter that was already replayed successfully.
+	ErrAlreadyReplayed = shared.ConflictError("dead letter already replayed")
+	// ErrNoHandler is returned when replaying a dead letter whose event type has no handler anymore.
+	ErrNoHandler = shared.InvalidError("no handler for the event type of the dead letter")
+)
+
+// DeadLetter is an event the consumer kept failing on. Error is the last handler error and
+// ReplayedAt is set once a replay succeeded.
+type DeadLetter struct {
+	ID         int64
+	Event      events.Event
+	Error      string
+	Attempts   int
+	CreatedAt  time.Time
+	ReplayedAt *time.Time
+}
+
+func (c *Consumer) storeDeadLetter(ctx context.Context, event events.Event, attempts int, cause error) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(ctx, "INSERT INTO dead_letter(event_id, event_type, event, error, attempts) VALUES($1, $2, $3, $4, $5)", event.ID, event.Type, data, cause.Error(), attempts)
+	return err
+}
+
+// DeadLetters returns the dead letters that were not replayed yet, oldest first.
+func (c *Consumer) DeadLetters(ctx context.Context) ([]*DeadLetter, error) {
+	rows, err := c.db.Query(ctx, "SELECT id, event, error, attempts, created_at, replayed_at FROM dead_letter WHERE replayed_at IS NULL ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	var deadLetters []*DeadLetter
+	var data []byte
+	var dl DeadLetter
+	_, err = pgx.ForEachRow(rows, []any{&dl.ID, &data, &dl.Error, &dl.Attempts, &dl.CreatedAt, &dl.ReplayedAt}, func() error {
+		deadLetter := dl
+		if err := json.Unmarshal(data, &deadLetter.Event); err != nil {
+			return err
+		}
+		deadLetters = append(deadLetters, &deadLetter)
+		return nil
+	})
+
+	return deadLetters, err
+}
+
+// Replay hands the event of the given dead letter to its handler again, once, and marks the dead
+// letter as replayed if it succeeds. A failure is recorded on the dead letter and returned.
+func (c *Consumer) Replay(ctx context.Context, id int64) error {
+	var data []byte
+	var replayedAt *time.Time
+	err := c.db.QueryRow(ctx, "SELECT event, replayed_at FROM dead_letter WHERE id = $1", id).Scan(&data, &replayedAt)
+	if err != nil {
+		return shared.DBError(err, "dead letter")
+	}
+	if replayedAt != nil {
+		return ErrAlreadyReplayed
+	}
+
+	var event events.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+	handler, ok := c.handlers[event.Type]
+	if !ok {
+		return ErrNoHandler
+	}
+
+	if err := c.process(ctx, event, handler); err != nil {
+		if _, uerr := c.db.Exec(ctx, "UPDATE dead_letter SET error = $2, attempts = attempts + 1 WHERE id = $1", id, err.Error()); uerr != nil {
+			return uerr
+		}
+		return fmt.Errorf("dead letter %d: %w", id, err)
+	}
+
+	_, err = c.db.Exec(ctx, "UPDATE dead_letter SET replayed_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+	return err
+}
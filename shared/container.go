@@ -2,16 +2,30 @@ package shared
 
 import (
 	"context"
-	"os"
+	"fmt"
+	"io/fs"
+	"net/url"
 	"time"
 
+	"github.com/PseudoMera/virtual-store/shared/migrate"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
-// SetupPostgresClient creates a postgres container and reads all sql files from the schema directory to initialize a postgres database.
-func SetupPostgresClient(ctx context.Context, projectRootPath string) (*PostgresDB, *postgres.PostgresContainer, error) {
+// SetupPostgresClient creates a postgres container and applies the given migrations to initialize a postgres database.
+func SetupPostgresClient(ctx context.Context, migrations fs.FS) (*PostgresDB, *postgres.PostgresContainer, error) {
+	dbs, container, err := SetupPostgresClients(ctx, migrations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dbs[0], container, nil
+}
+
+// SetupPostgresClients creates a postgres container with a database per set of migrations, like the
+// services owning a database each, and returns the databases in the same order.
+func SetupPostgresClients(ctx context.Context, migrations ...fs.FS) ([]*PostgresDB, *postgres.PostgresContainer, error) {
 	container, err := postgres.RunContainer(ctx,
 		testcontainers.WithImage("docker.io/postgres:16.1-alpine"),
 		postgres.WithUsername("postgres"),
@@ -29,37 +43,39 @@ func SetupPostgresClient(ctx context.Context, projectRootPath string) (*Postgres
 	if err != nil {
 		return nil, nil, err
 	}
+	connectionURL, err := url.Parse(connectionString)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	db, err := NewPostgresDatabase(context.Background(), connectionString)
+	admin, err := NewPostgresDatabase(context.Background(), connectionString)
 	if err != nil {
 		return nil, nil, err
 	}
+	defer admin.Close()
 
-	schemas := []string{"schema/schema.sql", "schema/triggers.sql"}
+	dbs := make([]*PostgresDB, len(migrations))
+	for i, fsys := range migrations {
+		name := fmt.Sprintf("service_%d", i)
+		if _, err := admin.db.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+			return nil, nil, err
+		}
 
-	for _, schema := range schemas {
-		schemaSQLStatements, err := loadSQLFile(projectRootPath + schema)
+		connectionURL.Path = "/" + name
+		db, err := NewPostgresDatabase(context.Background(), connectionURL.String())
 		if err != nil {
 			return nil, nil, err
 		}
 
-		_, err = db.db.Exec(context.Background(), schemaSQLStatements)
+		migrator, err := migrate.NewMigrator(db.DB(), fsys, NewLogger())
 		if err != nil {
 			return nil, nil, err
 		}
+		if err := migrator.Up(ctx); err != nil {
+			return nil, nil, err
+		}
+		dbs[i] = db
 	}
 
-	return db, container, nil
-}
-
-// loadEntireSQLFile loads the entire file to execute as one big query
-// If not done this way we would have to implement a custom splitter
-func loadSQLFile(filePath string) (string, error) {
-	// Read the entire SQL file
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", err
-	}
-
-	return string(content), err
+	return dbs, container, nil
 }
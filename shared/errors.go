@@ -0,0 +1,253 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorKind classifies domain errors so every service maps them to the same HTTP status and gRPC code.
+type ErrorKind string
+
+const (
+	// KindInvalid is the kind of errors caused by a malformed or incomplete request.
+	KindInvalid ErrorKind = "invalid"
+	// KindNotFound is the kind of errors caused by a resource that does not exist.
+	KindNotFound ErrorKind = "not_found"
+	// KindConflict is the kind of errors caused by the current state of a resource, like a duplicate
+	// or an operation that is not allowed anymore.
+	KindConflict ErrorKind = "conflict"
+	// KindUnauthorized is the kind of errors caused by a missing or invalid access token.
+	KindUnauthorized ErrorKind = "unauthorized"
+	// KindForbidden is the kind of errors caused by a caller that is not allowed to perform the operation.
+	KindForbidden ErrorKind = "forbidden"
+	// KindUnavailable is the kind of errors caused by a dependency that is temporarily unavailable.
+	// They must only be returned when the operation failed before changing anything, so the same
+	// request can be sent again, idempotency keys are released for them.
+	KindUnavailable ErrorKind = "unavailable"
+	// KindInternal is the kind of unexpected errors, their details are never sent to clients.
+	KindInternal ErrorKind = "internal"
+)
+
+const (
+	// internalErrorMessage replaces the message of internal errors in responses.
+	internalErrorMessage = "internal error"
+	// unavailableErrorMessage replaces the message of unavailable errors in responses.
+	unavailableErrorMessage = "service temporarily unavailable, the request can be sent again"
+	// retryAfter is how long clients are asked to wait before sending again a request that failed
+	// with an unavailable error.
+	retryAfter = time.Second
+)
+
+// Postgres error codes translated by DBError.
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	checkViolation      = "23514"
+)
+
+// Error is a domain error. Services return them, usually as package level variables so callers
+// can match them with errors.Is, and the APIs translate them with WriteError and GRPCError.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	// Fields maps the request fields that caused the error to what is wrong with them.
+	Fields map[string]string
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// InvalidError returns an error of kind KindInvalid.
+func InvalidError(message string) *Error {
+	return &Error{Kind: KindInvalid, Message: message}
+}
+
+// InvalidFieldError returns an error of kind KindInvalid caused by the given request field.
+func InvalidFieldError(field, message string) *Error {
+	return &Error{Kind: KindInvalid, Message: message, Fields: map[string]string{field: message}}
+}
+
+// NotFoundError returns an error of kind KindNotFound.
+func NotFoundError(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+// ConflictError returns an error of kind KindConflict.
+func ConflictError(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+// UnauthorizedError returns an error of kind KindUnauthorized.
+func UnauthorizedError(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message}
+}
+
+// ForbiddenError returns an error of kind KindForbidden.
+func ForbiddenError(message string) *Error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+// UnavailableError wraps the error of an unavailable dependency into an error of kind KindUnavailable.
+// It must only be used when nothing was changed before the failure.
+func UnavailableError(err error) *Error {
+	return &Error{Kind: KindUnavailable, Message: unavailableErrorMessage, Err: err}
+}
+
+// InternalError wraps an unexpected error into an error of kind KindInternal.
+func InternalError(err error) *Error {
+	return &Error{Kind: KindInternal, Message: err.Error(), Err: err}
+}
+
+// DBError translates the errors of a store query on the given resource into domain errors:
+// pgx.ErrNoRows becomes a not found error, unique violations a conflict and foreign key or check
+// violations an invalid request. The original error is kept, errors.Is(err, pgx.ErrNoRows) still holds.
+// Other errors are returned unchanged.
+func DBError(err error, resource string) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Kind: KindNotFound, Message: fmt.Sprintf("%s not found", resource), Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case uniqueViolation:
+		return &Error{Kind: KindConflict, Message: fmt.Sprintf("%s already exists", resource), Fields: constraintField(pgErr, "already exists"), Err: err}
+	case foreignKeyViolation:
+		return &Error{Kind: KindInvalid, Message: fmt.Sprintf("%s references a resource that does not exist", resource), Fields: constraintField(pgErr, "does not exist"), Err: err}
+	case checkViolation:
+		return &Error{Kind: KindInvalid, Message: fmt.Sprintf("%s has invalid values", resource), Fields: constraintField(pgErr, "is invalid"), Err: err}
+	default:
+		return err
+	}
+}
+
+// constraintField returns the field of a constraint violation, if Postgres reported its column.
+func constraintField(pgErr *pgconn.PgError, message string) map[string]string {
+	if pgErr.ColumnName == "" {
+		return nil
+	}
+
+	return map[string]string{pgErr.ColumnName: message}
+}
+
+// AsError returns the domain error in the chain of err, errors that are not domain errors are
+// translated with DBError and become internal errors if they are not database errors either.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if errors.As(DBError(err, "resource"), &e) {
+		return e
+	}
+
+	return InternalError(err)
+}
+
+// HTTPStatus returns the HTTP status code of the kind of err.
+func HTTPStatus(err error) int {
+	switch AsError(err).Kind {
+	case KindInvalid:
+		return http.StatusBadRequest
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteError writes err as an ErrorResponse with the HTTP status of its kind.
+// The message of internal errors is not sent to the client and unavailable errors carry a
+// Retry-After header.
+func WriteError(w http.ResponseWriter, err error) {
+	switch AsError(err).Kind {
+	case KindInternal:
+		err = &Error{Kind: KindInternal, Message: internalErrorMessage}
+	case KindUnavailable:
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	}
+
+	WriteErrorResponse(w, err, HTTPStatus(err))
+}
+
+// GRPCError converts err into a status error with the gRPC code of its kind, the fields of
+// invalid requests are attached as a BadRequest detail and unavailable errors carry a RetryInfo
+// detail. Status errors are returned unchanged and the message of internal errors is not sent
+// to the client.
+func GRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if _, ok := status.FromError(err); ok && !errors.As(err, &e) {
+		return err
+	}
+
+	e = AsError(err)
+	var code codes.Code
+	switch e.Kind {
+	case KindInvalid:
+		code = codes.InvalidArgument
+	case KindNotFound:
+		code = codes.NotFound
+	case KindConflict:
+		code = codes.FailedPrecondition
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			code = codes.AlreadyExists
+		}
+	case KindUnauthorized:
+		code = codes.Unauthenticated
+	case KindForbidden:
+		code = codes.PermissionDenied
+	case KindUnavailable:
+		st := status.New(codes.Unavailable, e.Message)
+		if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+			st = detailed
+		}
+		return st.Err()
+	default:
+		return status.Error(codes.Internal, internalErrorMessage)
+	}
+
+	st := status.New(code, err.Error())
+	if len(e.Fields) == 0 {
+		return st.Err()
+	}
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(e.Fields))
+	for field, description := range e.Fields {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+	}
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
@@ -0,0 +1,133 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	errEmptyName := InvalidFieldError("name", "name field cannot be empty")
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: errEmptyName, want: http.StatusBadRequest},
+		{err: fmt.Errorf("product 1: %w", ConflictError("insufficient stock")), want: http.StatusConflict},
+		{err: DBError(pgx.ErrNoRows, "product"), want: http.StatusNotFound},
+		{err: pgx.ErrNoRows, want: http.StatusNotFound},
+		{err: &pgconn.PgError{Code: uniqueViolation}, want: http.StatusConflict},
+		{err: UnauthorizedError("missing or invalid access token"), want: http.StatusUnauthorized},
+		{err: ForbiddenError("not allowed to perform this operation"), want: http.StatusForbidden},
+		{err: UnavailableError(errors.New("connection refused")), want: http.StatusServiceUnavailable},
+		{err: errors.New("connection refused"), want: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		if got := HTTPStatus(test.err); got != test.want {
+			t.Fatalf("%v: wanted %d, got %d", test.err, test.want, got)
+		}
+	}
+}
+
+func TestDBError(t *testing.T) {
+	err := DBError(pgx.ErrNoRows, "order")
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+	if err.Error() != "order not found" {
+		t.Fatalf("wanted %s, got %s", "order not found", err)
+	}
+
+	err = DBError(&pgconn.PgError{Code: uniqueViolation, ColumnName: "email"}, "user")
+	if e := AsError(err); e.Kind != KindConflict || e.Fields["email"] == "" {
+		t.Fatalf("wanted %s with an email field, got %+v", KindConflict, e)
+	}
+
+	other := errors.New("connection refused")
+	if err := DBError(other, "user"); err != other {
+		t.Fatalf("wanted %v, got %v", other, err)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteError(w, InvalidFieldError("user_id", "user id field cannot be empty"))
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("wanted %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if resp.Code != KindInvalid || resp.Fields["user_id"] != "user id field cannot be empty" {
+		t.Fatalf("wanted %s with a user_id field, got %+v", KindInvalid, resp)
+	}
+
+	w = httptest.NewRecorder()
+	WriteError(w, errors.New("password authentication failed for user postgres"))
+	resp = ErrorResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("wanted %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if resp.Message != internalErrorMessage {
+		t.Fatalf("wanted %s, got %s", internalErrorMessage, resp.Message)
+	}
+
+	w = httptest.NewRecorder()
+	WriteError(w, UnavailableError(errors.New("connection refused")))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("wanted %d with a Retry-After header, got %d %v", http.StatusServiceUnavailable, w.Code, w.Header())
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	st := status.Convert(GRPCError(InvalidFieldError("name", "name field cannot be empty")))
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("wanted %s, got %s", codes.InvalidArgument, st.Code())
+	}
+	if len(st.Details()) != 1 {
+		t.Fatalf("wanted %d, got %d", 1, len(st.Details()))
+	}
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	if !ok || badRequest.FieldViolations[0].Field != "name" {
+		t.Fatalf("wanted a name field violation, got %v", st.Details()[0])
+	}
+
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{err: DBError(pgx.ErrNoRows, "product"), want: codes.NotFound},
+		{err: ConflictError("cart is empty"), want: codes.FailedPrecondition},
+		{err: DBError(&pgconn.PgError{Code: uniqueViolation}, "user"), want: codes.AlreadyExists},
+		{err: status.Error(codes.Unavailable, "unavailable"), want: codes.Unavailable},
+		{err: errors.New("connection refused"), want: codes.Internal},
+	}
+	for _, test := range tests {
+		if got := status.Code(GRPCError(test.err)); got != test.want {
+			t.Fatalf("%v: wanted %s, got %s", test.err, test.want, got)
+		}
+	}
+
+	st = status.Convert(GRPCError(UnavailableError(errors.New("connection refused"))))
+	if st.Code() != codes.Unavailable || len(st.Details()) != 1 {
+		t.Fatalf("wanted %s with a RetryInfo detail, got %v", codes.Unavailable, st)
+	}
+	if _, ok := st.Details()[0].(*errdetails.RetryInfo); !ok {
+		t.Fatalf("wanted a RetryInfo detail, got %v", st.Details()[0])
+	}
+}
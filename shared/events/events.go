@@ -0,0 +1,112 @@
+// Package events defines the domain events the services publish through their outbox,
+// they are the contract between the publishing service and its consumers.
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/money"
+)
+
+// Aggregate types, the kind of entity an event belongs to. Events of the same aggregate are
+// delivered in the order they were written.
+const (
+	AggregateUser    = "user"
+	AggregateProduct = "product"
+	AggregateOrder   = "order"
+)
+
+// Event types.
+const (
+	UserCreated         = "UserCreated"
+	UserDeleted         = "UserDeleted"
+	ProductCreated      = "ProductCreated"
+	ProductUpdated      = "ProductUpdated"
+	ProductStockChanged = "ProductStockChanged"
+	OrderStatusChanged  = "OrderStatusChanged"
+	RefundSucceeded     = "RefundSucceeded"
+)
+
+// Reasons of a ProductStockChanged event.
+const (
+	StockSet     = "set"
+	StockReserve = "reserve"
+	StockRelease = "release"
+)
+
+// Event is a domain event as it is published. Payload holds one of the payload types below
+// as JSON, depending on Type.
+type Event struct {
+	// ID is unique across services, consumers use it to discard duplicated deliveries.
+	ID            string          `json:"id"`
+	Source        string          `json:"source"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// UserCreatedPayload is the payload of UserCreated.
+type UserCreatedPayload struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+
+// UserDeletedPayload is the payload of UserDeleted.
+type UserDeletedPayload struct {
+	UserID int `json:"user_id"`
+}
+
+// ProductPayload is the payload of ProductCreated and ProductUpdated. Price is in Currency and
+// Prices holds the prices set in other currencies.
+type ProductPayload struct {
+	ProductID   int          `json:"product_id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Currency    string       `json:"currency"`
+	Price       money.Money  `json:"price"`
+	Prices      money.Prices `json:"prices,omitempty"`
+	Stock       int          `json:"stock"`
+}
+
+// ProductStockChangedPayload is the payload of ProductStockChanged. SKUID is the SKU whose stock
+// changed by Delta and Stock the stock of the whole product after the change. OrderID is set when
+// the stock was reserved or released for an order.
+type ProductStockChangedPayload struct {
+	ProductID int    `json:"product_id"`
+	SKUID     int    `json:"sku_id"`
+	Stock     int    `json:"stock"`
+	Delta     int    `json:"delta"`
+	Reason    string `json:"reason"`
+	OrderID   int    `json:"order_id,omitempty"`
+}
+
+// OrderStatusChangedPayload is the payload of OrderStatusChanged. From is empty when the order is created.
+type OrderStatusChangedPayload struct {
+	OrderID int    `json:"order_id"`
+	UserID  int    `json:"user_id"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to"`
+	Actor   string `json:"actor"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// RefundSucceededPayload is the payload of RefundSucceeded, written once the money of a refund was
+// given back. Items are restocked by the product service when Restock is set.
+type RefundSucceededPayload struct {
+	RefundID int                 `json:"refund_id"`
+	OrderID  int                 `json:"order_id"`
+	Amount   money.Money         `json:"amount"`
+	Restock  bool                `json:"restock"`
+	Items    []RefundItemPayload `json:"items"`
+}
+
+// RefundItemPayload is a quantity of a SKU of a RefundSucceeded event.
+type RefundItemPayload struct {
+	ProductID int `json:"product_id"`
+	SKUID     int `json:"sku_id"`
+	Quantity  int `json:"quantity"`
+}
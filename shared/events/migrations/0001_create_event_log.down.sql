@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS consumer_cursor;
+DROP TABLE IF EXISTS event_log;
@@ -0,0 +1,23 @@
+/*
+    The events published by every service, in the order they were published. Consumers read
+    them from the position of their cursor, so the events published while a consumer is stopped
+    are read when it starts again.
+*/
+CREATE TABLE event_log (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    event JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX event_log_created_at_idx ON event_log (created_at);
+
+/*
+    The id of the last event of the log read by each consumer.
+*/
+CREATE TABLE consumer_cursor (
+    consumer VARCHAR PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,16 @@
+// Package migrations embeds the database migrations of the events database, the event bus shared
+// by the services. They are tracked in their own Table since the events database may be the
+// database of a service.
+// Migrations are named <version>_<name>.up.sql and <version>_<name>.down.sql and are applied
+// in version order by the migrate package.
+package migrations
+
+import "embed"
+
+// Table keeps track of the applied migrations of the events database.
+const Table = "events_schema_migrations"
+
+// FS holds every up and down migration.
+//
+//go:embed *.sql
+var FS embed.FS
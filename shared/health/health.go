@@ -0,0 +1,190 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Paths of the liveness and readiness HTTP endpoints.
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+const checkTimeout = 2 * time.Second
+
+var errShuttingDown = errors.New("shutting down")
+
+// Check reports whether a dependency of the service is available.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks of a service and exposes them over HTTP and
+// through the standard grpc.health.v1 health service.
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []namedCheck
+	services     []string
+	grpcHealth   *health.Server
+	shuttingDown atomic.Bool
+}
+
+// NewChecker returns a Checker reporting the given gRPC services, and the overall server status
+// under the empty service name, as serving until a check fails or Shutdown is called.
+func NewChecker(services ...string) *Checker {
+	c := &Checker{
+		services:   append([]string{""}, services...),
+		grpcHealth: health.NewServer(),
+	}
+	c.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+
+	return c
+}
+
+// AddCheck adds a named readiness check, such as pinging the database.
+func (c *Checker) AddCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Methods returns the full names of the gRPC health service methods.
+// They are meant to be public so load balancers and orchestrators can call them without a token.
+func Methods() []string {
+	return []string{
+		fmt.Sprintf("/%s/Check", healthpb.Health_ServiceDesc.ServiceName),
+		fmt.Sprintf("/%s/Watch", healthpb.Health_ServiceDesc.ServiceName),
+	}
+}
+
+// Register registers the grpc.health.v1 health service on the given gRPC server.
+func (c *Checker) Register(server *grpc.Server) {
+	healthpb.RegisterHealthServer(server, c.grpcHealth)
+}
+
+// Check runs every check and returns the error of each failed one by name.
+// Every check is given up to two seconds.
+func (c *Checker) Check(ctx context.Context) map[string]error {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	failures := make(map[string]error)
+	if c.shuttingDown.Load() {
+		failures["server"] = errShuttingDown
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range checks {
+		nc := nc
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			if err := nc.check(checkCtx); err != nil {
+				mu.Lock()
+				failures[nc.name] = err
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return failures
+}
+
+// Watch runs the checks every interval until ctx is done and reflects their result in the gRPC health service.
+func (c *Checker) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.shuttingDown.Load() {
+				return
+			}
+			if len(c.Check(ctx)) == 0 {
+				c.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+			} else {
+				c.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+			}
+		}
+	}
+}
+
+// Shutdown reports the service as not ready, both over HTTP and gRPC, so no new traffic is
+// sent to it while it drains. It is meant to run before the servers are stopped.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+	c.grpcHealth.Shutdown()
+}
+
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Liveness is the handler of the liveness endpoint, it succeeds as long as the process can serve requests.
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	shared.WriteResponse(http.StatusOK, response{Status: "ok"}, w)
+}
+
+// Readiness is the handler of the readiness endpoint, it fails with 503 if any check fails
+// or the service is shutting down.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	failures := c.Check(r.Context())
+	if len(failures) == 0 {
+		shared.WriteResponse(http.StatusOK, response{Status: "ok"}, w)
+		return
+	}
+
+	checks := make(map[string]string, len(failures))
+	for name, err := range failures {
+		checks[name] = err.Error()
+	}
+	shared.WriteResponse(http.StatusServiceUnavailable, response{Status: "unavailable", Checks: checks}, w)
+}
+
+// GRPCCheck returns a check asking the health service behind conn whether the given service is serving.
+// An empty service asks about the server as a whole.
+func GRPCCheck(conn grpc.ClientConnInterface, service string) Check {
+	client := healthpb.NewHealthClient(conn)
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("status %s", resp.Status)
+		}
+
+		return nil
+	}
+}
+
+func (c *Checker) setServingStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range c.services {
+		c.grpcHealth.SetServingStatus(service, status)
+	}
+}
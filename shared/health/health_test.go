@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const testService = "grpc.TestService"
+
+func grpcStatus(t *testing.T, c *Checker, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	resp, err := c.grpcHealth.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.Status
+}
+
+func TestReadiness(t *testing.T) {
+	c := NewChecker(testService)
+	c.AddCheck("postgres", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	c.Readiness(rec, httptest.NewRequest("GET", ReadinessPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("wanted %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	c.AddCheck("product", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	rec = httptest.NewRecorder()
+	c.Readiness(rec, httptest.NewRequest("GET", ReadinessPath, nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("wanted %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	var resp response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Checks) != 1 || resp.Checks["product"] != "connection refused" {
+		t.Fatalf("wanted %s, got %v", "product: connection refused", resp.Checks)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	c := NewChecker(testService)
+
+	for _, service := range []string{"", testService} {
+		if status := grpcStatus(t, c, service); status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("wanted %s, got %s", healthpb.HealthCheckResponse_SERVING, status)
+		}
+	}
+
+	c.Shutdown()
+
+	for _, service := range []string{"", testService} {
+		if status := grpcStatus(t, c, service); status != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Fatalf("wanted %s, got %s", healthpb.HealthCheckResponse_NOT_SERVING, status)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	c.Readiness(rec, httptest.NewRequest("GET", ReadinessPath, nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("wanted %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	// The process is still alive while it drains.
+	rec = httptest.NewRecorder()
+	c.Liveness(rec, httptest.NewRequest("GET", LivenessPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("wanted %d, got %d", http.StatusOK, rec.Code)
+	}
+}
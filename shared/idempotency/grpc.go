@@ -0,0 +1,147 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// MetadataKey is the gRPC metadata key carrying the idempotency key of a call.
+const MetadataKey = "idempotency-key"
+
+// UnaryServerInterceptor replays the stored response of calls to the given methods sent again
+// with the same idempotency-key metadata. Calls without the metadata are handled as usual.
+// Every outcome is stored except the Unavailable errors with a RetryInfo detail that
+// shared.GRPCError returns for errors of kind shared.KindUnavailable, which guarantee nothing was
+// changed, so the call can be sent again. The key is released too if the handler panics.
+// Methods are full gRPC method names such as "/order.OrderService/CreateOrder".
+// It must run after the auth interceptor since keys are scoped to the caller.
+func (s *Store) UnaryServerInterceptor(methods ...string) grpc.UnaryServerInterceptor {
+	idempotent := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		idempotent[method] = true
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(MetadataKey)
+		msg, ok := req.(proto.Message)
+		if !idempotent[info.FullMethod] || len(values) == 0 || !ok {
+			return handler(ctx, req)
+		}
+		key := values[0]
+
+		payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		operation := scope(ctx, info.FullMethod)
+		rec, err := s.begin(ctx, operation, key, hash(payload))
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		if rec != nil {
+			return replay(rec)
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				s.release(context.WithoutCancel(ctx), operation, key) //nolint:errcheck
+				panic(p)
+			}
+		}()
+
+		resp, err := handler(ctx, req)
+
+		ctx = context.WithoutCancel(ctx)
+		code := status.Code(err)
+		if retryable(err) {
+			s.release(ctx, operation, key) //nolint:errcheck
+			return resp, err
+		}
+
+		var outcome proto.Message = status.Convert(err).Proto()
+		if err == nil {
+			outcome, ok = resp.(proto.Message)
+			if !ok {
+				s.release(ctx, operation, key) //nolint:errcheck
+				return resp, err
+			}
+		}
+		response, marshalErr := marshalAny(outcome)
+		if marshalErr == nil {
+			marshalErr = s.complete(ctx, operation, key, int(code), nil, response)
+		}
+		if marshalErr != nil {
+			s.release(ctx, operation, key) //nolint:errcheck
+		}
+
+		return resp, err
+	}
+}
+
+// replay rebuilds the response or the error of a stored call.
+func replay(rec *record) (any, error) {
+	wrapped := new(anypb.Any)
+	if err := proto.Unmarshal(rec.response, wrapped); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	msg, err := wrapped.UnmarshalNew()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if codes.Code(rec.status) != codes.OK {
+		st, ok := msg.(*spb.Status)
+		if !ok {
+			return nil, status.Error(codes.Internal, "unexpected stored error")
+		}
+		return nil, status.ErrorProto(st)
+	}
+
+	return msg, nil
+}
+
+func marshalAny(msg proto.Message) ([]byte, error) {
+	wrapped, err := anypb.New(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(wrapped)
+}
+
+// retryable reports whether a call failed without changing anything, so it can be sent again.
+func retryable(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.Unavailable {
+		return false
+	}
+	for _, detail := range st.Details() {
+		if _, ok := detail.(*errdetails.RetryInfo); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrKeyReused), errors.Is(err, errKeyTooLong):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrInProgress):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,163 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultTTL   = 24 * time.Hour
+	maxKeyLength = 255
+)
+
+var (
+	// ErrKeyReused is returned when an idempotency key is sent again with a different request.
+	ErrKeyReused = errors.New("idempotency key was already used with a different request")
+	// ErrInProgress is returned when an idempotency key is sent again before the first request finished.
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+
+	errKeyTooLong = fmt.Errorf("idempotency key cannot be longer than %d characters", maxKeyLength)
+)
+
+// Store keeps the responses of requests sent with an idempotency key so retries of the
+// same request get the original response instead of running it again.
+// Keys are scoped to the caller and the operation and expire after the store TTL.
+type Store struct {
+	db  *pgxpool.Pool
+	ttl time.Duration
+}
+
+// NewStore returns a Store that keeps idempotency keys in the given database for ttl.
+// A zero ttl falls back to 24 hours.
+func NewStore(db *pgxpool.Pool, ttl time.Duration) *Store {
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+
+	return &Store{
+		db:  db,
+		ttl: ttl,
+	}
+}
+
+// record is the stored outcome of a finished request.
+type record struct {
+	status int
+	// headers are the HTTP headers set by the handler, gRPC calls have none.
+	headers  http.Header
+	response []byte
+}
+
+// begin claims the key for a new request with the given hash and returns nil, or returns
+// the record of the request that already used it. Returns ErrKeyReused if that request
+// had a different hash and ErrInProgress if it did not finish yet.
+func (s *Store) begin(ctx context.Context, scope, key, requestHash string) (*record, error) {
+	if len(key) > maxKeyLength {
+		return nil, errKeyTooLong
+	}
+
+	if _, err := s.db.Exec(ctx, "DELETE FROM idempotency_key WHERE scope = $1 AND key = $2 AND expires_at < CURRENT_TIMESTAMP", scope, key); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.db.Exec(ctx, "INSERT INTO idempotency_key(scope, key, request_hash, expires_at) VALUES($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4)) ON CONFLICT (scope, key) DO NOTHING", scope, key, requestHash, s.ttl.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	var storedHash string
+	var status *int
+	var headers http.Header
+	var response []byte
+	err = s.db.QueryRow(ctx, "SELECT request_hash, status, headers, response FROM idempotency_key WHERE scope = $1 AND key = $2", scope, key).Scan(&storedHash, &status, &headers, &response)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The first request failed and released the key in the meantime.
+		return nil, ErrInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	if storedHash != requestHash {
+		return nil, ErrKeyReused
+	}
+	if status == nil {
+		return nil, ErrInProgress
+	}
+
+	return &record{
+		status:   *status,
+		headers:  headers,
+		response: response,
+	}, nil
+}
+
+// complete stores the outcome of the request that claimed the key.
+func (s *Store) complete(ctx context.Context, scope, key string, status int, headers http.Header, response []byte) error {
+	_, err := s.db.Exec(ctx, "UPDATE idempotency_key SET status = $3, headers = $4, response = $5 WHERE scope = $1 AND key = $2", scope, key, status, headers, response)
+	return err
+}
+
+// release frees the key of a request that failed without changing anything, or panicked,
+// so it can be sent again.
+func (s *Store) release(ctx context.Context, scope, key string) error {
+	_, err := s.db.Exec(ctx, "DELETE FROM idempotency_key WHERE scope = $1 AND key = $2", scope, key)
+	return err
+}
+
+// DeleteExpired removes the expired keys and returns how many were removed.
+func (s *Store) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := s.db.Exec(ctx, "DELETE FROM idempotency_key WHERE expires_at < CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// RunExpiry removes the expired keys every interval until ctx is done.
+func (s *Store) RunExpiry(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeleteExpired(ctx); err != nil {
+				logger.ErrorContext(ctx, "error at RunExpiry", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// scope namespaces keys by operation and caller so different callers cannot replay each other's responses.
+func scope(ctx context.Context, operation string) string {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return operation
+	}
+	if identity.UserID == 0 {
+		return fmt.Sprintf("%s service:%s", operation, identity.Email)
+	}
+
+	return fmt.Sprintf("%s user:%d", operation, identity.UserID)
+}
+
+func hash(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,256 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/order/migrations"
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func setupStore(t *testing.T, ctx context.Context) *Store {
+	t.Helper()
+
+	// The idempotency_key table is created by the migrations of the order service, its only user.
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewStore(db.DB(), time.Hour)
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t, ctx)
+
+	calls := 0
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Fail") != "" {
+			shared.WriteError(w, shared.UnavailableError(errors.New("connection refused")))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`)) //nolint:errcheck
+	}))
+
+	do := func(key, body string, fail bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/order", bytes.NewBufferString(body))
+		req = req.WithContext(auth.NewContext(req.Context(), auth.Identity{UserID: 1}))
+		if key != "" {
+			req.Header.Set(HeaderKey, key)
+		}
+		if fail {
+			req.Header.Set("X-Fail", "true")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A failure that can be retried does not keep the key.
+	if rec := do("key", `{"items":[]}`, true); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("wanted %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	first := do("key", `{"items":[]}`, false)
+	replay := do("key", `{"items":[]}`, false)
+	if calls != 2 {
+		t.Fatalf("wanted %d, got %d", 2, calls)
+	}
+	if replay.Code != first.Code || replay.Body.String() != first.Body.String() {
+		t.Fatalf("wanted %d %s, got %d %s", first.Code, first.Body, replay.Code, replay.Body)
+	}
+	if replay.Header().Get(HeaderReplayed) != "true" {
+		t.Fatalf("wanted %s header, got none", HeaderReplayed)
+	}
+	if replay.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("wanted %s, got %s", "application/json", replay.Header().Get("Content-Type"))
+	}
+
+	if rec := do("key", `{"items":[1]}`, false); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("wanted %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	do("", `{"items":[]}`, false)
+	do("", `{"items":[]}`, false)
+	if calls != 4 {
+		t.Fatalf("wanted %d, got %d", 4, calls)
+	}
+}
+
+func TestMiddlewareFailures(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t, ctx)
+
+	calls := 0
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		shared.WriteError(w, errors.New("connection reset"))
+	}))
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/order", bytes.NewBufferString(`{"items":[]}`))
+		req.Header.Set(HeaderKey, "key")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A panic does not keep the key in progress.
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("wanted a panic")
+			}
+		}()
+		do()
+	}()
+
+	// Internal errors may have changed something, they are replayed instead of running again.
+	for i := 0; i < 2; i++ {
+		if rec := do(); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("wanted %d, got %d", http.StatusInternalServerError, rec.Code)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("wanted %d, got %d", 2, calls)
+	}
+}
+
+func TestMiddlewareKeepsRequestBody(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t, ctx)
+
+	handler := store.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body) //nolint:errcheck
+	}))
+
+	req := httptest.NewRequest("POST", "/api/v1/order", bytes.NewBufferString("body"))
+	req.Header.Set(HeaderKey, "key")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Body.String() != "body" {
+		t.Fatalf("wanted %s, got %s", "body", rec.Body)
+	}
+}
+
+func TestExpiredKey(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t, ctx)
+
+	if _, err := store.begin(ctx, "scope", "key", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.begin(ctx, "scope", "key", "hash"); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("wanted %v, got %v", ErrInProgress, err)
+	}
+
+	if _, err := store.db.Exec(ctx, "UPDATE idempotency_key SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 second'"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An expired key can be used again, even for a different request.
+	rec, err := store.begin(ctx, "scope", "key", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec != nil {
+		t.Fatalf("wanted a new key, got %v", rec)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	ctx := context.Background()
+	store := setupStore(t, ctx)
+
+	const method = "/grpc.OrderService/CreateOrder"
+	interceptor := store.UnaryServerInterceptor(method)
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+
+	calls := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		switch req.(*wrapperspb.StringValue).Value {
+		case "declined":
+			return nil, status.Error(codes.FailedPrecondition, "payment declined")
+		case "unavailable":
+			return nil, shared.GRPCError(shared.UnavailableError(errors.New("connection refused")))
+		case "internal":
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+		return wrapperspb.Int64(int64(calls)), nil
+	}
+
+	callCtx := metadata.NewIncomingContext(auth.NewContext(ctx, auth.Identity{UserID: 1}), metadata.Pairs(MetadataKey, "key"))
+	first, err := interceptor(callCtx, wrapperspb.String("order"), info, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := interceptor(callCtx, wrapperspb.String("order"), info, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || !proto.Equal(first.(proto.Message), replay.(proto.Message)) {
+		t.Fatalf("wanted %v after %d call, got %v after %d", first, 1, replay, calls)
+	}
+
+	if _, err := interceptor(callCtx, wrapperspb.String("other"), info, handler); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("wanted %s, got %v", codes.InvalidArgument, err)
+	}
+
+	// Errors that cannot succeed on a retry are replayed too.
+	declinedCtx := metadata.NewIncomingContext(auth.NewContext(ctx, auth.Identity{UserID: 1}), metadata.Pairs(MetadataKey, "declined"))
+	for i := 0; i < 2; i++ {
+		if _, err := interceptor(declinedCtx, wrapperspb.String("declined"), info, handler); status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("wanted %s, got %v", codes.FailedPrecondition, err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("wanted %d, got %d", 2, calls)
+	}
+
+	// Errors that guarantee nothing was changed release the key, other errors are replayed.
+	unavailableCtx := metadata.NewIncomingContext(auth.NewContext(ctx, auth.Identity{UserID: 1}), metadata.Pairs(MetadataKey, "unavailable"))
+	internalCtx := metadata.NewIncomingContext(auth.NewContext(ctx, auth.Identity{UserID: 1}), metadata.Pairs(MetadataKey, "internal"))
+	for i := 0; i < 2; i++ {
+		if _, err := interceptor(unavailableCtx, wrapperspb.String("unavailable"), info, handler); status.Code(err) != codes.Unavailable {
+			t.Fatalf("wanted %s, got %v", codes.Unavailable, err)
+		}
+		if _, err := interceptor(internalCtx, wrapperspb.String("internal"), info, handler); status.Code(err) != codes.Internal {
+			t.Fatalf("wanted %s, got %v", codes.Internal, err)
+		}
+	}
+	if calls != 5 {
+		t.Fatalf("wanted %d, got %d", 5, calls)
+	}
+
+	// Keys are scoped to the caller.
+	otherCtx := metadata.NewIncomingContext(auth.NewContext(ctx, auth.Identity{UserID: 2}), metadata.Pairs(MetadataKey, "key"))
+	if _, err := interceptor(otherCtx, wrapperspb.String("order"), info, handler); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 6 {
+		t.Fatalf("wanted %d, got %d", 6, calls)
+	}
+}
@@ -0,0 +1,128 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/PseudoMera/virtual-store/shared"
+)
+
+const (
+	// HeaderKey is the HTTP header carrying the idempotency key of a request.
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed is set on responses replayed from a previous request.
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+// Middleware is a chi middleware that replays the stored response of requests sent again with
+// the same Idempotency-Key header, with the headers set by the handler. Requests without the
+// header are served as usual.
+// Every response is stored except the 503 with a Retry-After header that shared.WriteError writes
+// for errors of kind shared.KindUnavailable, which guarantee nothing was changed, so the request
+// can be sent again. The key is released too if the handler panics.
+// It must run after the auth middleware since keys are scoped to the caller.
+func (s *Store) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderKey)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		operation := scope(r.Context(), r.Method+" "+r.URL.Path)
+		rec, err := s.begin(r.Context(), operation, key, hash(body))
+		if err != nil {
+			shared.WriteErrorResponse(w, err, httpStatus(err))
+			return
+		}
+		if rec != nil {
+			for name, values := range rec.headers {
+				w.Header()[name] = values
+			}
+			w.Header().Set(HeaderReplayed, "true")
+			w.WriteHeader(rec.status)
+			w.Write(rec.response) //nolint:errcheck
+			return
+		}
+
+		ctx := context.WithoutCancel(r.Context())
+		defer func() {
+			if p := recover(); p != nil {
+				s.release(ctx, operation, key) //nolint:errcheck
+				panic(p)
+			}
+		}()
+
+		before := w.Header().Clone()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == http.StatusServiceUnavailable && recorder.Header().Get("Retry-After") != "" {
+			s.release(ctx, operation, key) //nolint:errcheck
+			return
+		}
+		if err := s.complete(ctx, operation, key, recorder.status, changedHeaders(before, w.Header()), recorder.body.Bytes()); err != nil {
+			// Free the key rather than leaving it in progress until it expires.
+			s.release(ctx, operation, key) //nolint:errcheck
+		}
+	})
+}
+
+// changedHeaders returns the headers of after that are not in before, the ones set by the handler
+// rather than by the middlewares that ran earlier.
+func changedHeaders(before, after http.Header) http.Header {
+	headers := make(http.Header)
+	for name, values := range after {
+		if !slices.Equal(before[name], values) {
+			headers[name] = values
+		}
+	}
+
+	return headers
+}
+
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrKeyReused):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrInProgress):
+		return http.StatusConflict
+	case errors.Is(err, errKeyTooLong):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// responseRecorder writes the response through while keeping a copy of its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
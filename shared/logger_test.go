@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLoggerTraceIDs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(traceHandler{slog.NewJSONHandler(&buf, nil)}).With(slog.String("service", "order"))
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	logger.InfoContext(ctx, "error at CreateOrder")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["trace_id"] != traceID.String() {
+		t.Fatalf("wanted %s, got %v", traceID, record["trace_id"])
+	}
+	if record["span_id"] != spanID.String() {
+		t.Fatalf("wanted %s, got %v", spanID, record["span_id"])
+	}
+
+	buf.Reset()
+	logger.InfoContext(context.Background(), "error at CreateOrder")
+	if bytes.Contains(buf.Bytes(), []byte("trace_id")) {
+		t.Fatalf("wanted no trace_id, got %s", buf.String())
+	}
+}
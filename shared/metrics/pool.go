@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector exposes the statistics of a pgx connection pool when Prometheus scrapes them.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquireCount    *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+}
+
+// RegisterPool registers a collector for the connections, acquisitions and acquire wait time of the pool.
+func RegisterPool(pool *pgxpool.Pool) error {
+	return prometheus.Register(newPoolCollector(pool))
+}
+
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	return &poolCollector{
+		pool:            pool,
+		acquiredConns:   prometheus.NewDesc("pgxpool_acquired_connections", "Number of connections currently in use.", nil, nil),
+		idleConns:       prometheus.NewDesc("pgxpool_idle_connections", "Number of idle connections in the pool.", nil, nil),
+		totalConns:      prometheus.NewDesc("pgxpool_total_connections", "Number of connections in the pool.", nil, nil),
+		maxConns:        prometheus.NewDesc("pgxpool_max_connections", "Maximum size of the pool.", nil, nil),
+		acquireCount:    prometheus.NewDesc("pgxpool_acquires_total", "Number of successful connection acquisitions.", nil, nil),
+		acquireDuration: prometheus.NewDesc("pgxpool_acquire_wait_seconds_total", "Total time spent waiting to acquire a connection.", nil, nil),
+		emptyAcquires:   prometheus.NewDesc("pgxpool_empty_acquires_total", "Number of acquisitions that had to wait because the pool was empty.", nil, nil),
+	}
+}
+
+func (pc *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pc.acquiredConns
+	ch <- pc.idleConns
+	ch <- pc.totalConns
+	ch <- pc.maxConns
+	ch <- pc.acquireCount
+	ch <- pc.acquireDuration
+	ch <- pc.emptyAcquires
+}
+
+func (pc *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := pc.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(pc.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(pc.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(pc.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(pc.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(pc.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(pc.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(pc.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Command is the name of the subcommand the services accept to manage their migrations.
+const Command = "migrate"
+
+// Usage describes the arguments of the migrate subcommand.
+const Usage = `usage: migrate <command>
+
+commands:
+  up                 apply every pending migration
+  down [steps]       roll back the last steps migrations, 1 by default
+  status             list the migrations and when they were applied
+  baseline <version> mark the migrations up to version as applied without running them`
+
+var errUsage = errors.New(Usage)
+
+// Run executes the migrate subcommand with the given arguments, writing its output to w.
+func (m *Migrator) Run(ctx context.Context, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return errUsage
+			}
+			steps = n
+		}
+		return m.Down(ctx, steps)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d %-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	case "baseline":
+		if len(args) < 2 {
+			return errUsage
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return errUsage
+		}
+		return m.Baseline(ctx, version)
+	default:
+		return errUsage
+	}
+}
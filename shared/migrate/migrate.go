@@ -0,0 +1,296 @@
+// Package migrate applies versioned SQL migrations to a postgres database and keeps track of
+// them in the schema_migrations table, or in another table for sets of migrations sharing a database.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultTable is the table keeping track of the migrations applied by NewMigrator.
+const DefaultTable = "schema_migrations"
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS %s (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+var (
+	fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+	// ErrNoDownMigration is returned when rolling back a migration without a down file.
+	ErrNoDownMigration = errors.New("migration has no down file")
+)
+
+// Migration is a schema change. Down is empty for migrations that cannot be rolled back.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a known migration and whether it has been applied.
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Load reads the migrations at the root of fsys, sorted by version.
+// Every migration needs an up file, files that do not follow the naming scheme are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+	// table keeps track of the applied migrations, its name is also hashed into the key of the
+	// advisory lock held while migrating, so services starting at the same time against the same
+	// database apply each migration once.
+	table  string
+	logger *slog.Logger
+}
+
+// NewMigrator returns a Migrator applying the migrations found in fsys to the given database.
+func NewMigrator(db *pgxpool.Pool, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	return NewTableMigrator(db, fsys, DefaultTable, logger)
+}
+
+// NewTableMigrator returns a Migrator applying the migrations found in fsys to the given database
+// and keeping track of them in table, so their versions do not clash with the ones of the
+// other sets of migrations of the database.
+func NewTableMigrator(db *pgxpool.Pool, fsys fs.FS, table string, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		table:      table,
+		logger:     logger,
+	}, nil
+}
+
+// Up applies every migration that was not applied yet, in version order.
+// Each migration runs in its own transaction.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, migration.Up, fmt.Sprintf("INSERT INTO %s(version, name) VALUES($1, $2)", m.identifier())); err != nil {
+				return err
+			}
+			m.logger.InfoContext(ctx, "applied migration", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+		}
+
+		return nil
+	})
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrNoDownMigration)
+			}
+			if err := m.apply(ctx, conn, migration, migration.Down, fmt.Sprintf("DELETE FROM %s WHERE version = $1 AND name = $2", m.identifier())); err != nil {
+				return err
+			}
+			m.logger.InfoContext(ctx, "rolled back migration", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Baseline records every migration up to the given version as applied without running it.
+// It is meant for databases created before migrations were tracked.
+func (m *Migrator) Baseline(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, err := conn.Exec(ctx, fmt.Sprintf("INSERT INTO %s(version, name) VALUES($1, $2) ON CONFLICT DO NOTHING", m.identifier()), migration.Version, migration.Name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status returns every known migration alongside the time it was applied, if it was.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = make([]Status, len(m.migrations))
+		for i, migration := range m.migrations {
+			statuses[i] = Status{
+				Version: migration.Version,
+				Name:    migration.Name,
+			}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				statuses[i].AppliedAt = &appliedAt
+			}
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// withLock runs fn on a single connection holding the migrations advisory lock,
+// creating the migrations table if it does not exist.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock(hashtext($1))", m.table); err != nil {
+		return err
+	}
+	defer func() {
+		// The lock belongs to the session, it must be released before the connection goes back to the pool.
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", m.table); err != nil {
+			conn.Conn().Close(context.Background()) //nolint:errcheck
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, fmt.Sprintf(createMigrationsTable, m.identifier())); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// apply runs the given migration sql and the bookkeeping query in one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration Migration, sql, record string) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.Exec(ctx, record, migration.Version, migration.Name); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// appliedVersions returns the applied migration versions and when they were applied.
+func (m *Migrator) appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, fmt.Sprintf("SELECT version, applied_at FROM %s", m.identifier()))
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]time.Time)
+	var (
+		version   int64
+		appliedAt time.Time
+	)
+	_, err = pgx.ForEachRow(rows, []any{&version, &appliedAt}, func() error {
+		applied[version] = appliedAt
+		return nil
+	})
+
+	return applied, err
+}
+
+// identifier returns the quoted name of the migrations table.
+func (m *Migrator) identifier() string {
+	return pgx.Identifier{m.table}.Sanitize()
+}
@@ -0,0 +1,129 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/migrate"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":      {Data: []byte("CREATE INDEX a_idx ON a (id);")},
+		"0001_create_a.up.sql":       {Data: []byte("CREATE TABLE a (id INT);")},
+		"0001_create_a.down.sql":     {Data: []byte("DROP TABLE a;")},
+		"README.md":                  {Data: []byte("not a migration")},
+		"0010_create_b.up.sql":       {Data: []byte("CREATE TABLE b (id INT);")},
+		"0010_create_b.down.sql":     {Data: []byte("DROP TABLE b;")},
+		"nested/0003_ignored.up.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	migrations, err := migrate.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("wanted %d, got %d", 3, len(migrations))
+	}
+
+	versions := []int64{1, 2, 10}
+	for i, version := range versions {
+		if migrations[i].Version != version {
+			t.Fatalf("wanted %d, got %d", version, migrations[i].Version)
+		}
+	}
+	if migrations[0].Name != "create_a" || migrations[0].Down != "DROP TABLE a;" {
+		t.Fatalf("wanted %s with a down file, got %+v", "create_a", migrations[0])
+	}
+	if migrations[1].Down != "" {
+		t.Fatalf("wanted no down file, got %s", migrations[1].Down)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []fstest.MapFS{
+		{"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")}},
+		{
+			"0001_create_a.up.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+			"0001_create_b.up.sql": {Data: []byte("CREATE TABLE b (id INT);")},
+		},
+	}
+
+	for _, fsys := range tests {
+		if _, err := migrate.Load(fsys); err == nil {
+			t.Fatalf("wanted an error for %v", fsys)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+		"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := migrate.NewMigrator(db.DB(), fsys, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test harness already applied every migration, applying them again is a no-op.
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Down(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB().Exec(ctx, "SELECT * FROM a"); err == nil {
+		t.Fatalf("wanted table %s to be dropped", "a")
+	}
+
+	var out bytes.Buffer
+	if err := migrator.Run(ctx, []string{"status"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(out.String(), "pending"); got != 2 {
+		t.Fatalf("wanted %d, got %d", 2, got)
+	}
+
+	if err := migrator.Run(ctx, []string{"up"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Fatalf("wanted migration %d to be applied", status.Version)
+		}
+	}
+
+	// Another set of migrations of the same database keeps its own versions.
+	other, err := migrate.NewTableMigrator(db.DB(), fstest.MapFS{
+		"0001_create_c.up.sql": {Data: []byte("CREATE TABLE c (id INT);")},
+	}, "other_migrations", slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB().Exec(ctx, "SELECT * FROM c"); err != nil {
+		t.Fatal(err)
+	}
+}
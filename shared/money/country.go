@@ -0,0 +1,119 @@
+package money
+
+import "strings"
+
+// countryCurrencies maps ISO 3166 country codes to the currency used in the country.
+var countryCurrencies = map[string]string{
+	"AR": "ARS",
+	"AT": "EUR",
+	"AU": "AUD",
+	"BE": "EUR",
+	"BR": "BRL",
+	"CA": "CAD",
+	"CH": "CHF",
+	"CL": "CLP",
+	"CN": "CNY",
+	"CO": "COP",
+	"CR": "CRC",
+	"CZ": "CZK",
+	"DE": "EUR",
+	"DK": "DKK",
+	"DO": "DOP",
+	"EC": "USD",
+	"ES": "EUR",
+	"FI": "EUR",
+	"FR": "EUR",
+	"GB": "GBP",
+	"GR": "EUR",
+	"GT": "GTQ",
+	"HK": "HKD",
+	"IE": "EUR",
+	"IN": "INR",
+	"IS": "ISK",
+	"IT": "EUR",
+	"JP": "JPY",
+	"KR": "KRW",
+	"MX": "MXN",
+	"NL": "EUR",
+	"NO": "NOK",
+	"NZ": "NZD",
+	"PA": "USD",
+	"PE": "PEN",
+	"PL": "PLN",
+	"PR": "USD",
+	"PT": "EUR",
+	"PY": "PYG",
+	"SE": "SEK",
+	"SG": "SGD",
+	"SV": "USD",
+	"US": "USD",
+	"UY": "UYU",
+	"ZA": "ZAR",
+}
+
+// countryCodes maps the English names of the countries in countryCurrencies to their code.
+var countryCodes = map[string]string{
+	"ARGENTINA":                "AR",
+	"AUSTRALIA":                "AU",
+	"AUSTRIA":                  "AT",
+	"BELGIUM":                  "BE",
+	"BRAZIL":                   "BR",
+	"CANADA":                   "CA",
+	"CHILE":                    "CL",
+	"CHINA":                    "CN",
+	"COLOMBIA":                 "CO",
+	"COSTA RICA":               "CR",
+	"CZECHIA":                  "CZ",
+	"CZECH REPUBLIC":           "CZ",
+	"DENMARK":                  "DK",
+	"DOMINICAN REPUBLIC":       "DO",
+	"ECUADOR":                  "EC",
+	"EL SALVADOR":              "SV",
+	"FINLAND":                  "FI",
+	"FRANCE":                   "FR",
+	"GERMANY":                  "DE",
+	"GREECE":                   "GR",
+	"GUATEMALA":                "GT",
+	"HONG KONG":                "HK",
+	"ICELAND":                  "IS",
+	"INDIA":                    "IN",
+	"IRELAND":                  "IE",
+	"ITALY":                    "IT",
+	"JAPAN":                    "JP",
+	"MEXICO":                   "MX",
+	"NETHERLANDS":              "NL",
+	"NEW ZEALAND":              "NZ",
+	"NORWAY":                   "NO",
+	"PANAMA":                   "PA",
+	"PARAGUAY":                 "PY",
+	"PERU":                     "PE",
+	"POLAND":                   "PL",
+	"PORTUGAL":                 "PT",
+	"PUERTO RICO":              "PR",
+	"SINGAPORE":                "SG",
+	"SOUTH AFRICA":             "ZA",
+	"SOUTH KOREA":              "KR",
+	"SPAIN":                    "ES",
+	"SWEDEN":                   "SE",
+	"SWITZERLAND":              "CH",
+	"UK":                       "GB",
+	"UNITED KINGDOM":           "GB",
+	"UNITED STATES":            "US",
+	"UNITED STATES OF AMERICA": "US",
+	"URUGUAY":                  "UY",
+	"USA":                      "US",
+}
+
+// CountryCurrency returns the currency used in a country given by its ISO 3166 alpha-2 code or its
+// English name, in any case. Unknown countries use DefaultCurrency.
+func CountryCurrency(country string) string {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if code, ok := countryCodes[country]; ok {
+		country = code
+	}
+	if currency, ok := countryCurrencies[country]; ok {
+		return currency
+	}
+
+	return DefaultCurrency
+}
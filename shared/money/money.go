@@ -0,0 +1,343 @@
+// Package money implements exact amounts of money. An amount is held as an integer number of the
+// minor units of its currency, such as cents, so it never loses precision on its way between the
+// NUMERIC columns, JSON documents and gRPC messages of the services.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/PseudoMera/virtual-store/shared/money/moneypb"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultCurrency is the currency of the amounts that do not set one.
+const DefaultCurrency = "USD"
+
+var (
+	// ErrInvalidAmount is returned when parsing or scanning an amount that is not a decimal
+	// number with at most the digits of the minor units of its currency.
+	ErrInvalidAmount = errors.New("invalid amount")
+	// ErrInvalidCurrency is returned for a currency that is not an ISO 4217 code.
+	ErrInvalidCurrency = errors.New("invalid currency")
+)
+
+// zeroDecimalCurrencies are the currencies without minor units, every other currency has two
+// decimal digits. Currencies with three decimal digits do not fit the NUMERIC(12, 2) columns.
+var zeroDecimalCurrencies = map[string]bool{
+	"CLP": true,
+	"ISK": true,
+	"JPY": true,
+	"KRW": true,
+	"PYG": true,
+	"UGX": true,
+	"VND": true,
+}
+
+// Money is an amount of a currency. Amount is in the minor units of Currency, an empty Currency
+// is DefaultCurrency. The zero value is zero DefaultCurrency, except that it takes the currency
+// of the amount added to or subtracted from it so sums can start from it.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New returns amount minor units of the given currency, DefaultCurrency if it is empty.
+func New(amount int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	return Money{
+		Amount:   amount,
+		Currency: currency,
+	}
+}
+
+// Parse parses a decimal amount of the given currency, DefaultCurrency if it is empty, such as
+// "120.40". The amount cannot have more decimal digits than the minor units of the currency.
+func Parse(amount, currency string) (Money, error) {
+	m := New(0, currency)
+	r, ok := parseDecimal(amount)
+	if !ok {
+		return m, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	return m, m.setRat(r)
+}
+
+// MustParse is like Parse but panics if the amount is invalid, it is meant for constants.
+func MustParse(amount, currency string) Money {
+	m, err := Parse(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+// ValidCurrency reports whether the currency is an ISO 4217 code, three upper case letters.
+func ValidCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, c := range currency {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Digits returns the number of decimal digits of the minor units of the currency.
+func Digits(currency string) int {
+	if zeroDecimalCurrencies[currency] {
+		return 0
+	}
+
+	return 2
+}
+
+// CurrencyCode returns the currency of m, DefaultCurrency if it is not set.
+func (m Money) CurrencyCode() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+
+	return m.Currency
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// IsNegative reports whether the amount is lower than zero.
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m plus o. Both must be amounts of the same currency.
+func (m Money) Add(o Money) Money {
+	if m == (Money{}) {
+		return o
+	}
+	m.mustMatch(o)
+	m.Amount += o.Amount
+	return m
+}
+
+// Sub returns m minus o. Both must be amounts of the same currency.
+func (m Money) Sub(o Money) Money {
+	if m == (Money{}) {
+		m.Currency = o.CurrencyCode()
+	}
+	m.mustMatch(o)
+	m.Amount -= o.Amount
+	return m
+}
+
+// Mul returns m times n.
+func (m Money) Mul(n int) Money {
+	m.Amount *= int64(n)
+	return m
+}
+
+// Cmp compares m and o, it returns -1 if m is lower, 0 if they are equal and +1 if m is greater.
+// Both must be amounts of the same currency.
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SameCurrency reports whether m and o are amounts of the same currency.
+func (m Money) SameCurrency(o Money) bool {
+	return m.CurrencyCode() == o.CurrencyCode()
+}
+
+// mustMatch panics if m and o are amounts of different currencies, mixing them is a programming error.
+func (m Money) mustMatch(o Money) {
+	if !m.SameCurrency(o) {
+		panic(fmt.Sprintf("money: mixing %s and %s", m.CurrencyCode(), o.CurrencyCode()))
+	}
+}
+
+// String returns the amount as a decimal number with the digits of the minor units of its currency, such as "120.40".
+func (m Money) String() string {
+	digits := Digits(m.CurrencyCode())
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	if digits == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	s := fmt.Sprintf("%0*d", digits+1, amount)
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+// InCurrency returns the same decimal amount as an amount of the given currency, without
+// converting it, such as for an amount that was parsed before its currency was known.
+// Returns ErrInvalidAmount if the amount has more decimal digits than the minor units of the currency.
+func (m Money) InCurrency(currency string) (Money, error) {
+	if !ValidCurrency(currency) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+
+	r := new(big.Rat).SetFrac(big.NewInt(m.Amount), pow10(Digits(m.CurrencyCode())))
+	converted := New(0, currency)
+	return converted, converted.setRat(r)
+}
+
+// setRat sets the amount of m to r, which must be a whole number of minor units.
+func (m *Money) setRat(r *big.Rat) error {
+	r = new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(Digits(m.CurrencyCode()))))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return fmt.Errorf("%w: %s is not a whole number of %s minor units", ErrInvalidAmount, r.FloatString(2), m.CurrencyCode())
+	}
+
+	m.Amount = r.Num().Int64()
+	return nil
+}
+
+// parseDecimal parses a decimal number without exponent, such as "155.3".
+func parseDecimal(s string) (*big.Rat, bool) {
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if whole == "" || !isDigits(whole) || !isDigits(fraction) || strings.HasSuffix(s, ".") {
+		return nil, false
+	}
+
+	return new(big.Rat).SetString(s)
+}
+
+// isDigits reports whether s only has decimal digits.
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MarshalJSON encodes the amount as a decimal string, such as "120.40".
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON decodes a decimal string or number exactly, it keeps the currency of m or sets DefaultCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var amount string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return err
+		}
+	} else {
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidAmount, data)
+		}
+		amount = number.String()
+	}
+
+	parsed, err := Parse(amount, m.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner, it keeps the currency of m so the currency
+// column must be scanned first when there is one.
+func (m *Money) ScanNumeric(n pgtype.Numeric) error {
+	m.Currency = m.CurrencyCode()
+	r, ok := numericRat(n)
+	if !ok {
+		return fmt.Errorf("%w: cannot scan %v", ErrInvalidAmount, n)
+	}
+
+	return m.setRat(r)
+}
+
+// numericRat returns the value of a finite numeric.
+func numericRat(n pgtype.Numeric) (*big.Rat, bool) {
+	if !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite {
+		return nil, false
+	}
+
+	r := new(big.Rat)
+	if n.Int != nil {
+		r.SetInt(n.Int)
+	}
+	scale := new(big.Rat).SetInt(pow10(int(abs(n.Exp))))
+	if n.Exp < 0 {
+		r.Quo(r, scale)
+	} else {
+		r.Mul(r, scale)
+	}
+
+	return r, true
+}
+
+// NumericValue implements pgtype.NumericValuer.
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{
+		Int:   big.NewInt(m.Amount),
+		Exp:   -int32(Digits(m.CurrencyCode())),
+		Valid: true,
+	}, nil
+}
+
+// Proto returns m as a Money message.
+func (m Money) Proto() *moneypb.Money {
+	return &moneypb.Money{
+		Amount:   m.Amount,
+		Currency: m.CurrencyCode(),
+	}
+}
+
+// FromProto returns the amount of a Money message, nil is zero DefaultCurrency.
+func FromProto(m *moneypb.Money) (Money, error) {
+	if m == nil {
+		return Money{}, nil
+	}
+	if m.Currency != "" && !ValidCurrency(m.Currency) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidCurrency, m.Currency)
+	}
+
+	return New(m.Amount, m.Currency), nil
+}
+
+// pow10 returns 10 to the power of n.
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func abs(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,211 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		err      error
+	}{
+		{amount: "120.40", want: 12040},
+		{amount: "120.4", want: 12040},
+		{amount: "0.1", want: 10},
+		{amount: "-3", want: -300},
+		{amount: "1200", currency: "JPY", want: 1200},
+		{amount: "1.005", err: ErrInvalidAmount},
+		{amount: "12.5", currency: "JPY", err: ErrInvalidAmount},
+		{amount: "1e3", err: ErrInvalidAmount},
+		{amount: "1.", err: ErrInvalidAmount},
+		{amount: "", err: ErrInvalidAmount},
+	}
+
+	for _, test := range tests {
+		m, err := Parse(test.amount, test.currency)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%s: wanted %v, got %v", test.amount, test.err, err)
+		}
+		if err == nil && m.Amount != test.want {
+			t.Fatalf("%s: wanted %d, got %d", test.amount, test.want, m.Amount)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{money: New(12040, "USD"), want: "120.40"},
+		{money: New(5, ""), want: "0.05"},
+		{money: New(-150, "EUR"), want: "-1.50"},
+		{money: New(1200, "JPY"), want: "1200"},
+		{money: Money{}, want: "0.00"},
+	}
+
+	for _, test := range tests {
+		if got := test.money.String(); got != test.want {
+			t.Fatalf("wanted %s, got %s", test.want, got)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	var total Money
+	for _, price := range []Money{New(1050, "EUR"), New(250, "EUR")} {
+		total = total.Add(price.Mul(2))
+	}
+	if total != New(2600, "EUR") {
+		t.Fatalf("wanted %v, got %v", New(2600, "EUR"), total)
+	}
+	if got := (Money{}).Sub(New(100, "JPY")); got != New(-100, "JPY") {
+		t.Fatalf("wanted %v, got %v", New(-100, "JPY"), got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("wanted a panic adding amounts of different currencies")
+		}
+	}()
+	total.Add(New(100, "USD"))
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct{ Price Money }{Price: New(12040, "USD")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wanted := `{"Price":"120.40"}`; string(data) != wanted {
+		t.Fatalf("wanted %s, got %s", wanted, data)
+	}
+
+	for _, data := range []string{`"120.40"`, `120.40`, `120.4`} {
+		var m Money
+		if err := json.Unmarshal([]byte(data), &m); err != nil {
+			t.Fatal(err)
+		}
+		if m != New(12040, DefaultCurrency) {
+			t.Fatalf("%s: wanted %d, got %d", data, 12040, m.Amount)
+		}
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`"abc"`), &m); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidAmount, err)
+	}
+}
+
+func TestNumeric(t *testing.T) {
+	var m Money
+	if err := m.ScanNumeric(pgtype.Numeric{Int: big.NewInt(12040), Exp: -2, Valid: true}); err != nil {
+		t.Fatal(err)
+	}
+	if m != New(12040, DefaultCurrency) {
+		t.Fatalf("wanted %d, got %d", 12040, m.Amount)
+	}
+
+	m = Money{Currency: "JPY"}
+	if err := m.ScanNumeric(pgtype.Numeric{Int: big.NewInt(12), Exp: 2, Valid: true}); err != nil {
+		t.Fatal(err)
+	}
+	if m.Amount != 1200 {
+		t.Fatalf("wanted %d, got %d", 1200, m.Amount)
+	}
+	if err := m.ScanNumeric(pgtype.Numeric{Int: big.NewInt(1250), Exp: -2, Valid: true}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidAmount, err)
+	}
+	if err := m.ScanNumeric(pgtype.Numeric{}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidAmount, err)
+	}
+
+	n, err := New(12040, "USD").NumericValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Int.Int64() != 12040 || n.Exp != -2 {
+		t.Fatalf("wanted %de%d, got %de%d", 12040, -2, n.Int.Int64(), n.Exp)
+	}
+
+	// Round trip through the pgx codec in both wire formats.
+	typeMap := pgtype.NewMap()
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := typeMap.Encode(pgtype.NumericOID, format, New(12040, "USD"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var scanned Money
+		if err := typeMap.Scan(pgtype.NumericOID, format, buf, &scanned); err != nil {
+			t.Fatal(err)
+		}
+		if scanned != New(12040, "USD") {
+			t.Fatalf("wanted %d, got %d", 12040, scanned.Amount)
+		}
+	}
+}
+
+func TestProto(t *testing.T) {
+	m, err := FromProto(New(12040, "EUR").Proto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != New(12040, "EUR") {
+		t.Fatalf("wanted %v, got %v", New(12040, "EUR"), m)
+	}
+
+	if _, err := FromProto(New(1, "euro").Proto()); !errors.Is(err, ErrInvalidCurrency) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidCurrency, err)
+	}
+}
+
+func TestInCurrency(t *testing.T) {
+	m, err := MustParse("1200", "").InCurrency("JPY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != New(1200, "JPY") {
+		t.Fatalf("wanted %v, got %v", New(1200, "JPY"), m)
+	}
+
+	if _, err := MustParse("12.50", "").InCurrency("JPY"); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidAmount, err)
+	}
+}
+
+func TestPricesJSON(t *testing.T) {
+	var prices Prices
+	if err := json.Unmarshal([]byte(`{"EUR": "9.90", "JPY": 1500}`), &prices); err != nil {
+		t.Fatal(err)
+	}
+	if prices["EUR"] != New(990, "EUR") || prices["JPY"] != New(1500, "JPY") {
+		t.Fatalf("wanted %v and %v, got %v", New(990, "EUR"), New(1500, "JPY"), prices)
+	}
+
+	if err := json.Unmarshal([]byte(`{"euro": "9.90"}`), &prices); !errors.Is(err, ErrInvalidCurrency) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidCurrency, err)
+	}
+}
+
+func TestCountryCurrency(t *testing.T) {
+	tests := map[string]string{
+		"JP":                 "JPY",
+		"es":                 "EUR",
+		"Dominican Republic": "DOP",
+		" united kingdom ":   "GBP",
+		"Testing Lands":      DefaultCurrency,
+		"":                   DefaultCurrency,
+	}
+
+	for country, want := range tests {
+		if got := CountryCurrency(country); got != want {
+			t.Fatalf("%q: wanted %s, got %s", country, want, got)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/PseudoMera/virtual-store/shared/money/moneypb"
+)
+
+// Prices are amounts by currency, such as the prices of a product in currencies other than its own.
+// Every amount is an amount of the currency of its key.
+type Prices map[string]Money
+
+// UnmarshalJSON decodes an object of decimal amounts by currency, such as {"EUR": "9.90"}, each
+// amount is parsed in the currency of its key.
+func (p *Prices) UnmarshalJSON(data []byte) error {
+	var amounts map[string]json.RawMessage
+	if err := json.Unmarshal(data, &amounts); err != nil {
+		return err
+	}
+	if amounts == nil {
+		*p = nil
+		return nil
+	}
+
+	prices := make(Prices, len(amounts))
+	for currency, amount := range amounts {
+		if !ValidCurrency(currency) {
+			return fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+		}
+		price := New(0, currency)
+		if err := price.UnmarshalJSON(amount); err != nil {
+			return err
+		}
+		prices[currency] = price
+	}
+	*p = prices
+
+	return nil
+}
+
+// Currencies returns the currencies of the prices in alphabetical order.
+func (p Prices) Currencies() []string {
+	currencies := make([]string, 0, len(p))
+	for currency := range p {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	return currencies
+}
+
+// Proto returns the prices as Money messages ordered by currency.
+func (p Prices) Proto() []*moneypb.Money {
+	prices := make([]*moneypb.Money, 0, len(p))
+	for _, currency := range p.Currencies() {
+		prices = append(prices, p[currency].Proto())
+	}
+
+	return prices
+}
+
+// PricesFromProto returns the prices of Money messages, which cannot repeat a currency.
+func PricesFromProto(messages []*moneypb.Money) (Prices, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	prices := make(Prices, len(messages))
+	for _, message := range messages {
+		price, err := FromProto(message)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := prices[price.Currency]; ok {
+			return nil, fmt.Errorf("%w: %s is repeated", ErrInvalidCurrency, price.Currency)
+		}
+		prices[price.Currency] = price
+	}
+
+	return prices, nil
+}
@@ -0,0 +1,150 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RateDigits is the number of decimal digits kept for exchange rates, those of the NUMERIC(18, 8) columns.
+const RateDigits = 8
+
+var (
+	// ErrInvalidRate is returned when parsing or scanning an exchange rate that is not a positive decimal number.
+	ErrInvalidRate = errors.New("invalid exchange rate")
+	// ErrUnknownRate is returned when there is no exchange rate between two currencies.
+	ErrUnknownRate = shared.ConflictError("unknown exchange rate")
+)
+
+// Rate is an exchange rate, one unit of From is worth Value units of To. AsOf is when the rate was
+// published by its source.
+type Rate struct {
+	From  string
+	To    string
+	Value *big.Rat
+	AsOf  time.Time
+}
+
+// NewRate returns the rate from one currency to another, value is rounded to RateDigits decimal digits.
+func NewRate(from, to string, value *big.Rat, asOf time.Time) (Rate, error) {
+	if !ValidCurrency(from) || !ValidCurrency(to) {
+		return Rate{}, fmt.Errorf("%w: %q to %q", ErrInvalidCurrency, from, to)
+	}
+	value = round(value, RateDigits)
+	if value.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("%w: %s to %s is not positive", ErrInvalidRate, from, to)
+	}
+
+	return Rate{
+		From:  from,
+		To:    to,
+		Value: value,
+		AsOf:  asOf,
+	}, nil
+}
+
+// ParseRate parses the decimal value of the rate from one currency to another, such as "0.92".
+func ParseRate(from, to, value string, asOf time.Time) (Rate, error) {
+	r, ok := parseDecimal(value)
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, value)
+	}
+
+	return NewRate(from, to, r, asOf)
+}
+
+// Identity returns the rate of a currency to itself.
+func Identity(currency string, asOf time.Time) Rate {
+	return Rate{
+		From:  currency,
+		To:    currency,
+		Value: big.NewRat(1, 1),
+		AsOf:  asOf,
+	}
+}
+
+// String returns the value of the rate as a decimal number, such as "0.92".
+func (r Rate) String() string {
+	if r.Value == nil {
+		return "0"
+	}
+
+	s := r.Value.FloatString(RateDigits)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Convert returns m in the To currency, rounded half away from zero to its minor units.
+// m must be an amount of the From currency.
+func (r Rate) Convert(m Money) (Money, error) {
+	if m.CurrencyCode() != r.From || r.Value == nil {
+		return Money{}, fmt.Errorf("%w: cannot convert %s with a %s to %s rate", ErrInvalidRate, m.CurrencyCode(), r.From, r.To)
+	}
+
+	amount := new(big.Rat).SetFrac(big.NewInt(m.Amount), pow10(Digits(r.From)))
+	converted := New(0, r.To)
+	return converted, converted.setRat(round(amount.Mul(amount, r.Value), Digits(r.To)))
+}
+
+// MarshalJSON encodes the rate as an object with its value as a decimal string.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		From  string    `json:"from"`
+		To    string    `json:"to"`
+		Value string    `json:"value"`
+		AsOf  time.Time `json:"as_of"`
+	}{
+		From:  r.From,
+		To:    r.To,
+		Value: r.String(),
+		AsOf:  r.AsOf,
+	})
+}
+
+// ScanNumeric implements pgtype.NumericScanner for the value of the rate, it keeps its currencies
+// and timestamp.
+func (r *Rate) ScanNumeric(n pgtype.Numeric) error {
+	value, ok := numericRat(n)
+	if !ok || value.Sign() <= 0 {
+		return fmt.Errorf("%w: cannot scan %v", ErrInvalidRate, n)
+	}
+
+	r.Value = value
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer for the value of the rate.
+func (r Rate) NumericValue() (pgtype.Numeric, error) {
+	if r.Value == nil {
+		return pgtype.Numeric{}, nil
+	}
+
+	scaled := new(big.Rat).Mul(round(r.Value, RateDigits), new(big.Rat).SetInt(pow10(RateDigits)))
+	return pgtype.Numeric{
+		Int:   scaled.Num(),
+		Exp:   -RateDigits,
+		Valid: true,
+	}, nil
+}
+
+// round rounds r half away from zero to the given number of decimal digits.
+func round(r *big.Rat, digits int) *big.Rat {
+	scale := new(big.Rat).SetInt(pow10(digits))
+	scaled := new(big.Rat).Mul(r, scale)
+
+	// Add half a unit away from zero and truncate.
+	half := big.NewRat(1, 2)
+	if scaled.Sign() < 0 {
+		half.Neg(half)
+	}
+	scaled.Add(scaled, half)
+	truncated := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+
+	return new(big.Rat).SetFrac(truncated, pow10(digits))
+}
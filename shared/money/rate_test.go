@@ -0,0 +1,171 @@
+package money
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		from, to string
+		rate     string
+		amount   Money
+		want     Money
+	}{
+		{from: "USD", to: "EUR", rate: "0.92", amount: New(1000, "USD"), want: New(920, "EUR")},
+		{from: "USD", to: "EUR", rate: "0.9235", amount: New(1999, "USD"), want: New(1846, "EUR")},
+		{from: "USD", to: "JPY", rate: "155.3", amount: New(1999, "USD"), want: New(3104, "JPY")},
+		{from: "JPY", to: "USD", rate: "0.00643915", amount: New(3104, "JPY"), want: New(1999, "USD")},
+		{from: "USD", to: "EUR", rate: "0.925", amount: New(-1, "USD"), want: New(-1, "EUR")},
+	}
+
+	for _, test := range tests {
+		rate, err := ParseRate(test.from, test.to, test.rate, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := rate.Convert(test.amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Fatalf("%s %s at %s: wanted %v, got %v", test.amount, test.from, test.rate, test.want, got)
+		}
+	}
+
+	rate, _ := ParseRate("USD", "EUR", "0.92", time.Time{})
+	if _, err := rate.Convert(New(100, "EUR")); !errors.Is(err, ErrInvalidRate) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidRate, err)
+	}
+	for _, value := range []string{"0", "-1", "1e3", "abc"} {
+		if _, err := ParseRate("USD", "EUR", value, time.Time{}); !errors.Is(err, ErrInvalidRate) {
+			t.Fatalf("%s: wanted %v, got %v", value, ErrInvalidRate, err)
+		}
+	}
+}
+
+func TestRateNumeric(t *testing.T) {
+	rate, err := ParseRate("USD", "JPY", "155.123456789", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.String() != "155.12345679" {
+		t.Fatalf("wanted %s, got %s", "155.12345679", rate.String())
+	}
+
+	typeMap := pgtype.NewMap()
+	buf, err := typeMap.Encode(pgtype.NumericOID, pgtype.BinaryFormatCode, rate, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanned := Rate{From: "USD", To: "JPY"}
+	if err := typeMap.Scan(pgtype.NumericOID, pgtype.BinaryFormatCode, buf, &scanned); err != nil {
+		t.Fatal(err)
+	}
+	if scanned.Value.Cmp(rate.Value) != 0 {
+		t.Fatalf("wanted %s, got %s", rate, scanned)
+	}
+}
+
+func TestRates(t *testing.T) {
+	rates := &Rates{
+		Base: "USD",
+		Values: map[string]*big.Rat{
+			"EUR": big.NewRat(92, 100),
+			"GBP": big.NewRat(80, 100),
+		},
+	}
+
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{from: "USD", to: "EUR", want: "0.92"},
+		{from: "EUR", to: "USD", want: "1.08695652"},
+		{from: "EUR", to: "GBP", want: "0.86956522"},
+		{from: "GBP", to: "GBP", want: "1"},
+	}
+	for _, test := range tests {
+		rate, err := rates.Rate(test.from, test.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rate.String() != test.want {
+			t.Fatalf("%s to %s: wanted %s, got %s", test.from, test.to, test.want, rate)
+		}
+	}
+
+	if _, err := rates.Rate("USD", "JPY"); !errors.Is(err, ErrUnknownRate) {
+		t.Fatalf("wanted %v, got %v", ErrUnknownRate, err)
+	}
+}
+
+func TestRateCache(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"base": "USD", "as_of": "2024-05-01T00:00:00Z", "rates": {"EUR": "0.92", "JPY": 155.3}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewRateCache(NewFileRates(path), time.Hour)
+	rate, err := cache.Rate(ctx, "JPY", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.String() != "0.00592402" || !rate.AsOf.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("wanted %s as of %s, got %s as of %s", "0.00592402", "2024-05-01", rate, rate.AsOf)
+	}
+
+	// The rates are kept until they expire, even if the file goes away.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Rate(ctx, "USD", "EUR"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewRateCache(NewFileRates(path), time.Hour).Rate(ctx, "USD", "EUR"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("wanted %v, got %v", os.ErrNotExist, err)
+	}
+}
+
+// failingRates is a RateSource that always fails and counts its calls.
+type failingRates struct {
+	calls int
+}
+
+func (fr *failingRates) LoadRates(ctx context.Context) (*Rates, error) {
+	fr.calls++
+	return nil, os.ErrNotExist
+}
+
+func TestRateCacheBackoff(t *testing.T) {
+	ctx := context.Background()
+	source := &failingRates{}
+	cache := NewRateCache(source, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Rate(ctx, "USD", "EUR"); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("wanted %v, got %v", os.ErrNotExist, err)
+		}
+	}
+	// The source is not called again until the backoff delay passes.
+	if source.calls != 1 {
+		t.Fatalf("wanted %d, got %d", 1, source.calls)
+	}
+
+	cache.retryAt = time.Now()
+	if _, err := cache.Rate(ctx, "USD", "EUR"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("wanted %v, got %v", os.ErrNotExist, err)
+	}
+	if source.calls != 2 || time.Until(cache.retryAt) <= rateRetryDelay {
+		t.Fatalf("wanted %d calls and a longer delay, got %d calls and %s", 2, source.calls, time.Until(cache.retryAt))
+	}
+}
@@ -0,0 +1,203 @@
+package money
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRateTTL is how long a RateCache keeps its rates when no TTL is given.
+	DefaultRateTTL = 10 * time.Minute
+	// rateRetryDelay is how long a RateCache waits before loading the rates again after a first
+	// failure, the delay doubles with every failure up to the TTL.
+	rateRetryDelay = time.Second
+)
+
+// RateProvider returns the exchange rates used to convert amounts between currencies.
+// Rate returns an error wrapping ErrUnknownRate when it has no rate between the currencies.
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (Rate, error)
+}
+
+// RateSource loads the latest exchange rates, such as from a file or a database table.
+type RateSource interface {
+	LoadRates(ctx context.Context) (*Rates, error)
+}
+
+// Rates are exchange rates from a Base currency, one unit of Base is worth Values[currency]
+// units of every other currency. AsOf is when the rates were published.
+type Rates struct {
+	Base   string
+	Values map[string]*big.Rat
+	AsOf   time.Time
+}
+
+// Rate returns the rate from one currency to another, crossing through the base currency when
+// neither of them is the base currency.
+func (r *Rates) Rate(from, to string) (Rate, error) {
+	if from == to {
+		return Identity(from, r.AsOf), nil
+	}
+
+	fromValue, toValue := r.value(from), r.value(to)
+	if fromValue == nil || toValue == nil {
+		return Rate{}, fmt.Errorf("%w: %s to %s", ErrUnknownRate, from, to)
+	}
+
+	return NewRate(from, to, new(big.Rat).Quo(toValue, fromValue), r.AsOf)
+}
+
+// value returns the value of one unit of Base in the given currency, nil if it is unknown.
+func (r *Rates) value(currency string) *big.Rat {
+	if currency == r.Base {
+		return big.NewRat(1, 1)
+	}
+
+	value := r.Values[currency]
+	if value == nil || value.Sign() <= 0 {
+		return nil
+	}
+
+	return value
+}
+
+type fileRates struct {
+	path string
+}
+
+// NewFileRates returns a RateSource reading the rates from a JSON file such as
+//
+//	{"base": "USD", "as_of": "2024-05-01T00:00:00Z", "rates": {"EUR": "0.92", "JPY": "155.3"}}
+//
+// The rates can be decimal strings or numbers, as_of defaults to the modification time of the file.
+func NewFileRates(path string) RateSource {
+	return &fileRates{
+		path: path,
+	}
+}
+
+func (fr *fileRates) LoadRates(ctx context.Context) (*Rates, error) {
+	data, err := os.ReadFile(fr.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Base  string                 `json:"base"`
+		AsOf  time.Time              `json:"as_of"`
+		Rates map[string]json.Number `json:"rates"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("exchange rates file %s: %w", fr.path, err)
+	}
+	if file.Base == "" {
+		file.Base = DefaultCurrency
+	}
+	if file.AsOf.IsZero() {
+		info, err := os.Stat(fr.path)
+		if err != nil {
+			return nil, err
+		}
+		file.AsOf = info.ModTime()
+	}
+
+	rates := &Rates{
+		Base:   file.Base,
+		Values: make(map[string]*big.Rat, len(file.Rates)),
+		AsOf:   file.AsOf,
+	}
+	for currency, value := range file.Rates {
+		rate, err := ParseRate(file.Base, currency, value.String(), file.AsOf)
+		if err != nil {
+			return nil, fmt.Errorf("exchange rates file %s: %w", fr.path, err)
+		}
+		rates.Values[currency] = rate.Value
+	}
+
+	return rates, nil
+}
+
+// RateCache is a RateProvider that loads the rates of a RateSource and keeps them for a TTL.
+// When reloading them fails it keeps using the rates it has, the AsOf of the rates it returns
+// tells how old they are, and only fails if it never loaded any. After a failure the source is
+// not called again until a backoff delay passes, the last error is returned in the meantime.
+type RateCache struct {
+	source RateSource
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	rates    *Rates
+	loadedAt time.Time
+	// err is the error of the last load, failures the number of loads that failed in a row and
+	// retryAt when the source can be called again after them.
+	err      error
+	failures int
+	retryAt  time.Time
+}
+
+// NewRateCache returns a RateCache of the given source, a zero ttl is DefaultRateTTL.
+func NewRateCache(source RateSource, ttl time.Duration) *RateCache {
+	if ttl == 0 {
+		ttl = DefaultRateTTL
+	}
+
+	return &RateCache{
+		source: source,
+		ttl:    ttl,
+	}
+}
+
+func (rc *RateCache) Rate(ctx context.Context, from, to string) (Rate, error) {
+	if from == to {
+		return Identity(from, time.Now()), nil
+	}
+
+	rates, err := rc.Rates(ctx)
+	if err != nil {
+		return Rate{}, err
+	}
+
+	return rates.Rate(from, to)
+}
+
+// Rates returns the cached rates, loading them from the source once they expire.
+func (rc *RateCache) Rates(ctx context.Context) (*Rates, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := time.Now()
+	if rc.rates != nil && now.Sub(rc.loadedAt) < rc.ttl {
+		return rc.rates, nil
+	}
+	if now.Before(rc.retryAt) {
+		return rc.cached()
+	}
+
+	rates, err := rc.source.LoadRates(ctx)
+	if err != nil {
+		delay := min(rateRetryDelay<<rc.failures, rc.ttl)
+		if delay < rc.ttl {
+			rc.failures++
+		}
+		rc.err, rc.retryAt = err, now.Add(delay)
+		return rc.cached()
+	}
+	rc.rates, rc.loadedAt = rates, now
+	rc.err, rc.failures, rc.retryAt = nil, 0, time.Time{}
+
+	return rates, nil
+}
+
+// cached returns the rates loaded last, or the error of the last load if it never loaded any.
+func (rc *RateCache) cached() (*Rates, error) {
+	if rc.rates != nil {
+		return rc.rates, nil
+	}
+
+	return nil, rc.err
+}
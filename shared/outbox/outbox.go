@@ -0,0 +1,156 @@
+// Package outbox implements the transactional outbox: services write their domain events to the
+// outbox table in the same transaction as the change they describe and a Relay publishes them
+// afterwards, so an event is published if and only if its change was committed.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// lockKey is hashed into the key of the advisory lock held while relaying, so a single relay
+	// per database publishes at a time and events keep their order.
+	lockKey   = "outbox"
+	batchSize = 100
+	// publishedRetention is how long published events are kept before they are removed.
+	publishedRetention = 7 * 24 * time.Hour
+)
+
+// Add writes an event about the given aggregate to the outbox within tx, payload is marshalled to JSON.
+func Add(ctx context.Context, tx pgx.Tx, aggregateType string, aggregateID int, eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO outbox(aggregate_type, aggregate_id, event_type, payload) VALUES($1, $2, $3, $4)", aggregateType, strconv.Itoa(aggregateID), eventType, data)
+	return err
+}
+
+// Relay publishes the events written to the outbox of a service database.
+// Delivery is at least once: an event is marked as published only after the publisher
+// accepted it, so it can be published again if the relay stops in between.
+type Relay struct {
+	db        *pgxpool.Pool
+	source    string
+	publisher Publisher
+	logger    *slog.Logger
+}
+
+// NewRelay returns a Relay publishing the events of the given database, on behalf of the source service, with publisher.
+func NewRelay(db *pgxpool.Pool, source string, publisher Publisher, logger *slog.Logger) *Relay {
+	return &Relay{
+		db:        db,
+		source:    source,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// PublishPending publishes a batch of pending events in the order they were written and returns how many were published.
+// When an event cannot be published the following events of its aggregate are held back until the next call,
+// so the events of an aggregate are never delivered out of order. Another relay holding the database
+// lock makes it a no-op.
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var locked bool
+	if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock(hashtext($1))", lockKey).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(ctx, "SELECT id, event_id::text, aggregate_type, aggregate_id, event_type, payload, created_at FROM outbox WHERE published_at IS NULL ORDER BY id LIMIT $1", batchSize)
+	if err != nil {
+		return 0, err
+	}
+	type pendingEvent struct {
+		id    int64
+		event events.Event
+	}
+	var pending []pendingEvent
+	var p pendingEvent
+	_, err = pgx.ForEachRow(rows, []any{&p.id, &p.event.ID, &p.event.AggregateType, &p.event.AggregateID, &p.event.Type, &p.event.Payload, &p.event.CreatedAt}, func() error {
+		p.event.Source = r.source
+		pending = append(pending, p)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var published []int64
+	var errs []error
+	blocked := make(map[string]bool)
+	for _, p := range pending {
+		aggregate := p.event.AggregateType + ":" + p.event.AggregateID
+		if blocked[aggregate] {
+			continue
+		}
+		if err := r.publisher.Publish(ctx, p.event); err != nil {
+			blocked[aggregate] = true
+			errs = append(errs, fmt.Errorf("event %s: %w", p.event.ID, err))
+			continue
+		}
+		published = append(published, p.id)
+	}
+
+	if len(published) > 0 {
+		if _, err := tx.Exec(ctx, "UPDATE outbox SET published_at = CURRENT_TIMESTAMP WHERE id = ANY($1)", published); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return len(published), errors.Join(errs...)
+}
+
+// DeletePublished removes the events published more than publishedRetention ago and returns how many were removed.
+func (r *Relay) DeletePublished(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM outbox WHERE published_at < CURRENT_TIMESTAMP - make_interval(secs => $1)", publishedRetention.Seconds())
+	return tag.RowsAffected(), err
+}
+
+// Run publishes the pending events every interval until ctx is done, removing the old published ones along the way.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				published, err := r.PublishPending(ctx)
+				if err != nil {
+					r.logger.ErrorContext(ctx, "error at Run", slog.String("error", err.Error()))
+				}
+				if published < batchSize {
+					break
+				}
+			}
+			if _, err := r.DeletePublished(ctx); err != nil {
+				r.logger.ErrorContext(ctx, "error at Run", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
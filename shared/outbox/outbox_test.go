@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/PseudoMera/virtual-store/user/migrations"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestMemoryBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewMemoryBus()
+
+	var handled []string
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		if event.Type == events.ProductUpdated {
+			return errors.New("handler failed")
+		}
+		handled = append(handled, event.ID)
+		return nil
+	})
+
+	if err := bus.Publish(ctx, events.Event{ID: "1", Type: events.ProductCreated}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Publish(ctx, events.Event{ID: "2", Type: events.ProductUpdated}); err == nil {
+		t.Fatalf("wanted the handler error")
+	}
+
+	if len(handled) != 1 {
+		t.Fatalf("wanted %d, got %d", 1, len(handled))
+	}
+	if published := bus.Events(); len(published) != 1 || published[0].ID != "1" {
+		t.Fatalf("wanted only event %s to be published, got %+v", "1", published)
+	}
+}
+
+func addEvent(t *testing.T, ctx context.Context, db *pgxpool.Pool, productID, stock int, commit bool) {
+	t.Helper()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if err := Add(ctx, tx, events.AggregateProduct, productID, events.ProductStockChanged, events.ProductStockChangedPayload{
+		ProductID: productID,
+		Stock:     stock,
+		Reason:    events.StockSet,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if commit {
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	// The outbox table is created by the migrations of every service.
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addEvent(t, ctx, db.DB(), 1, 10, true)
+	addEvent(t, ctx, db.DB(), 2, 20, true)
+	addEvent(t, ctx, db.DB(), 1, 11, true)
+	addEvent(t, ctx, db.DB(), 1, 12, false)
+
+	bus := NewMemoryBus()
+	failed := false
+	bus.Subscribe(func(ctx context.Context, event events.Event) error {
+		var payload events.ProductStockChangedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return err
+		}
+		if payload.Stock == 10 && !failed {
+			failed = true
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	relay := NewRelay(db.DB(), "product", bus, slog.Default())
+
+	// The first event of product 1 fails, its second event must wait for it.
+	published, err := relay.PublishPending(ctx)
+	if err == nil {
+		t.Fatalf("wanted the publisher error")
+	}
+	if published != 1 {
+		t.Fatalf("wanted %d, got %d", 1, published)
+	}
+
+	published, err = relay.PublishPending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 2 {
+		t.Fatalf("wanted %d, got %d", 2, published)
+	}
+
+	published, err = relay.PublishPending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 0 {
+		t.Fatalf("wanted %d, got %d", 0, published)
+	}
+
+	wanted := []string{"2", "1", "1"}
+	got := bus.Events()
+	if len(got) != len(wanted) {
+		t.Fatalf("wanted %d, got %d", len(wanted), len(got))
+	}
+	for i, event := range got {
+		if event.AggregateID != wanted[i] {
+			t.Fatalf("wanted %s, got %s", wanted[i], event.AggregateID)
+		}
+		if event.Source != "product" || event.ID == "" {
+			t.Fatalf("wanted a product event with an id, got %+v", event)
+		}
+	}
+}
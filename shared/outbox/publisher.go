@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// Channel is the Postgres notification channel LogPublisher wakes the consumers up on.
+	Channel = "vstore_events"
+	// logLockKey is hashed into the key of the advisory lock held while appending to the event
+	// log, so events are committed in the order of their ids and consumers never skip one.
+	logLockKey = "event_log"
+	// logRetention is how long events are kept in the event log once every consumer read them.
+	logRetention = 7 * 24 * time.Hour
+)
+
+// Publisher delivers events to their consumers. Publish must only return nil once the event was
+// accepted, the relay publishes it again otherwise.
+type Publisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+// LogPublisher publishes events by appending them to the event_log table of the events database,
+// the event bus, which consumers read from their own cursor. Every service publishing and
+// consuming on the same database sees every event. A notification without the event is sent on
+// the channel to wake the consumers up, they read the log anyway if it is missed.
+type LogPublisher struct {
+	db      *pgxpool.Pool
+	channel string
+}
+
+// NewLogPublisher returns a LogPublisher appending to the event log of the db event bus and
+// notifying on the given channel. The event log is created by the migrations of the shared/events/migrations package.
+func NewLogPublisher(db *pgxpool.Pool, channel string) *LogPublisher {
+	return &LogPublisher{
+		db:      db,
+		channel: channel,
+	}
+}
+
+// Publish appends the event to the log, an event that was already published is not appended again.
+func (p *LogPublisher) Publish(ctx context.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", logLockKey); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO event_log(event_id, event) VALUES($1, $2) ON CONFLICT (event_id) DO NOTHING", event.ID, data); err != nil {
+		return err
+	}
+	// Notifications are only sent once the transaction commits.
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, '')", p.channel); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteExpired removes the events older than logRetention that every consumer read and returns how many were removed.
+func (p *LogPublisher) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := p.db.Exec(ctx, "DELETE FROM event_log WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => $1) AND id <= (SELECT COALESCE(MIN(position), 0) FROM consumer_cursor)", logRetention.Seconds())
+	return tag.RowsAffected(), err
+}
+
+// RunExpiry removes the expired events every interval until ctx is done.
+func (p *LogPublisher) RunExpiry(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.DeleteExpired(ctx); err != nil {
+				logger.ErrorContext(ctx, "error at RunExpiry", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Handler consumes an event.
+type Handler func(ctx context.Context, event events.Event) error
+
+// MemoryBus is an in memory Publisher for tests. Published events are kept and handed to the
+// subscribed handlers synchronously, a handler error fails the publication.
+type MemoryBus struct {
+	mu       sync.Mutex
+	events   []events.Event
+	handlers []Handler
+}
+
+// NewMemoryBus returns an empty MemoryBus.
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{}
+}
+
+// Subscribe adds a handler called with every event published from now on.
+func (b *MemoryBus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, event events.Event) error {
+	b.mu.Lock()
+	handlers := append([]Handler(nil), b.handlers...)
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+
+	return nil
+}
+
+// Events returns the events published so far, oldest first.
+func (b *MemoryBus) Events() []events.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]events.Event(nil), b.events...)
+}
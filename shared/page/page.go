@@ -0,0 +1,178 @@
+// Package page implements keyset pagination for the list endpoints. A page is selected with the
+// sort column and the id of the last row of the previous page, carried by an opaque cursor, so
+// pages stay stable while rows are inserted and deep pages cost as much as the first one.
+package page
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/PseudoMera/virtual-store/shared"
+)
+
+const (
+	// DefaultLimit is the size of a page when the request does not set one.
+	DefaultLimit = 20
+	// MaxLimit is the largest page size, larger limits are lowered to it.
+	MaxLimit = 100
+)
+
+var (
+	ErrInvalidLimit  = shared.InvalidFieldError("limit", "limit cannot be negative")
+	ErrInvalidSort   = shared.InvalidFieldError("sort", "unknown sort field")
+	ErrInvalidCursor = shared.InvalidFieldError("cursor", "cursor is invalid or was issued for another sort")
+	// ErrSortCurrency is returned when sorting by a price without selecting a currency, amounts of
+	// different currencies cannot be compared.
+	ErrSortCurrency = shared.InvalidFieldError("currency", "sorting by price requires a currency")
+)
+
+// Request selects a page of a list. Cursor is the NextCursor of the previous page, empty for the
+// first one, and must be used with the same Sort and Desc. Sort defaults to the list default and
+// WithTotal asks for the number of rows matching the filters across all pages.
+type Request struct {
+	Limit     int    `json:"limit"`
+	Cursor    string `json:"cursor"`
+	Sort      string `json:"sort"`
+	Desc      bool   `json:"desc"`
+	WithTotal bool   `json:"with_total"`
+}
+
+// Page is a page of a list. NextCursor is empty on the last page and Total is only set when requested.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// SortField is a column a list can be sorted by. Type is its SQL type, cursor values are cast to it.
+type SortField struct {
+	Column string
+	Type   string
+}
+
+// cursor is the position after which the next page starts: the sort value and id of the last row.
+type cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c) //nolint:errcheck
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// Query builds the WHERE clause of a list query, numbering its arguments.
+type Query struct {
+	conditions []string
+	args       []any
+}
+
+// Arg adds an argument to the query and returns its placeholder.
+func (q *Query) Arg(value any) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// Where adds a condition, conditions are joined with AND.
+func (q *Query) Where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// Conditions returns the WHERE clause, empty if there are no conditions.
+func (q *Query) Conditions() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// Args returns the arguments of the query.
+func (q *Query) Args() []any {
+	return q.args
+}
+
+// Apply resolves the sort and limit of r, adds the condition selecting the rows after the cursor
+// to q and returns the ORDER BY and LIMIT clauses. One row more than the limit is selected so New
+// knows whether there is a next page. The id column breaks ties between equal sort values.
+func (r *Request) Apply(q *Query, fields map[string]SortField, defaultSort string) (string, error) {
+	if r.Limit < 0 {
+		return "", ErrInvalidLimit
+	}
+	if r.Limit == 0 {
+		r.Limit = DefaultLimit
+	}
+	r.Limit = min(r.Limit, MaxLimit)
+	if r.Sort == "" {
+		r.Sort = defaultSort
+	}
+	field, ok := fields[r.Sort]
+	if !ok {
+		return "", ErrInvalidSort
+	}
+
+	direction, op := "ASC", ">"
+	if r.Desc {
+		direction, op = "DESC", "<"
+	}
+
+	if r.Cursor != "" {
+		c, err := decodeCursor(r.Cursor)
+		if err != nil {
+			return "", err
+		}
+		if c.Sort != r.Sort || c.Desc != r.Desc {
+			return "", ErrInvalidCursor
+		}
+		if field.Column == "id" {
+			q.Where(fmt.Sprintf("id %s %s", op, q.Arg(c.ID)))
+		} else {
+			q.Where(fmt.Sprintf("(%s, id) %s (%s::%s, %s)", field.Column, op, q.Arg(c.Value), field.Type, q.Arg(c.ID)))
+		}
+	}
+
+	if field.Column == "id" {
+		return fmt.Sprintf(" ORDER BY id %s LIMIT %s", direction, q.Arg(r.Limit+1)), nil
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", field.Column, direction, direction, q.Arg(r.Limit+1)), nil
+}
+
+// New returns the page of the items selected with the clauses of Apply. key returns the value of
+// the sort column of r and the id of an item, they make the cursor of the next page.
+func New[T any](items []T, r Request, key func(item T, sort string) (string, int)) *Page[T] {
+	p := &Page[T]{
+		Items: items,
+	}
+	if len(items) > r.Limit {
+		p.Items = items[:r.Limit]
+		value, id := key(p.Items[r.Limit-1], r.Sort)
+		p.NextCursor = encodeCursor(cursor{
+			Sort:  r.Sort,
+			Desc:  r.Desc,
+			Value: value,
+			ID:    id,
+		})
+	}
+	if p.Items == nil {
+		p.Items = []T{}
+	}
+
+	return p
+}
@@ -0,0 +1,85 @@
+package page
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+var testFields = map[string]SortField{
+	"id":    {Column: "id", Type: "int"},
+	"price": {Column: "price", Type: "numeric"},
+}
+
+type item struct {
+	id    int
+	price float64
+}
+
+func itemKey(i item, sort string) (string, int) {
+	return strconv.FormatFloat(i.price, 'f', -1, 64), i.id
+}
+
+func TestApply(t *testing.T) {
+	var q Query
+	q.Where("user_id = " + q.Arg(1))
+
+	r := Request{Limit: 500}
+	orderBy, err := r.Apply(&q, testFields, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Limit != MaxLimit || r.Sort != "id" {
+		t.Fatalf("wanted limit %d sorted by %s, got %d sorted by %s", MaxLimit, "id", r.Limit, r.Sort)
+	}
+	if wanted := " ORDER BY id ASC LIMIT $2"; orderBy != wanted {
+		t.Fatalf("wanted %s, got %s", wanted, orderBy)
+	}
+
+	p := New([]item{{id: 1, price: 10}, {id: 2, price: 10.5}, {id: 3, price: 11}}, Request{Limit: 2, Sort: "price", Desc: true}, itemKey)
+	if len(p.Items) != 2 || p.NextCursor == "" {
+		t.Fatalf("wanted %d items and a cursor, got %d items and %q", 2, len(p.Items), p.NextCursor)
+	}
+
+	q = Query{}
+	r = Request{Sort: "price", Desc: true, Cursor: p.NextCursor}
+	orderBy, err = r.Apply(&q, testFields, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wanted := " WHERE (price, id) < ($1::numeric, $2)"; q.Conditions() != wanted {
+		t.Fatalf("wanted %s, got %s", wanted, q.Conditions())
+	}
+	if wanted := " ORDER BY price DESC, id DESC LIMIT $3"; orderBy != wanted {
+		t.Fatalf("wanted %s, got %s", wanted, orderBy)
+	}
+	if args := q.Args(); args[0] != "10.5" || args[1] != 2 || args[2] != DefaultLimit+1 {
+		t.Fatalf("wanted the key of item %d, got %v", 2, args)
+	}
+
+	p = New([]item{{id: 1}}, Request{Limit: 2}, itemKey)
+	if p.NextCursor != "" {
+		t.Fatalf("wanted no cursor on the last page, got %s", p.NextCursor)
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	cursor := New([]item{{id: 1}, {id: 2}}, Request{Limit: 1, Sort: "price"}, itemKey).NextCursor
+
+	tests := map[error]Request{
+		ErrInvalidLimit:  {Limit: -1},
+		ErrInvalidSort:   {Sort: "name"},
+		ErrInvalidCursor: {Cursor: "not a cursor"},
+	}
+	for wanted, r := range tests {
+		if _, err := r.Apply(&Query{}, testFields, "id"); !errors.Is(err, wanted) {
+			t.Fatalf("wanted %v, got %v", wanted, err)
+		}
+	}
+
+	// A cursor only continues the sort it was issued for.
+	r := Request{Sort: "price", Desc: true, Cursor: cursor}
+	if _, err := r.Apply(&Query{}, testFields, "id"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("wanted %v, got %v", ErrInvalidCursor, err)
+	}
+}
@@ -3,6 +3,7 @@ package shared
 import (
 	"context"
 
+	"github.com/PseudoMera/virtual-store/shared/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -10,8 +11,15 @@ type PostgresDB struct {
 	db *pgxpool.Pool
 }
 
+// NewPostgresDatabase connects to the database, tracing the queries run within a span.
 func NewPostgresDatabase(ctx context.Context, connectionStr string) (*PostgresDB, error) {
-	pool, err := pgxpool.New(ctx, connectionStr)
+	config, err := pgxpool.ParseConfig(connectionStr)
+	if err != nil {
+		return nil, err
+	}
+	config.ConnConfig.Tracer = tracing.QueryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -27,3 +35,8 @@ func NewPostgresDatabase(ctx context.Context, connectionStr string) (*PostgresDB
 func (pg *PostgresDB) DB() *pgxpool.Pool {
 	return pg.db
 }
+
+// Close closes all the connections of the pool, waiting for the ones in use to be released.
+func (pg *PostgresDB) Close() {
+	pg.db.Close()
+}
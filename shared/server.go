@@ -0,0 +1,144 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Exit codes returned by ServerRunner.Run.
+const (
+	ExitOK            = 0
+	ExitServerError   = 1
+	ExitShutdownError = 2
+)
+
+const (
+	shutdownTimeout   = 15 * time.Second
+	readHeaderTimeout = 10 * time.Second
+)
+
+// ServerRunner runs the HTTP and gRPC servers of a service side by side until the process
+// receives SIGINT or SIGTERM or either server fails, then shuts both down gracefully.
+type ServerRunner struct {
+	httpServer     *http.Server
+	grpcAddr       string
+	grpcServer     *grpc.Server
+	beforeShutdown []func()
+	onShutdown     []func()
+	logger         *slog.Logger
+}
+
+// NewServerRunner returns a ServerRunner serving handler over HTTP on httpAddr and
+// grpcServer on grpcAddr. Addresses have the form ":3000".
+func NewServerRunner(httpAddr string, handler http.Handler, grpcAddr string, grpcServer *grpc.Server, logger *slog.Logger) *ServerRunner {
+	return &ServerRunner{
+		httpServer: &http.Server{
+			Addr:              httpAddr,
+			Handler:           handler,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+		grpcAddr:   grpcAddr,
+		grpcServer: grpcServer,
+		logger:     logger,
+	}
+}
+
+// BeforeShutdown registers a function to call before the servers start draining, such as
+// reporting the service as not ready. Functions are called in the order they were registered.
+func (sr *ServerRunner) BeforeShutdown(fn func()) {
+	sr.beforeShutdown = append(sr.beforeShutdown, fn)
+}
+
+// OnShutdown registers a function to call once both servers stopped, such as closing the database pool.
+// Functions are called in the order they were registered.
+func (sr *ServerRunner) OnShutdown(fn func()) {
+	sr.onShutdown = append(sr.onShutdown, fn)
+}
+
+// Run starts both servers and blocks until ctx is done, a termination signal is received or
+// either server fails. In-flight HTTP requests and gRPC calls are then drained for up to 15
+// seconds before the shutdown functions run.
+// Returns ExitServerError if a server failed, ExitShutdownError if the servers could not be
+// drained in time and ExitOK otherwise, meant to be passed to os.Exit.
+func (sr *ServerRunner) Run(ctx context.Context) int {
+	defer sr.shutdown()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lis, err := net.Listen("tcp", sr.grpcAddr)
+	if err != nil {
+		sr.logger.Error("error at Run", slog.String("error", fmt.Errorf("grpc server: %w", err).Error()))
+		return ExitServerError
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		if err := sr.grpcServer.Serve(lis); err != nil {
+			errs <- fmt.Errorf("grpc server: %w", err)
+		}
+	}()
+	go func() {
+		if err := sr.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("http server: %w", err)
+		}
+	}()
+	sr.logger.Info("servers started", slog.String("http", sr.httpServer.Addr), slog.String("grpc", lis.Addr().String()))
+
+	code := ExitOK
+	select {
+	case <-ctx.Done():
+		sr.logger.Info("shutting down servers")
+	case err := <-errs:
+		sr.logger.Error("error at Run", slog.String("error", err.Error()))
+		code = ExitServerError
+	}
+
+	for _, fn := range sr.beforeShutdown {
+		fn()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := sr.httpServer.Shutdown(shutdownCtx); err != nil {
+		sr.logger.Error("error at Run", slog.String("error", fmt.Errorf("http server shutdown: %w", err).Error()))
+		sr.httpServer.Close() //nolint:errcheck
+		if code == ExitOK {
+			code = ExitShutdownError
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		sr.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		sr.logger.Error("error at Run", slog.String("error", "grpc server shutdown: "+shutdownCtx.Err().Error()))
+		sr.grpcServer.Stop()
+		if code == ExitOK {
+			code = ExitShutdownError
+		}
+	}
+
+	return code
+}
+
+func (sr *ServerRunner) shutdown() {
+	for _, fn := range sr.onShutdown {
+		fn()
+	}
+}
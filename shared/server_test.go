@@ -0,0 +1,61 @@
+package shared
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestServerRunnerShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	runner := NewServerRunner("127.0.0.1:0", http.NotFoundHandler(), "127.0.0.1:0", grpc.NewServer(), slog.Default())
+	var closed []string
+	runner.OnShutdown(func() { closed = append(closed, "first") })
+	runner.OnShutdown(func() { closed = append(closed, "second") })
+
+	done := make(chan int)
+	go func() {
+		done <- runner.Run(ctx)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case code := <-done:
+		if code != ExitOK {
+			t.Fatalf("wanted %d, got %d", ExitOK, code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("servers did not shut down")
+	}
+	if len(closed) != 2 || closed[0] != "first" || closed[1] != "second" {
+		t.Fatalf("wanted %v, got %v", []string{"first", "second"}, closed)
+	}
+}
+
+func TestServerRunnerServerError(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	// The HTTP address is already in use so the HTTP server fails to start.
+	runner := NewServerRunner(lis.Addr().String(), http.NotFoundHandler(), "127.0.0.1:0", grpc.NewServer(), slog.Default())
+	closed := false
+	runner.OnShutdown(func() { closed = true })
+
+	if code := runner.Run(context.Background()); code != ExitServerError {
+		t.Fatalf("wanted %d, got %d", ExitServerError, code)
+	}
+	if !closed {
+		t.Fatal("wanted shutdown functions to run")
+	}
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters supported by NewExporter.
+const (
+	// ExporterNone disables tracing, it is used when no exporter is configured.
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector over gRPC.
+	// The collector is configured with the standard OTEL_EXPORTER_OTLP_* env variables.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans as JSON to the standard output.
+	ExporterStdout = "stdout"
+)
+
+const instrumentationName = "github.com/PseudoMera/virtual-store/shared/tracing"
+
+// tracer returns the tracer of the global provider installed by Setup.
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+var errUnknownExporter = errors.New("unknown trace exporter")
+
+// NewExporter returns the span exporter with the given name, nil for ExporterNone or an empty name.
+// Tests can pass a tracetest.InMemoryExporter to Setup instead.
+func NewExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		return otlptracegrpc.New(ctx)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownExporter, name)
+	}
+}
+
+// Setup installs a global tracer provider exporting the spans of the given service, and the
+// W3C trace context and baggage propagators. With a nil exporter spans are still propagated
+// so traces are not broken by a service that does not export them.
+// The returned provider must be shut down on exit to flush the pending spans.
+func Setup(serviceName string, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+
+	return provider
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
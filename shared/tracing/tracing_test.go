@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// setupExporter installs a global provider exporting synchronously to an in-memory exporter.
+func setupExporter(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) }) //nolint:errcheck
+
+	return exporter
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := setupExporter(t)
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/api/v1/product/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/product/1", nil)
+	req.Header.Set("traceparent", traceparent)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("wanted %d, got %d", 1, len(spans))
+	}
+	if spans[0].Name != "GET /api/v1/product/{id}" {
+		t.Fatalf("wanted %s, got %s", "GET /api/v1/product/{id}", spans[0].Name)
+	}
+	if got := spans[0].SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("wanted %s, got %s", "4bf92f3577b34da6a3ce929d0e0e4736", got)
+	}
+	if spans[0].Status.Code.String() != "Error" {
+		t.Fatalf("wanted %s, got %s", "Error", spans[0].Status.Code)
+	}
+}
+
+func TestGRPCPropagation(t *testing.T) {
+	exporter := setupExporter(t)
+	const method = "/grpc.ProductService/GetProduct"
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	var serverSpan trace.SpanContext
+	server := UnaryServerInterceptor()
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		_, err := server(metadata.NewIncomingContext(context.Background(), md), nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+			serverSpan = trace.SpanContextFromContext(ctx)
+			return nil, status.Error(codes.NotFound, "not found")
+		})
+		return err
+	}
+
+	err := UnaryClientInterceptor()(ctx, method, nil, nil, nil, invoker)
+	parent.End()
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("wanted %s, got %v", codes.NotFound, err)
+	}
+
+	if serverSpan.TraceID() != parent.SpanContext().TraceID() {
+		t.Fatalf("wanted %s, got %s", parent.SpanContext().TraceID(), serverSpan.TraceID())
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("wanted %d, got %d", 3, len(spans))
+	}
+	for _, span := range spans[:2] {
+		if span.Name != "grpc.ProductService/GetProduct" {
+			t.Fatalf("wanted %s, got %s", "grpc.ProductService/GetProduct", span.Name)
+		}
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	exporter := setupExporter(t)
+	tracer := QueryTracer{}
+	data := pgx.TraceQueryStartData{SQL: "SELECT id FROM product WHERE id = $1"}
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, data)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	if len(exporter.GetSpans()) != 0 {
+		t.Fatalf("wanted %d, got %d", 0, len(exporter.GetSpans()))
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	ctx = tracer.TraceQueryStart(ctx, nil, data)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("wanted %d, got %d", 2, len(spans))
+	}
+	if spans[0].Name != "SELECT" {
+		t.Fatalf("wanted %s, got %s", "SELECT", spans[0].Name)
+	}
+	if spans[0].Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("wanted %s, got %s", parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
 	"github.com/PseudoMera/virtual-store/user/service"
 )
 
@@ -37,7 +38,7 @@ func (u *UserAPI) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	id, err := u.service.CreateUser(r.Context(), req.Email, req.Password)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
 		return
 	}
 
@@ -59,7 +60,11 @@ func (u *UserAPI) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := u.service.GetUser(r.Context(), req.Email)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
+		return
+	}
+	if err := auth.Authorize(r.Context(), user.ID); err != nil {
+		shared.WriteError(w, err)
 		return
 	}
 
@@ -85,10 +90,14 @@ func (u *UserAPI) CreateUserProfile(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if err := auth.Authorize(r.Context(), req.UserID); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
 
 	id, err := u.service.CreateUserProfile(r.Context(), req.UserID, req.Name, req.Photo, req.Country, req.Address, req.Phone)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
 		return
 	}
 
@@ -107,10 +116,14 @@ func (u *UserAPI) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if err := auth.Authorize(r.Context(), req.UserID); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
 
 	profile, err := u.service.RetrieveUserProfile(r.Context(), req.UserID)
 	if err != nil {
-		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		shared.WriteError(w, err)
 		return
 	}
 
@@ -132,11 +145,154 @@ func (u *UserAPI) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if err := auth.Authorize(r.Context(), req.UserID); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
 
 	if err := u.service.UpdateUserProfile(r.Context(), req.UserID, req.Name, req.Photo, req.Country, req.Address, req.Phone); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+func newTokenResponse(tokens *service.TokenPair) TokenResponse {
+	return TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
+	}
+}
+
+func (u *UserAPI) Login(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := u.service.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, newTokenResponse(tokens), w)
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (u *UserAPI) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
 		return
 	}
 
+	tokens, err := u.service.RefreshToken(r.Context(), req.RefreshToken)
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, newTokenResponse(tokens), w)
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"`
+}
+
+func (u *UserAPI) Logout(w http.ResponseWriter, r *http.Request) {
+	var req LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := u.service.Logout(r.Context(), req.RefreshToken, req.All); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type AssignRoleRequest struct {
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+func (u *UserAPI) AssignRole(w http.ResponseWriter, r *http.Request) {
+	var req AssignRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := auth.CheckPermission(r.Context(), auth.PermissionUserRole); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := u.service.AssignRole(r.Context(), req.UserID, req.Role); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type DeleteUserRequest struct {
+	UserID int `json:"user_id"`
+}
+
+func (u *UserAPI) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	var req DeleteUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		shared.WriteErrorResponse(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := auth.Authorize(r.Context(), req.UserID); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	if err := u.service.DeleteUser(r.Context(), req.UserID); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (u *UserAPI) GetRoles(w http.ResponseWriter, r *http.Request) {
+	if err := auth.CheckPermission(r.Context(), auth.PermissionUserRole); err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	roles, err := u.service.GetRoles(r.Context())
+	if err != nil {
+		shared.WriteError(w, err)
+		return
+	}
+
+	shared.WriteResponse(http.StatusOK, roles, w)
+}
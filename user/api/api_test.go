@@ -8,16 +8,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/user/migrations"
 
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
 	"github.com/PseudoMera/virtual-store/user/service"
 	"github.com/PseudoMera/virtual-store/user/store"
 	"github.com/go-chi/chi/v5"
 )
 
 const (
-	projectRootPath = "../../"
-
 	testEmail    = "test@test.test"
 	testPassword = "testPassword!!!"
 	testName     = "tester"
@@ -27,15 +29,36 @@ const (
 	testPhone    = "test-test-test"
 )
 
+var testTokenConfig = service.TokenConfig{
+	Secret: []byte("testSecret"),
+}
+
+var testAuthenticator = auth.NewAuthenticator([]byte("testSecret"))
+
+// setAuthorization sets a valid access token for the given user and permissions on the request.
+func setAuthorization(t *testing.T, req *http.Request, userID int, permissions ...string) {
+	t.Helper()
+
+	token, _, err := testAuthenticator.IssueToken(auth.Identity{
+		UserID:      userID,
+		Email:       testEmail,
+		Permissions: permissions,
+	}, auth.AccessToken, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
 func TestCreateUser(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	s := store.NewStore(db.DB())
-	serv := service.NewUserService(s, slog.Default())
+	serv := service.NewUserService(s, slog.Default(), testTokenConfig)
 	api := NewUserAPI(serv)
 	router := chi.NewRouter()
 	router.Post("/api/v1/user", api.CreateUser)
@@ -74,14 +97,14 @@ func TestCreateUser(t *testing.T) {
 
 func TestGetUser(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	s := store.NewStore(db.DB())
 
-	_, err = s.StoreUser(ctx, store.User{
+	userID, err := s.StoreUser(ctx, store.User{
 		Email:    testEmail,
 		Password: testPassword,
 	})
@@ -89,9 +112,10 @@ func TestGetUser(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	serv := service.NewUserService(s, slog.Default())
+	serv := service.NewUserService(s, slog.Default(), testTokenConfig)
 	api := NewUserAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Get("/api/v1/user", api.GetUser)
 
 	ts := httptest.NewServer(router)
@@ -111,6 +135,7 @@ func TestGetUser(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -127,7 +152,7 @@ func TestGetUser(t *testing.T) {
 
 func TestCreateUserProfile(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -141,9 +166,10 @@ func TestCreateUserProfile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	serv := service.NewUserService(s, slog.Default())
+	serv := service.NewUserService(s, slog.Default(), testTokenConfig)
 	api := NewUserAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Post("/api/v1/user/profile", api.CreateUserProfile)
 
 	ts := httptest.NewServer(router)
@@ -168,6 +194,7 @@ func TestCreateUserProfile(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -184,7 +211,7 @@ func TestCreateUserProfile(t *testing.T) {
 
 func TestGetUserProfiel(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -210,9 +237,10 @@ func TestGetUserProfiel(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	serv := service.NewUserService(s, slog.Default())
+	serv := service.NewUserService(s, slog.Default(), testTokenConfig)
 	api := NewUserAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Get("/api/v1/user/profile", api.GetUserProfile)
 
 	ts := httptest.NewServer(router)
@@ -232,6 +260,7 @@ func TestGetUserProfiel(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -248,7 +277,7 @@ func TestGetUserProfiel(t *testing.T) {
 
 func TestUpdateUserProfile(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -274,9 +303,10 @@ func TestUpdateUserProfile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	serv := service.NewUserService(s, slog.Default())
+	serv := service.NewUserService(s, slog.Default(), testTokenConfig)
 	api := NewUserAPI(serv)
 	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
 	router.Put("/api/v1/user/profile", api.UpdateUserProfile)
 
 	ts := httptest.NewServer(router)
@@ -301,6 +331,7 @@ func TestUpdateUserProfile(t *testing.T) {
 	}
 
 	req.Header.Set("Content-type", "application/json")
+	setAuthorization(t, req, userID)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -314,3 +345,253 @@ func TestUpdateUserProfile(t *testing.T) {
 		t.Fatalf("wanted %d, got %d", http.StatusNoContent, status)
 	}
 }
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := store.NewStore(db.DB())
+	_, err = s.StoreUser(ctx, store.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serv := service.NewUserService(s, slog.Default(), testTokenConfig)
+	api := NewUserAPI(serv)
+	router := chi.NewRouter()
+	router.Post("/api/v1/user/login", api.Login)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	loginReq := LoginRequest{
+		Email:    testEmail,
+		Password: testPassword,
+	}
+	loginReqBytes, err := json.Marshal(loginReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", ts.URL+"/api/v1/user/login", bytes.NewBuffer(loginReqBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusOK {
+		t.Fatalf("wanted %d, got %d", http.StatusOK, status)
+	}
+
+	var tokens TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		t.Fatal(err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("wanted access and refresh tokens, got %+v", tokens)
+	}
+
+	loginReq.Password = testPassword + "wrong"
+	loginReqBytes, err = json.Marshal(loginReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest("POST", ts.URL+"/api/v1/user/login", bytes.NewBuffer(loginReqBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusUnauthorized {
+		t.Fatalf("wanted %d, got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestRefreshTokenAndLogout(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := store.NewStore(db.DB())
+	_, err = s.StoreUser(ctx, store.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serv := service.NewUserService(s, slog.Default(), testTokenConfig)
+	tokens, err := serv.Login(ctx, testEmail, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := NewUserAPI(serv)
+	router := chi.NewRouter()
+	router.Post("/api/v1/user/token/refresh", api.RefreshToken)
+	router.Post("/api/v1/user/logout", api.Logout)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	post := func(path string, body any) *http.Response {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest("POST", ts.URL+path, bytes.NewBuffer(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-type", "application/json")
+
+		client := &http.Client{}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return resp
+	}
+
+	resp := post("/api/v1/user/token/refresh", RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusOK {
+		t.Fatalf("wanted %d, got %d", http.StatusOK, status)
+	}
+
+	var refreshed TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&refreshed); err != nil {
+		t.Fatal(err)
+	}
+
+	// Refresh tokens are single use.
+	resp = post("/api/v1/user/token/refresh", RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusUnauthorized {
+		t.Fatalf("wanted %d, got %d", http.StatusUnauthorized, status)
+	}
+
+	tokens, err = serv.Login(ctx, testEmail, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp = post("/api/v1/user/logout", LogoutRequest{RefreshToken: tokens.RefreshToken})
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusNoContent {
+		t.Fatalf("wanted %d, got %d", http.StatusNoContent, status)
+	}
+
+	resp = post("/api/v1/user/token/refresh", RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusUnauthorized {
+		t.Fatalf("wanted %d, got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestAssignRole(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := store.NewStore(db.DB())
+	userID, err := s.StoreUser(ctx, store.User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serv := service.NewUserService(s, slog.Default(), testTokenConfig)
+	api := NewUserAPI(serv)
+	router := chi.NewRouter()
+	router.Use(testAuthenticator.Middleware)
+	router.Put("/api/v1/user/role", api.AssignRole)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	assignRole := func(permissions ...string) int {
+		assignRoleBytes, err := json.Marshal(AssignRoleRequest{
+			UserID: userID,
+			Role:   "staff",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest("PUT", ts.URL+"/api/v1/user/role", bytes.NewBuffer(assignRoleBytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Content-type", "application/json")
+		setAuthorization(t, req, userID, permissions...)
+
+		client := &http.Client{}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	if status := assignRole(); status != http.StatusForbidden {
+		t.Fatalf("wanted %d, got %d", http.StatusForbidden, status)
+	}
+	if status := assignRole(auth.PermissionUserRole); status != http.StatusNoContent {
+		t.Fatalf("wanted %d, got %d", http.StatusNoContent, status)
+	}
+
+	tokens, err := serv.Login(ctx, testEmail, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := testAuthenticator.Authenticate("Bearer " + tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Role != "staff" {
+		t.Fatalf("wanted %s, got %s", "staff", identity.Role)
+	}
+	if !identity.HasPermission(auth.PermissionProductStock) {
+		t.Fatalf("wanted staff to have %s, got %v", auth.PermissionProductStock, identity.Permissions)
+	}
+}
@@ -2,25 +2,39 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"time"
 )
 
 const (
-	connectionString = "CONNECTION_STRING"
-	httpServerPort   = "HTTP_SERVER_PORT"
-	grpcServerPort   = "GRPC_SERVER_PORT"
+	connectionString       = "CONNECTION_STRING"
+	eventsConnectionString = "EVENTS_CONNECTION_STRING"
+	httpServerPort         = "HTTP_SERVER_PORT"
+	grpcServerPort         = "GRPC_SERVER_PORT"
+	jwtSecret              = "JWT_SECRET"
+	accessTokenTTL         = "ACCESS_TOKEN_TTL"
+	refreshTokenTTL        = "REFRESH_TOKEN_TTL"
+	tracesExporter         = "TRACES_EXPORTER"
 )
 
 var (
 	errEmptyConnectionString = errors.New("env variable 'CONNECTION_STRING' cannot be empty")
 	errEmptyHTTPServerPort   = errors.New("env variable 'HTTP_SERVER_PORT' cannot be empty")
 	errEmptyGRPCServerPort   = errors.New("env variable 'GRPC_SERVER_PORT' cannot be empty")
+	errEmptyJWTSecret        = errors.New("env variable 'JWT_SECRET' cannot be empty")
 )
 
 type config struct {
 	connectionString string
-	httpServerPort   string
-	grpcServerPort   string
+	// eventsConnectionString is the database the domain events are published on, it defaults to connectionString.
+	eventsConnectionString string
+	httpServerPort         string
+	grpcServerPort         string
+	jwtSecret              string
+	accessTokenTTL         time.Duration
+	refreshTokenTTL        time.Duration
+	tracesExporter         string
 }
 
 func getConfig() config {
@@ -29,6 +43,11 @@ func getConfig() config {
 		panic(errEmptyConnectionString)
 	}
 
+	eventsCstr := os.Getenv(eventsConnectionString)
+	if eventsCstr == "" {
+		eventsCstr = cstr
+	}
+
 	httpPort := os.Getenv(httpServerPort)
 	if httpPort == "" {
 		panic(errEmptyHTTPServerPort)
@@ -39,9 +58,34 @@ func getConfig() config {
 		panic(errEmptyGRPCServerPort)
 	}
 
+	secret := os.Getenv(jwtSecret)
+	if secret == "" {
+		panic(errEmptyJWTSecret)
+	}
+
 	return config{
-		connectionString: cstr,
-		httpServerPort:   httpPort,
-		grpcServerPort:   grpcPort,
+		connectionString:       cstr,
+		eventsConnectionString: eventsCstr,
+		httpServerPort:         httpPort,
+		grpcServerPort:         grpcPort,
+		jwtSecret:              secret,
+		accessTokenTTL:         getDuration(accessTokenTTL),
+		refreshTokenTTL:        getDuration(refreshTokenTTL),
+		tracesExporter:         os.Getenv(tracesExporter),
+	}
+}
+
+// getDuration parses an optional duration env variable, returning 0 when it is not set.
+func getDuration(key string) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
 	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		panic(fmt.Errorf("env variable '%s' is not a valid duration: %w", key, err))
+	}
+
+	return d
 }
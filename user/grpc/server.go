@@ -2,45 +2,51 @@ package grpc
 
 import (
 	"context"
-	"errors"
 
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	"github.com/PseudoMera/virtual-store/user/service"
 	"github.com/PseudoMera/virtual-store/user/store"
 )
 
 var (
-	errEmptyEmail    = errors.New("email field cannot be empty")
-	errEmptyPassword = errors.New("password field cannot be empty")
-	errEmptyUserID   = errors.New("user_id field cannot be empty")
-	errEmptyName     = errors.New("name field cannot be empty")
-	errEmptyPhoto    = errors.New("photo field cannot be empty")
-	errEmptyCountry  = errors.New("country field cannot be empty")
-	errEmptyAddress  = errors.New("address field cannot be empty")
-	errEmptyPhone    = errors.New("phone field cannot be empty")
-
-	errRetrievingUser = errors.New("something went wrong while retrieving user")
+	errEmptyEmail    = shared.InvalidFieldError("email", "email field cannot be empty")
+	errEmptyPassword = shared.InvalidFieldError("password", "password field cannot be empty")
+	errEmptyUserID   = shared.InvalidFieldError("user_id", "user_id field cannot be empty")
+	errEmptyName     = shared.InvalidFieldError("name", "name field cannot be empty")
+	errEmptyPhoto    = shared.InvalidFieldError("photo", "photo field cannot be empty")
+	errEmptyCountry  = shared.InvalidFieldError("country", "country field cannot be empty")
+	errEmptyAddress  = shared.InvalidFieldError("address", "address field cannot be empty")
+	errEmptyPhone    = shared.InvalidFieldError("phone", "phone field cannot be empty")
 )
 
 type UserServer struct {
-	db *store.Store
+	db      *store.Store
+	service *service.UserService
 	UnimplementedUserServiceServer
 }
 
-// NewUserServer returns a GRPC server with the given database
-func NewUserServer(db *store.Store) *UserServer {
+// NewUserServer returns a GRPC server with the given database and user service.
+// The user service is used for the authentication flows.
+func NewUserServer(db *store.Store, service *service.UserService) *UserServer {
 	return &UserServer{
-		db: db,
+		db:      db,
+		service: service,
 	}
 }
 
 func (us *UserServer) GetUser(ctx context.Context, req *GetUserRequest) (*User, error) {
 	email := req.Email
 	if email == "" {
-		return nil, errEmptyEmail
+		return nil, shared.GRPCError(errEmptyEmail)
 	}
 
 	user, err := us.db.RetrieveUser(ctx, email)
 	if err != nil {
-		return nil, errRetrievingUser
+		return nil, shared.GRPCError(err)
+	}
+	if err := auth.Authorize(ctx, user.ID); err != nil {
+		return nil, shared.GRPCError(err)
 	}
 
 	cID := int64(user.ID)
@@ -48,17 +54,40 @@ func (us *UserServer) GetUser(ctx context.Context, req *GetUserRequest) (*User,
 		Email:    user.Email,
 		Id:       cID,
 		Password: user.Password,
+		Role:     user.Role,
 	}
 	return cUser, nil
 }
 
+// GetUserByID returns the user with the given id without its password.
+// Other services use it to check the users they reference, with the user:read permission.
+func (us *UserServer) GetUserByID(ctx context.Context, req *GetUserByIDRequest) (*User, error) {
+	if req.Id == 0 {
+		return nil, shared.GRPCError(errEmptyUserID)
+	}
+	if err := auth.Authorize(ctx, int(req.Id), auth.PermissionUserRead); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	user, err := us.db.RetrieveUserByID(ctx, int(req.Id))
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &User{
+		Id:    int64(user.ID),
+		Email: user.Email,
+		Role:  user.Role,
+	}, nil
+}
+
 func (us *UserServer) CreateUser(ctx context.Context, req *CreateUserRequest) (*User, error) {
 	email, password := req.Email, req.Password
 	if email == "" {
-		return nil, errEmptyEmail
+		return nil, shared.GRPCError(errEmptyEmail)
 	}
 	if password == "" {
-		return nil, errEmptyPassword
+		return nil, shared.GRPCError(errEmptyPassword)
 	}
 
 	id, err := us.db.StoreUser(ctx, store.User{
@@ -66,7 +95,7 @@ func (us *UserServer) CreateUser(ctx context.Context, req *CreateUserRequest) (*
 		Password: password,
 	})
 	if err != nil {
-		return nil, err
+		return nil, shared.GRPCError(err)
 	}
 
 	cID := int64(id)
@@ -75,28 +104,31 @@ func (us *UserServer) CreateUser(ctx context.Context, req *CreateUserRequest) (*
 		Id:       cID,
 		Email:    email,
 		Password: password,
-	}, err
+	}, nil
 }
 
 func (us *UserServer) CreateUserProfile(ctx context.Context, req *CreateUserProfileRequest) (*CreateUserProfileResponse, error) {
 	userID, name, photo, country, address, phone := req.Id, req.Name, req.Photo, req.Country, req.Address, req.Phone
 	if userID == 0 {
-		return nil, errEmptyUserID
+		return nil, shared.GRPCError(errEmptyUserID)
 	}
 	if name == "" {
-		return nil, errEmptyName
+		return nil, shared.GRPCError(errEmptyName)
 	}
 	if photo == "" {
-		return nil, errEmptyPhoto
+		return nil, shared.GRPCError(errEmptyPhoto)
 	}
 	if country == "" {
-		return nil, errEmptyCountry
+		return nil, shared.GRPCError(errEmptyCountry)
 	}
 	if address == "" {
-		return nil, errEmptyAddress
+		return nil, shared.GRPCError(errEmptyAddress)
 	}
 	if phone == "" {
-		return nil, errEmptyPhone
+		return nil, shared.GRPCError(errEmptyPhone)
+	}
+	if err := auth.Authorize(ctx, int(userID)); err != nil {
+		return nil, shared.GRPCError(err)
 	}
 
 	profileID, err := us.db.StoreUserProfile(ctx, store.Profile{
@@ -107,15 +139,25 @@ func (us *UserServer) CreateUserProfile(ctx context.Context, req *CreateUserProf
 		Address: address,
 		Phone:   phone,
 	})
-	return &CreateUserProfileResponse{Id: int64(profileID)}, err
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &CreateUserProfileResponse{Id: int64(profileID)}, nil
 }
 
 func (us *UserServer) GetUserProfile(ctx context.Context, req *GetUserProfileRequest) (*Profile, error) {
 	if req.Id == 0 {
-		return nil, errEmptyUserID
+		return nil, shared.GRPCError(errEmptyUserID)
+	}
+	if err := auth.Authorize(ctx, int(req.Id), auth.PermissionUserRead); err != nil {
+		return nil, shared.GRPCError(err)
 	}
 
 	profile, err := us.db.RetrieveUserProfile(ctx, int(req.Id))
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
 
 	return &Profile{
 		Id:      int64(profile.ID),
@@ -125,28 +167,31 @@ func (us *UserServer) GetUserProfile(ctx context.Context, req *GetUserProfileReq
 		Country: profile.Country,
 		Address: profile.Address,
 		Phone:   profile.Phone,
-	}, err
+	}, nil
 }
 
 func (us *UserServer) UpdateUserProfile(ctx context.Context, req *UpdateUserProfileRequest) (*SuccessResponse, error) {
 	userID, name, photo, country, address, phone := req.UserID, req.Name, req.Photo, req.Country, req.Address, req.Phone
 	if userID == 0 {
-		return nil, errEmptyUserID
+		return nil, shared.GRPCError(errEmptyUserID)
 	}
 	if name == "" {
-		return nil, errEmptyName
+		return nil, shared.GRPCError(errEmptyName)
 	}
 	if photo == "" {
-		return nil, errEmptyPhoto
+		return nil, shared.GRPCError(errEmptyPhoto)
 	}
 	if country == "" {
-		return nil, errEmptyCountry
+		return nil, shared.GRPCError(errEmptyCountry)
 	}
 	if address == "" {
-		return nil, errEmptyAddress
+		return nil, shared.GRPCError(errEmptyAddress)
 	}
 	if phone == "" {
-		return nil, errEmptyPhone
+		return nil, shared.GRPCError(errEmptyPhone)
+	}
+	if err := auth.Authorize(ctx, int(userID)); err != nil {
+		return nil, shared.GRPCError(err)
 	}
 
 	if err := us.db.UpdateUserProfile(ctx, store.Profile{
@@ -157,10 +202,99 @@ func (us *UserServer) UpdateUserProfile(ctx context.Context, req *UpdateUserProf
 		Address: address,
 		Phone:   phone,
 	}); err != nil {
-		return nil, err
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+func (us *UserServer) Login(ctx context.Context, req *LoginRequest) (*TokenResponse, error) {
+	tokens, err := us.service.Login(ctx, req.Email, req.Password)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return newTokenResponse(tokens), nil
+}
+
+func (us *UserServer) RefreshToken(ctx context.Context, req *RefreshTokenRequest) (*TokenResponse, error) {
+	tokens, err := us.service.RefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return newTokenResponse(tokens), nil
+}
+
+func (us *UserServer) Logout(ctx context.Context, req *LogoutRequest) (*SuccessResponse, error) {
+	if err := us.service.Logout(ctx, req.RefreshToken, req.All); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+func (us *UserServer) AssignRole(ctx context.Context, req *AssignRoleRequest) (*SuccessResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionUserRole); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	if err := us.service.AssignRole(ctx, int(req.UserID), req.Role); err != nil {
+		return nil, shared.GRPCError(err)
 	}
 
 	return &SuccessResponse{
 		Msg: "Success!",
 	}, nil
 }
+
+func (us *UserServer) DeleteUser(ctx context.Context, req *DeleteUserRequest) (*SuccessResponse, error) {
+	if err := auth.Authorize(ctx, int(req.UserID)); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	if err := us.service.DeleteUser(ctx, int(req.UserID)); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	return &SuccessResponse{
+		Msg: "Success!",
+	}, nil
+}
+
+func (us *UserServer) GetRoles(ctx context.Context, req *GetRolesRequest) (*GetRolesResponse, error) {
+	if err := auth.CheckPermission(ctx, auth.PermissionUserRole); err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	roles, err := us.service.GetRoles(ctx)
+	if err != nil {
+		return nil, shared.GRPCError(err)
+	}
+
+	parsedRoles := make([]*Role, len(roles))
+	for i := range roles {
+		parsedRoles[i] = &Role{
+			Name:        roles[i].Name,
+			Description: roles[i].Description,
+			Permissions: roles[i].Permissions,
+		}
+	}
+
+	return &GetRolesResponse{
+		Roles: parsedRoles,
+	}, nil
+}
+
+func newTokenResponse(tokens *service.TokenPair) *TokenResponse {
+	return &TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	}
+}
@@ -3,15 +3,24 @@ package main
 import (
 	"context"
 	"fmt"
-	"net"
-	"net/http"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
+	eventmigrations "github.com/PseudoMera/virtual-store/shared/events/migrations"
+	"github.com/PseudoMera/virtual-store/shared/health"
+	"github.com/PseudoMera/virtual-store/shared/metrics"
+	"github.com/PseudoMera/virtual-store/shared/migrate"
+	"github.com/PseudoMera/virtual-store/shared/outbox"
+	"github.com/PseudoMera/virtual-store/shared/tracing"
 	"github.com/PseudoMera/virtual-store/user/api"
 	"github.com/PseudoMera/virtual-store/user/grpc"
+	"github.com/PseudoMera/virtual-store/user/migrations"
 	"github.com/PseudoMera/virtual-store/user/service"
 	"github.com/PseudoMera/virtual-store/user/store"
+	"github.com/go-chi/chi/v5"
 	egrpc "google.golang.org/grpc"
 )
 
@@ -23,38 +32,131 @@ func main() {
 	config := getConfig()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	database, err := shared.NewPostgresDatabase(ctx, config.connectionString)
+	cancel()
 	if err != nil {
 		panic(err)
 	}
-	defer cancel()
 
 	logger := shared.NewLogger()
+	migrator, err := migrate.NewMigrator(database.DB(), migrations.FS, logger)
+	if err != nil {
+		panic(err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == migrate.Command {
+		err := migrator.Run(context.Background(), os.Args[2:], os.Stdout)
+		database.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		panic(err)
+	}
+
+	exporter, err := tracing.NewExporter(context.Background(), config.tracesExporter)
+	if err != nil {
+		panic(err)
+	}
+	tracerProvider := tracing.Setup("user", exporter)
+	if err := metrics.RegisterPool(database.DB()); err != nil {
+		panic(err)
+	}
 	router := api.NewRouter()
 	store := store.NewStore(database.DB())
-	userService := service.NewUserService(store, logger)
+	userService := service.NewUserService(store, logger, service.TokenConfig{
+		Secret:          []byte(config.jwtSecret),
+		AccessTokenTTL:  config.accessTokenTTL,
+		RefreshTokenTTL: config.refreshTokenTTL,
+	})
 	userAPI := api.NewUserAPI(userService)
-
-	router.Post(fmt.Sprintf("%s/user", apiPath), userAPI.CreateUser)
-	router.Get(fmt.Sprintf("%s/user", apiPath), userAPI.GetUser)
-	router.Post(fmt.Sprintf("%s/user/profile", apiPath), userAPI.CreateUserProfile)
-	router.Get(fmt.Sprintf("%s/user/profile", apiPath), userAPI.GetUserProfile)
-	router.Put(fmt.Sprintf("%s/user/profile", apiPath), userAPI.UpdateUserProfile)
-
-	lis, err := net.Listen("tcp", config.grpcServerPort)
+	authenticator := auth.NewAuthenticator([]byte(config.jwtSecret))
+	checker := health.NewChecker(grpc.UserService_ServiceDesc.ServiceName)
+	checker.AddCheck("postgres", database.DB().Ping)
+	background, stopBackground := context.WithCancel(context.Background())
+	go checker.Watch(background, 10*time.Second)
+	eventBus := database
+	if config.eventsConnectionString != config.connectionString {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		eventBus, err = shared.NewPostgresDatabase(ctx, config.eventsConnectionString)
+		cancel()
+		if err != nil {
+			panic(err)
+		}
+	}
+	eventsMigrator, err := migrate.NewTableMigrator(eventBus.DB(), eventmigrations.FS, eventmigrations.Table, logger)
 	if err != nil {
 		panic(err)
 	}
+	if err := eventsMigrator.Up(context.Background()); err != nil {
+		panic(err)
+	}
+	publisher := outbox.NewLogPublisher(eventBus.DB(), outbox.Channel)
+	go publisher.RunExpiry(background, time.Hour, logger)
+	relay := outbox.NewRelay(database.DB(), "user", publisher, logger)
+	go relay.Run(background, time.Second)
+
+	router.Get(health.LivenessPath, checker.Liveness)
+	router.Get(health.ReadinessPath, checker.Readiness)
+	router.Handle(metrics.Path, metrics.Handler())
+	router.Post(fmt.Sprintf("%s/user", apiPath), userAPI.CreateUser)
+	router.Post(fmt.Sprintf("%s/user/login", apiPath), userAPI.Login)
+	router.Post(fmt.Sprintf("%s/user/token/refresh", apiPath), userAPI.RefreshToken)
+	router.Post(fmt.Sprintf("%s/user/logout", apiPath), userAPI.Logout)
+	router.Group(func(r chi.Router) {
+		r.Use(authenticator.Middleware)
+		r.Get(fmt.Sprintf("%s/user", apiPath), userAPI.GetUser)
+		r.Post(fmt.Sprintf("%s/user/profile", apiPath), userAPI.CreateUserProfile)
+		r.Get(fmt.Sprintf("%s/user/profile", apiPath), userAPI.GetUserProfile)
+		r.Put(fmt.Sprintf("%s/user/profile", apiPath), userAPI.UpdateUserProfile)
+		r.Delete(fmt.Sprintf("%s/user", apiPath), userAPI.DeleteUser)
+		r.Put(fmt.Sprintf("%s/user/role", apiPath), userAPI.AssignRole)
+		r.Get(fmt.Sprintf("%s/roles", apiPath), userAPI.GetRoles)
+	})
 
-	var opts []egrpc.ServerOption
+	publicMethods := []string{
+		grpcMethod("CreateUser"),
+		grpcMethod("Login"),
+		grpcMethod("RefreshToken"),
+		grpcMethod("Logout"),
+	}
+	publicMethods = append(publicMethods, health.Methods()...)
+	opts := []egrpc.ServerOption{
+		egrpc.ChainUnaryInterceptor(
+			tracing.UnaryServerInterceptor(),
+			metrics.UnaryServerInterceptor(),
+			authenticator.UnaryServerInterceptor(publicMethods...),
+		),
+		egrpc.ChainStreamInterceptor(
+			tracing.StreamServerInterceptor(),
+			metrics.StreamServerInterceptor(),
+			authenticator.StreamServerInterceptor(publicMethods...),
+		),
+	}
 	grpcServer := egrpc.NewServer(opts...)
-	serviceServer := grpc.NewUserServer(store)
+	serviceServer := grpc.NewUserServer(store, userService)
 	grpc.RegisterUserServiceServer(grpcServer, serviceServer)
+	checker.Register(grpcServer)
 
-	if err := grpcServer.Serve(lis); err != nil {
-		panic(err)
+	runner := shared.NewServerRunner(fmt.Sprintf(":%s", config.httpServerPort), router, fmt.Sprintf(":%s", config.grpcServerPort), grpcServer, logger)
+	runner.BeforeShutdown(checker.Shutdown)
+	runner.OnShutdown(stopBackground)
+	runner.OnShutdown(database.Close)
+	if eventBus != database {
+		runner.OnShutdown(eventBus.Close)
 	}
+	runner.OnShutdown(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			logger.Error("error at tracer shutdown", slog.String("error", err.Error()))
+		}
+	})
+	os.Exit(runner.Run(context.Background()))
+}
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", config.httpServerPort), router); err != nil {
-		panic(err)
-	}
+// grpcMethod returns the full gRPC method name of a user service method.
+func grpcMethod(name string) string {
+	return fmt.Sprintf("/%s/%s", grpc.UserService_ServiceDesc.ServiceName, name)
 }
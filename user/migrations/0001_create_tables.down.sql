@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS user_token;
+DROP TABLE IF EXISTS user_profile;
+DROP TABLE IF EXISTS vstore_user;
+DROP TABLE IF EXISTS role_permission;
+DROP TABLE IF EXISTS permission;
+DROP TABLE IF EXISTS role;
@@ -0,0 +1,86 @@
+CREATE TABLE role (
+    name VARCHAR PRIMARY KEY,
+    description VARCHAR NOT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE permission (
+    name VARCHAR PRIMARY KEY,
+    description VARCHAR NOT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE role_permission (
+    role VARCHAR NOT NULL,
+    permission VARCHAR NOT NULL,
+    FOREIGN KEY (role) REFERENCES role (name) ON DELETE CASCADE,
+    FOREIGN KEY (permission) REFERENCES permission (name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission),
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO role(name, description) VALUES
+    ('customer', 'Browses the catalog and manages its own orders'),
+    ('staff', 'Manages product stock and ships orders'),
+    ('admin', 'Manages everything');
+
+INSERT INTO permission(name, description) VALUES
+    ('product:write', 'Create and update products'),
+    ('product:stock', 'Update product stock'),
+    ('order:read', 'Read the orders of any user'),
+    ('order:status', 'Change the status of any order, e.g. ship it'),
+    ('order:write', 'Create and update the orders of any user'),
+    ('order:refund', 'Refund orders'),
+    ('user:role', 'Assign roles to users'),
+    ('user:read', 'Read any user');
+
+INSERT INTO role_permission(role, permission) VALUES
+    ('staff', 'product:stock'),
+    ('staff', 'order:read'),
+    ('staff', 'order:status'),
+    ('staff', 'order:refund'),
+    ('admin', 'product:write'),
+    ('admin', 'product:stock'),
+    ('admin', 'order:read'),
+    ('admin', 'order:status'),
+    ('admin', 'order:write'),
+    ('admin', 'order:refund'),
+    ('admin', 'user:role'),
+    ('admin', 'user:read');
+
+CREATE TABLE vstore_user (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    email VARCHAR NOT NULL UNIQUE,
+    password VARCHAR NOT NULL,
+    role VARCHAR NOT NULL DEFAULT 'customer',
+    FOREIGN KEY (role) REFERENCES role (name),
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_profile (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id  INTEGER NOT NULL UNIQUE,
+    FOREIGN KEY (user_id) REFERENCES vstore_user (id) ON DELETE CASCADE,
+    UNIQUE(user_id),
+    name VARCHAR NOT NULL,
+    photo VARCHAR,
+    country VARCHAR NOT NULL,
+    address VARCHAR NOT NULL,
+    phone VARCHAR,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_token (
+    id VARCHAR PRIMARY KEY,
+    user_id INT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES vstore_user (id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
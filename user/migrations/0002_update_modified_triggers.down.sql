@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS update_role_modtime ON role;
+DROP TRIGGER IF EXISTS update_permission_modtime ON permission;
+DROP TRIGGER IF EXISTS update_role_permission_modtime ON role_permission;
+DROP TRIGGER IF EXISTS update_user_modtime ON vstore_user;
+DROP TRIGGER IF EXISTS update_user_profile_modtime ON user_profile;
+DROP TRIGGER IF EXISTS update_user_token_modtime ON user_token;
+
+DROP FUNCTION IF EXISTS update_modified_column();
@@ -0,0 +1,17 @@
+/*
+    Updates the updated_at column when a record is modified
+*/
+CREATE OR REPLACE FUNCTION update_modified_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_role_modtime BEFORE UPDATE ON role FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_permission_modtime BEFORE UPDATE ON permission FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_role_permission_modtime BEFORE UPDATE ON role_permission FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_user_modtime BEFORE UPDATE ON vstore_user FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_user_profile_modtime BEFORE UPDATE ON user_profile FOR EACH ROW EXECUTE FUNCTION update_modified_column();
+CREATE TRIGGER update_user_token_modtime BEFORE UPDATE ON user_token FOR EACH ROW EXECUTE FUNCTION update_modified_column();
@@ -0,0 +1 @@
+DELETE FROM permission WHERE name = 'events:replay';
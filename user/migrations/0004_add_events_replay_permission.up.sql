@@ -0,0 +1,5 @@
+INSERT INTO permission(name, description) VALUES
+    ('events:replay', 'List and replay the events the services failed to consume');
+
+INSERT INTO role_permission(role, permission) VALUES
+    ('admin', 'events:replay');
@@ -0,0 +1,5 @@
+INSERT INTO permission(name, description) VALUES
+    ('rates:write', 'Set the exchange rates of the order service');
+
+INSERT INTO role_permission(role, permission) VALUES
+    ('admin', 'rates:write');
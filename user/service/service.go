@@ -4,45 +4,58 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"time"
 
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/auth"
 	"github.com/PseudoMera/virtual-store/user/store"
+	"github.com/jackc/pgx/v5"
 )
 
 var (
-	errEmptyEmail    = errors.New("email field cannot be empty")
-	errEmptyPassword = errors.New("password field cannot be empty")
-	errEmptyUserID   = errors.New("user_id field cannot be empty")
-	errEmptyName     = errors.New("name field cannot be empty")
-	errEmptyPhoto    = errors.New("photo field cannot be empty")
-	errEmptyCountry  = errors.New("country field cannot be empty")
-	errEmptyAddress  = errors.New("address field cannot be empty")
-	errEmptyPhone    = errors.New("phone field cannot be empty")
+	errEmptyEmail    = shared.InvalidFieldError("email", "email field cannot be empty")
+	errEmptyPassword = shared.InvalidFieldError("password", "password field cannot be empty")
+	errEmptyUserID   = shared.InvalidFieldError("user_id", "user_id field cannot be empty")
+	errEmptyName     = shared.InvalidFieldError("name", "name field cannot be empty")
+	errEmptyPhoto    = shared.InvalidFieldError("photo", "photo field cannot be empty")
+	errEmptyCountry  = shared.InvalidFieldError("country", "country field cannot be empty")
+	errEmptyAddress  = shared.InvalidFieldError("address", "address field cannot be empty")
+	errEmptyPhone    = shared.InvalidFieldError("phone", "phone field cannot be empty")
+	errEmptyToken    = shared.InvalidFieldError("refresh_token", "refresh_token field cannot be empty")
+	errEmptyRole     = shared.InvalidFieldError("role", "role field cannot be empty")
+
+	// ErrInvalidCredentials is returned when the email does not exist or the password does not match.
+	ErrInvalidCredentials = shared.UnauthorizedError("invalid email or password")
+	// ErrInvalidToken is returned when a refresh token is malformed, expired or revoked.
+	ErrInvalidToken = shared.UnauthorizedError("invalid or expired token")
 )
 
 type UserService struct {
 	db     *store.Store
 	logger *slog.Logger
+	tokens *tokenManager
 }
 
-// NewUserService returns a UserService with the given db and logger.
+// NewUserService returns a UserService with the given db, logger and token configuration.
 // The user service can be used to interact with the database through
 // the Store struct. The service also has validation for the methods
 // so it's better to use this than directly interacting with the Store struct.
-func NewUserService(db *store.Store, logger *slog.Logger) *UserService {
+func NewUserService(db *store.Store, logger *slog.Logger, tokenConfig TokenConfig) *UserService {
 	return &UserService{
 		db:     db,
 		logger: logger,
+		tokens: newTokenManager(tokenConfig),
 	}
 }
 
 // CreateUser creates a new user with the given email and password.
 func (u *UserService) CreateUser(ctx context.Context, email, password string) (int, error) {
 	if email == "" {
-		u.logger.Info("error at CreateUser", slog.String("error", errEmptyAddress.Error()))
+		u.logger.InfoContext(ctx, "error at CreateUser", slog.String("error", errEmptyAddress.Error()))
 		return 0, errEmptyEmail
 	}
 	if password == "" {
-		u.logger.Info("error at CreateUser", slog.String("error", errEmptyPassword.Error()))
+		u.logger.InfoContext(ctx, "error at CreateUser", slog.String("error", errEmptyPassword.Error()))
 		return 0, errEmptyPassword
 	}
 
@@ -56,7 +69,7 @@ func (u *UserService) CreateUser(ctx context.Context, email, password string) (i
 // GetUser returns the user with the given email if it exists.
 func (u *UserService) GetUser(ctx context.Context, email string) (*store.User, error) {
 	if email == "" {
-		u.logger.Info("error at GetUser", slog.String("error", errEmptyAddress.Error()))
+		u.logger.InfoContext(ctx, "error at GetUser", slog.String("error", errEmptyAddress.Error()))
 		return nil, errEmptyEmail
 	}
 
@@ -67,27 +80,27 @@ func (u *UserService) GetUser(ctx context.Context, email string) (*store.User, e
 // Returns the profile ID if no errors ocurred during the query operation.
 func (u *UserService) CreateUserProfile(ctx context.Context, userID int, name string, photo string, country string, address string, phone string) (int, error) {
 	if userID == 0 {
-		u.logger.Info("error at CreateUserProfile", slog.String("error", errEmptyUserID.Error()))
+		u.logger.InfoContext(ctx, "error at CreateUserProfile", slog.String("error", errEmptyUserID.Error()))
 		return 0, errEmptyUserID
 	}
 	if name == "" {
-		u.logger.Info("error at CreateUserProfile", slog.String("error", errEmptyName.Error()))
+		u.logger.InfoContext(ctx, "error at CreateUserProfile", slog.String("error", errEmptyName.Error()))
 		return 0, errEmptyName
 	}
 	if photo == "" {
-		u.logger.Info("error at CreateUserProfile", slog.String("error", errEmptyPhoto.Error()))
+		u.logger.InfoContext(ctx, "error at CreateUserProfile", slog.String("error", errEmptyPhoto.Error()))
 		return 0, errEmptyPhoto
 	}
 	if country == "" {
-		u.logger.Info("error at CreateUserProfile", slog.String("error", errEmptyCountry.Error()))
+		u.logger.InfoContext(ctx, "error at CreateUserProfile", slog.String("error", errEmptyCountry.Error()))
 		return 0, errEmptyCountry
 	}
 	if address == "" {
-		u.logger.Info("error at CreateUserProfile", slog.String("error", errEmptyAddress.Error()))
+		u.logger.InfoContext(ctx, "error at CreateUserProfile", slog.String("error", errEmptyAddress.Error()))
 		return 0, errEmptyAddress
 	}
 	if phone == "" {
-		u.logger.Info("error at CreateUserProfile", slog.String("error", errEmptyPhone.Error()))
+		u.logger.InfoContext(ctx, "error at CreateUserProfile", slog.String("error", errEmptyPhone.Error()))
 		return 0, errEmptyPhone
 	}
 
@@ -104,7 +117,7 @@ func (u *UserService) CreateUserProfile(ctx context.Context, userID int, name st
 // RetrieveUserProfile returns the profile associated with the user id.
 func (u *UserService) RetrieveUserProfile(ctx context.Context, userID int) (*store.Profile, error) {
 	if userID == 0 {
-		u.logger.Info("error at RetrieveUserProfile", slog.String("error", errEmptyUserID.Error()))
+		u.logger.InfoContext(ctx, "error at RetrieveUserProfile", slog.String("error", errEmptyUserID.Error()))
 		return nil, errEmptyUserID
 	}
 
@@ -114,27 +127,27 @@ func (u *UserService) RetrieveUserProfile(ctx context.Context, userID int) (*sto
 // UpdateUserProfile updates the user profile associated with the user id.
 func (u *UserService) UpdateUserProfile(ctx context.Context, userID int, name string, photo string, country string, address string, phone string) error {
 	if userID == 0 {
-		u.logger.Info("error at UpdateUserProfile", slog.String("error", errEmptyUserID.Error()))
+		u.logger.InfoContext(ctx, "error at UpdateUserProfile", slog.String("error", errEmptyUserID.Error()))
 		return errEmptyUserID
 	}
 	if name == "" {
-		u.logger.Info("error at UpdateUserProfile", slog.String("error", errEmptyName.Error()))
+		u.logger.InfoContext(ctx, "error at UpdateUserProfile", slog.String("error", errEmptyName.Error()))
 		return errEmptyName
 	}
 	if photo == "" {
-		u.logger.Info("error at UpdateUserProfile", slog.String("error", errEmptyPhoto.Error()))
+		u.logger.InfoContext(ctx, "error at UpdateUserProfile", slog.String("error", errEmptyPhoto.Error()))
 		return errEmptyPhoto
 	}
 	if country == "" {
-		u.logger.Info("error at UpdateUserProfile", slog.String("error", errEmptyCountry.Error()))
+		u.logger.InfoContext(ctx, "error at UpdateUserProfile", slog.String("error", errEmptyCountry.Error()))
 		return errEmptyCountry
 	}
 	if address == "" {
-		u.logger.Info("error at UpdateUserProfile", slog.String("error", errEmptyAddress.Error()))
+		u.logger.InfoContext(ctx, "error at UpdateUserProfile", slog.String("error", errEmptyAddress.Error()))
 		return errEmptyAddress
 	}
 	if phone == "" {
-		u.logger.Info("error at UpdateUserProfile", slog.String("error", errEmptyPhone.Error()))
+		u.logger.InfoContext(ctx, "error at UpdateUserProfile", slog.String("error", errEmptyPhone.Error()))
 		return errEmptyPhone
 	}
 
@@ -147,3 +160,172 @@ func (u *UserService) UpdateUserProfile(ctx context.Context, userID int, name st
 		Phone:   phone,
 	})
 }
+
+// Login verifies the given credentials and returns a new access and refresh token pair.
+func (u *UserService) Login(ctx context.Context, email, password string) (*TokenPair, error) {
+	if email == "" {
+		u.logger.InfoContext(ctx, "error at Login", slog.String("error", errEmptyEmail.Error()))
+		return nil, errEmptyEmail
+	}
+	if password == "" {
+		u.logger.InfoContext(ctx, "error at Login", slog.String("error", errEmptyPassword.Error()))
+		return nil, errEmptyPassword
+	}
+
+	user, err := u.db.RetrieveUserCredentials(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			u.logger.InfoContext(ctx, "error at Login", slog.String("error", ErrInvalidCredentials.Error()))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if !store.CheckPassword(user.Password, password) {
+		u.logger.InfoContext(ctx, "error at Login", slog.String("error", ErrInvalidCredentials.Error()))
+		return nil, ErrInvalidCredentials
+	}
+
+	return u.issueTokens(ctx, user)
+}
+
+// RefreshToken exchanges a valid refresh token for a new token pair.
+// Refresh tokens are single use, the given token is revoked before the new pair is issued.
+// Presenting a token that was already revoked revokes every token of the user, since it
+// means the token was most likely stolen.
+func (u *UserService) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		u.logger.InfoContext(ctx, "error at RefreshToken", slog.String("error", errEmptyToken.Error()))
+		return nil, errEmptyToken
+	}
+
+	claims, err := u.tokens.parse(refreshToken, auth.RefreshToken)
+	if err != nil {
+		u.logger.InfoContext(ctx, "error at RefreshToken", slog.String("error", err.Error()))
+		return nil, ErrInvalidToken
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if err := u.db.RevokeToken(ctx, claims.ID); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+
+		u.logger.WarnContext(ctx, "refresh token reused, revoking all user tokens", slog.Int("user_id", userID))
+		if err := u.db.RevokeUserTokens(ctx, userID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidToken
+	}
+
+	user, err := u.db.RetrieveUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return u.issueTokens(ctx, user)
+}
+
+// Logout revokes the given refresh token. If all is true every refresh token
+// of the token owner is revoked, logging the user out of all sessions.
+// Access tokens are short lived and expire on their own.
+func (u *UserService) Logout(ctx context.Context, refreshToken string, all bool) error {
+	if refreshToken == "" {
+		u.logger.InfoContext(ctx, "error at Logout", slog.String("error", errEmptyToken.Error()))
+		return errEmptyToken
+	}
+
+	claims, err := u.tokens.parse(refreshToken, auth.RefreshToken)
+	if err != nil {
+		u.logger.InfoContext(ctx, "error at Logout", slog.String("error", err.Error()))
+		return ErrInvalidToken
+	}
+
+	if all {
+		userID, err := claims.UserID()
+		if err != nil {
+			return ErrInvalidToken
+		}
+		return u.db.RevokeUserTokens(ctx, userID)
+	}
+
+	if err := u.db.RevokeToken(ctx, claims.ID); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	return nil
+}
+
+// AssignRole assigns the given role to the user. The new permissions are embedded
+// in the access tokens issued from the next login or refresh on.
+func (u *UserService) AssignRole(ctx context.Context, userID int, role string) error {
+	if userID == 0 {
+		u.logger.InfoContext(ctx, "error at AssignRole", slog.String("error", errEmptyUserID.Error()))
+		return errEmptyUserID
+	}
+	if role == "" {
+		u.logger.InfoContext(ctx, "error at AssignRole", slog.String("error", errEmptyRole.Error()))
+		return errEmptyRole
+	}
+
+	return u.db.UpdateUserRole(ctx, userID, role)
+}
+
+// DeleteUser deletes the user, the other services drop its data when they consume the UserDeleted event.
+func (u *UserService) DeleteUser(ctx context.Context, userID int) error {
+	if userID == 0 {
+		u.logger.InfoContext(ctx, "error at DeleteUser", slog.String("error", errEmptyUserID.Error()))
+		return errEmptyUserID
+	}
+
+	return u.db.DeleteUser(ctx, userID)
+}
+
+// GetRoles returns every role alongside the permissions granted to it.
+func (u *UserService) GetRoles(ctx context.Context) ([]*store.Role, error) {
+	return u.db.RetrieveRoles(ctx)
+}
+
+func (u *UserService) issueTokens(ctx context.Context, user *store.User) (*TokenPair, error) {
+	permissions, err := u.db.RetrieveRolePermissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := auth.Identity{
+		UserID:      user.ID,
+		Email:       user.Email,
+		Role:        user.Role,
+		Permissions: permissions,
+	}
+
+	now := time.Now()
+	accessToken, _, err := u.tokens.sign(identity, auth.AccessToken, now)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshClaims, err := u.tokens.sign(identity, auth.RefreshToken, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.db.StoreToken(ctx, store.Token{
+		ID:        refreshClaims.ID,
+		UserID:    user.ID,
+		ExpiresAt: refreshClaims.ExpiresAt.Time,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    u.tokens.accessTokenTTL,
+	}, nil
+}
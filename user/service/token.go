@@ -0,0 +1,64 @@
+package service
+
+import (
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/auth"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// TokenConfig holds the secret used to sign tokens and how long they are valid for.
+// Zero TTLs fall back to 15 minutes for access tokens and 7 days for refresh tokens.
+type TokenConfig struct {
+	Secret          []byte
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// TokenPair is the result of a successful login or refresh.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+type tokenManager struct {
+	authenticator   *auth.Authenticator
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+func newTokenManager(config TokenConfig) *tokenManager {
+	tm := &tokenManager{
+		authenticator:   auth.NewAuthenticator(config.Secret),
+		accessTokenTTL:  config.AccessTokenTTL,
+		refreshTokenTTL: config.RefreshTokenTTL,
+	}
+	if tm.accessTokenTTL == 0 {
+		tm.accessTokenTTL = defaultAccessTokenTTL
+	}
+	if tm.refreshTokenTTL == 0 {
+		tm.refreshTokenTTL = defaultRefreshTokenTTL
+	}
+
+	return tm
+}
+
+// sign returns a signed token of the given type for the identity alongside its claims.
+func (tm *tokenManager) sign(identity auth.Identity, tokenType auth.TokenType, now time.Time) (string, *auth.Claims, error) {
+	ttl := tm.accessTokenTTL
+	if tokenType == auth.RefreshToken {
+		ttl = tm.refreshTokenTTL
+	}
+
+	return tm.authenticator.IssueToken(identity, tokenType, now, ttl)
+}
+
+// parse validates the signature and expiration of the token and makes sure it has the expected type.
+func (tm *tokenManager) parse(tokenString string, tokenType auth.TokenType) (*auth.Claims, error) {
+	return tm.authenticator.ParseToken(tokenString, tokenType)
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/shared/auth"
+)
+
+func TestTokenManager(t *testing.T) {
+	tm := newTokenManager(TokenConfig{
+		Secret: []byte("secret"),
+	})
+
+	now := time.Now()
+	token, claims, err := tm.sign(auth.Identity{UserID: 1, Email: "test@test.test"}, auth.RefreshToken, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.ExpiresAt.Sub(now) <= defaultAccessTokenTTL {
+		t.Fatalf("wanted refresh token to outlive access tokens, got %s", claims.ExpiresAt.Sub(now))
+	}
+
+	parsed, err := tm.parse(token, auth.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ID != claims.ID {
+		t.Fatalf("wanted %s, got %s", claims.ID, parsed.ID)
+	}
+
+	if _, err := tm.parse(token, auth.AccessToken); err == nil {
+		t.Fatal("wanted error when parsing a refresh token as an access token")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"time"
 
+	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/PseudoMera/virtual-store/shared/outbox"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -24,10 +27,17 @@ type User struct {
 	ID        int
 	Email     string
 	Password  string
+	Role      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+type Role struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
 type Profile struct {
 	ID        int
 	UserID    int
@@ -40,7 +50,16 @@ type Profile struct {
 	UpdatedAt time.Time
 }
 
-// StoreUser creates a new user and stores in the databse.
+type Token struct {
+	ID        string
+	UserID    int
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// StoreUser creates a new user and stores in the databse alongside a UserCreated event.
 // Returns the created user ID if everything went well,
 // an error if there was an issue.
 func (s *Store) StoreUser(ctx context.Context, user User) (int, error) {
@@ -48,17 +67,52 @@ func (s *Store) StoreUser(ctx context.Context, user User) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
 	var id int
-	err = s.db.QueryRow(ctx, "INSERT INTO vstore_user(email, password) VALUES($1, $2) RETURNING id", user.Email, hPassword).Scan(&id)
-	return id, err
+	var role string
+	err = tx.QueryRow(ctx, "INSERT INTO vstore_user(email, password) VALUES($1, $2) RETURNING id, role", user.Email, hPassword).Scan(&id, &role)
+	if err != nil {
+		return 0, shared.DBError(err, "user")
+	}
+
+	if err := outbox.Add(ctx, tx, events.AggregateUser, id, events.UserCreated, events.UserCreatedPayload{
+		UserID: id,
+		Email:  user.Email,
+		Role:   role,
+	}); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit(ctx)
 }
 
 // RetrieveUser retrieves the user with the given email.
 // Returns a User or an error depending on the result of the query.
 func (s *Store) RetrieveUser(ctx context.Context, email string) (*User, error) {
 	user := new(User)
-	err := s.db.QueryRow(ctx, "SELECT id, email, created_at FROM vstore_user WHERE email = $1", email).Scan(&user.ID, &user.Email, &user.CreatedAt)
-	return user, err
+	err := s.db.QueryRow(ctx, "SELECT id, email, role, created_at FROM vstore_user WHERE email = $1", email).Scan(&user.ID, &user.Email, &user.Role, &user.CreatedAt)
+	return user, shared.DBError(err, "user")
+}
+
+// RetrieveUserByID retrieves the user with the given id.
+func (s *Store) RetrieveUserByID(ctx context.Context, id int) (*User, error) {
+	user := new(User)
+	err := s.db.QueryRow(ctx, "SELECT id, email, role, created_at FROM vstore_user WHERE id = $1", id).Scan(&user.ID, &user.Email, &user.Role, &user.CreatedAt)
+	return user, shared.DBError(err, "user")
+}
+
+// RetrieveUserCredentials retrieves the user with the given email including its hashed password.
+// It should only be used to verify credentials, the password must never leave the service.
+func (s *Store) RetrieveUserCredentials(ctx context.Context, email string) (*User, error) {
+	user := new(User)
+	err := s.db.QueryRow(ctx, "SELECT id, email, password, role, created_at FROM vstore_user WHERE email = $1", email).Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.CreatedAt)
+	return user, shared.DBError(err, "user")
 }
 
 // StoreUserProfile creates a new profile for the specified user.
@@ -66,14 +120,14 @@ func (s *Store) RetrieveUser(ctx context.Context, email string) (*User, error) {
 func (s *Store) StoreUserProfile(ctx context.Context, profile Profile) (int, error) {
 	var id int
 	err := s.db.QueryRow(ctx, "INSERT INTO user_profile(user_id, name, photo, country, address, phone) VALUES($1, $2, $3, $4, $5, $6) RETURNING id", profile.UserID, profile.Name, profile.Photo, profile.Country, profile.Address, profile.Phone).Scan(&id)
-	return id, err
+	return id, shared.DBError(err, "user profile")
 }
 
 // RetrieveUserProfile retrieves the user profile with the given user ID.
 func (s *Store) RetrieveUserProfile(ctx context.Context, userID int) (*Profile, error) {
 	profile := new(Profile)
 	err := s.db.QueryRow(ctx, "SELECT id, user_id, name, photo, country, address, phone, created_at FROM user_profile WHERE user_id = $1", userID).Scan(&profile.ID, &profile.UserID, &profile.Name, &profile.Photo, &profile.Country, &profile.Address, &profile.Phone, &profile.CreatedAt)
-	return profile, err
+	return profile, shared.DBError(err, "user profile")
 }
 
 // UpdateUserProfile updates the user profile data.
@@ -82,6 +136,105 @@ func (s *Store) UpdateUserProfile(ctx context.Context, profile Profile) error {
 	return err
 }
 
+// UpdateUserRole assigns the given role to the user.
+// Returns pgx.ErrNoRows if the user does not exist.
+func (s *Store) UpdateUserRole(ctx context.Context, userID int, role string) error {
+	var id int
+	err := s.db.QueryRow(ctx, "UPDATE vstore_user SET role = $2 WHERE id = $1 RETURNING id", userID, role).Scan(&id)
+	return shared.DBError(err, "user")
+}
+
+// DeleteUser deletes the user alongside its profile and tokens and writes a UserDeleted event.
+// Returns a not found error if the user does not exist.
+func (s *Store) DeleteUser(ctx context.Context, userID int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var id int
+	if err := tx.QueryRow(ctx, "DELETE FROM vstore_user WHERE id = $1 RETURNING id", userID).Scan(&id); err != nil {
+		return shared.DBError(err, "user")
+	}
+	if err := outbox.Add(ctx, tx, events.AggregateUser, id, events.UserDeleted, events.UserDeletedPayload{
+		UserID: id,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RetrieveRolePermissions retrieves the names of the permissions granted to the given role.
+func (s *Store) RetrieveRolePermissions(ctx context.Context, role string) ([]string, error) {
+	rows, err := s.db.Query(ctx, "SELECT permission FROM role_permission WHERE role = $1 ORDER BY permission", role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+// RetrieveRoles retrieves every role alongside the permissions granted to it.
+func (s *Store) RetrieveRoles(ctx context.Context) ([]*Role, error) {
+	rows, err := s.db.Query(ctx, `SELECT r.name, r.description, COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM role r LEFT JOIN role_permission rp ON rp.role = r.name
+		GROUP BY r.name, r.description ORDER BY r.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*Role
+	for rows.Next() {
+		role := new(Role)
+		if err := rows.Scan(&role.Name, &role.Description, &role.Permissions); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// StoreToken stores a refresh token issued to a user so it can be revoked later on.
+func (s *Store) StoreToken(ctx context.Context, token Token) error {
+	_, err := s.db.Exec(ctx, "INSERT INTO user_token(id, user_id, expires_at) VALUES($1, $2, $3)", token.ID, token.UserID, token.ExpiresAt)
+	return err
+}
+
+// RetrieveToken retrieves the refresh token with the given id.
+func (s *Store) RetrieveToken(ctx context.Context, id string) (*Token, error) {
+	token := new(Token)
+	err := s.db.QueryRow(ctx, "SELECT id, user_id, expires_at, revoked_at, created_at, updated_at FROM user_token WHERE id = $1", id).Scan(&token.ID, &token.UserID, &token.ExpiresAt, &token.RevokedAt, &token.CreatedAt, &token.UpdatedAt)
+	return token, err
+}
+
+// RevokeToken marks the refresh token with the given id as revoked.
+// Returns pgx.ErrNoRows if the token does not exist or was already revoked,
+// which makes it safe to use for token rotation.
+func (s *Store) RevokeToken(ctx context.Context, id string) error {
+	var revokedID string
+	return s.db.QueryRow(ctx, "UPDATE user_token SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL RETURNING id", id).Scan(&revokedID)
+}
+
+// RevokeUserTokens revokes every active refresh token of the given user.
+func (s *Store) RevokeUserTokens(ctx context.Context, userID int) error {
+	_, err := s.db.Exec(ctx, "UPDATE user_token SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
+
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -2,23 +2,27 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/PseudoMera/virtual-store/user/migrations"
 
 	"github.com/PseudoMera/virtual-store/shared"
+	"github.com/PseudoMera/virtual-store/shared/events"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
 	testEmail    = "testEmail@test.test"
 	testPassword = "testing"
 	testName     = "tester"
-
-	projectRootPath = "../../"
 )
 
 func TestUserStore(t *testing.T) {
 	ctx := context.Background()
-	db, _, err := shared.SetupPostgresClient(ctx, projectRootPath)
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -85,4 +89,125 @@ func TestUserStore(t *testing.T) {
 	if err = store.UpdateUserProfile(ctx, updatedProfile); err != nil {
 		t.Fatal(err)
 	}
+
+	if err = store.DeleteUser(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.RetrieveUserProfile(ctx, id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+	if err = store.DeleteUser(ctx, id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+
+	var count int
+	if err := db.DB().QueryRow(ctx, "SELECT COUNT(*) FROM outbox WHERE event_type = $1", events.UserDeleted).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("wanted %d, got %d", 1, count)
+	}
+}
+
+func TestTokenStore(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	userID, err := store.StoreUser(ctx, User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentials, err := store.RetrieveUserCredentials(ctx, testEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPassword(credentials.Password, testPassword) {
+		t.Fatal("wanted stored password to match")
+	}
+
+	token := Token{
+		ID:        "token",
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := store.StoreToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	rToken, err := store.RetrieveToken(ctx, token.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rToken.UserID != userID {
+		t.Fatalf("wanted %d, got %d", userID, rToken.UserID)
+	}
+	if rToken.RevokedAt != nil {
+		t.Fatalf("wanted token not to be revoked, got %v", rToken.RevokedAt)
+	}
+
+	if err := store.RevokeToken(ctx, token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RevokeToken(ctx, token.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+}
+
+func TestRoleStore(t *testing.T) {
+	ctx := context.Background()
+	db, _, err := shared.SetupPostgresClient(ctx, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(db.DB())
+	userID, err := store.StoreUser(ctx, User{
+		Email:    testEmail,
+		Password: testPassword,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := store.RetrieveUserByID(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != "customer" {
+		t.Fatalf("wanted %s, got %s", "customer", user.Role)
+	}
+
+	permissions, err := store.RetrieveRolePermissions(ctx, user.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(permissions) != 0 {
+		t.Fatalf("wanted %d, got %d", 0, len(permissions))
+	}
+
+	if err := store.UpdateUserRole(ctx, userID, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateUserRole(ctx, userID, "unknown"); err == nil {
+		t.Fatal("wanted error when assigning an unknown role")
+	}
+	if err := store.UpdateUserRole(ctx, userID+1, "admin"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("wanted %v, got %v", pgx.ErrNoRows, err)
+	}
+
+	roles, err := store.RetrieveRoles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roles) != 3 {
+		t.Fatalf("wanted %d, got %d", 3, len(roles))
+	}
 }